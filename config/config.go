@@ -18,7 +18,9 @@ type AppConfig struct {
 	Log           LogConfig           `yaml:"log"`
 	Foreign       bool                `yaml:"foreign"`
 	Aliyun        Aliyun              `yaml:"aliyun"`
+	Translate     TranslateConfig     `yaml:"translate"`
 	Supabase      Supabase            `yaml:"supabase"`
+	Admin         Admin               `yaml:"admin"`
 }
 
 type ElasticsearchConfig struct {
@@ -45,6 +47,44 @@ type Aliyun struct {
 	AccessKeySecret string `yaml:"accessKeySecret"`
 }
 
+// TranslateConfig 机器翻译服务配置，Provider 可选 aliyun、deepl、google、openai，默认 aliyun
+type TranslateConfig struct {
+	Provider    string  `yaml:"provider"`
+	Concurrency int     `yaml:"concurrency"` // 单个翻译任务并发请求翻译接口的数量
+	QPS         float64 `yaml:"qps"`         // 翻译服务商的QPS限制
+	Burst       int     `yaml:"burst"`
+	ClusterQPS  int     `yaml:"clusterQps"` // 所有 worker 共享的总QPS，大于0时启用基于Redis的分布式限流
+	PairQPS     int     `yaml:"pairQps"`    // 所有 worker 共享的单个语言对QPS，0表示不限制
+	MaxRetries  int     `yaml:"maxRetries"` // 限流或临时错误时的最大重试次数
+	Memory      Memory  `yaml:"memory"`
+	DeepL       DeepL   `yaml:"deepl"`
+	Google      Google  `yaml:"google"`
+	OpenAI      OpenAI  `yaml:"openai"`
+}
+
+// Memory 翻译记忆配置，缓存在Redis中，Durable 为 true 时同时写入 translation_memory 表
+type Memory struct {
+	Enabled bool `yaml:"enabled"`
+	TTLDays int  `yaml:"ttlDays"` // Redis 中记录的过期天数，0表示不过期
+	Durable bool `yaml:"durable"`
+}
+
+type DeepL struct {
+	AuthKey  string `yaml:"authKey"`
+	Endpoint string `yaml:"endpoint"` // 免费版为 https://api-free.deepl.com
+}
+
+type Google struct {
+	ApiKey string `yaml:"apiKey"`
+}
+
+// OpenAI 兼容 OpenAI Chat Completions 协议的大模型翻译服务
+type OpenAI struct {
+	BaseUrl string `yaml:"baseUrl"`
+	ApiKey  string `yaml:"apiKey"`
+	Model   string `yaml:"model"`
+}
+
 type Redis struct {
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
@@ -63,6 +103,25 @@ type Supabase struct {
 	SupabaseApiKey    string `yaml:"supabaseApiKey"`
 	SupabaseSecretKey string `yaml:"supabaseSecretKey"`
 	Jwt               string `yaml:"jwt"`
+	StorageBucket     string `yaml:"storageBucket"` // 上传的大文件和译文存放的 Storage bucket，默认 translations
+}
+
+// Admin 管理员配置，UserIds 中的用户可以查看所有用户共享的翻译接口限流等全局信息
+type Admin struct {
+	UserIds []string `yaml:"userIds"`
+}
+
+// IsAdmin 用户是否是配置中的管理员
+func IsAdmin(userid string) bool {
+	if Cfg == nil || userid == "" {
+		return false
+	}
+	for _, id := range Cfg.Admin.UserIds {
+		if id == userid {
+			return true
+		}
+	}
+	return false
 }
 
 type LogConfig struct {
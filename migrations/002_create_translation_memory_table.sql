@@ -0,0 +1,13 @@
+-- 创建翻译记忆表，key 由用户、翻译服务商、语言对和原文哈希组成
+CREATE TABLE IF NOT EXISTS translation_memory (
+    key VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    translated TEXT NOT NULL,
+    update_time TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 创建索引，按用户查询和清除翻译记忆
+CREATE INDEX IF NOT EXISTS idx_translation_memory_user_id ON translation_memory(user_id);
+
+-- 添加关闭翻译记忆的字段
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS disable_memory BOOLEAN DEFAULT FALSE;
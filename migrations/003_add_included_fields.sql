@@ -0,0 +1,15 @@
+-- 添加只翻译的路径规则字段，ignored_fields 同样支持路径规则
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS included_fields TEXT;
+
+-- 之前的 ignored_fields 是任意层级的 key 名，改写成 **['key']，保持原来的含义，
+-- 也避免 items[ 这类 key 在新的路径语法下解析失败。included_fields 为 NULL 的是改写前的记录，重复执行不会再次改写
+UPDATE user_json_translations
+SET ignored_fields = COALESCE((
+        SELECT string_agg('**[''' || trim(field) || ''']', ',')
+        FROM unnest(string_to_array(ignored_fields, ',')) AS field
+        WHERE trim(field) <> ''
+    ), ''),
+    included_fields = ''
+WHERE included_fields IS NULL;
+
+ALTER TABLE user_json_translations ALTER COLUMN included_fields SET DEFAULT '';
@@ -0,0 +1,2 @@
+-- 添加占位符类型字段，为空时使用默认的类型
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS placeholders TEXT DEFAULT '';
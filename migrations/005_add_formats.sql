@@ -0,0 +1,2 @@
+-- 添加按路径指定字符串格式的字段，为空时自动检测
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS formats TEXT DEFAULT '';
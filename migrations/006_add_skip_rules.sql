@@ -0,0 +1,4 @@
+-- 添加自动跳过规则的字段，以及创建时统计到的跳过的字符串
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS disabled_detectors TEXT DEFAULT '';
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS skip_patterns JSONB DEFAULT '[]'::jsonb;
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS skipped_paths JSONB DEFAULT '[]'::jsonb;
@@ -0,0 +1,2 @@
+-- 添加占位符丢失等原因保留原文的字符串，翻译完成时写入
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS failed_paths JSONB DEFAULT '[]'::jsonb;
@@ -0,0 +1,18 @@
+-- 创建多语言翻译任务表，一次请求翻译成多个目标语言时每个语言对应一条 user_json_translations
+CREATE TABLE IF NOT EXISTS user_json_translation_jobs (
+    id UUID PRIMARY KEY,
+    userid VARCHAR(255) NOT NULL,
+    from_lang VARCHAR(20) NOT NULL,
+    to_langs JSONB NOT NULL DEFAULT '[]'::jsonb,
+    char_total INTEGER DEFAULT 0,
+    webhook_mode VARCHAR(10) NOT NULL DEFAULT 'all',
+    webhook_sent BOOLEAN DEFAULT FALSE,
+    create_time TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    update_time TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_json_translation_jobs_userid ON user_json_translation_jobs(userid);
+
+-- 每个目标语言的翻译记录关联到父任务
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS job_id UUID REFERENCES user_json_translation_jobs(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_user_json_translations_job_id ON user_json_translations(job_id);
@@ -0,0 +1,4 @@
+-- 增量翻译：记录上一次的原文和译文，没有变化的字符串沿用上一次的译文
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS base_id UUID;
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS previous_source TEXT DEFAULT '';
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS previous_target TEXT DEFAULT '';
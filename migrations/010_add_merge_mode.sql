@@ -0,0 +1,4 @@
+-- 合并模式：保留已有的目标语言JSON中的值，只翻译缺少的 key，翻译完成时写入合并的报告
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS existing_target TEXT DEFAULT '';
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS prune_removed BOOLEAN DEFAULT FALSE;
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS merge_report JSONB;
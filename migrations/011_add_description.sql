@@ -0,0 +1,2 @@
+-- 请求的描述，和 key 路径一起作为翻译的上下文
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS description TEXT DEFAULT '';
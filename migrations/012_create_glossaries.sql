@@ -0,0 +1,17 @@
+-- 创建用户术语表，target_lang 为空时适用于所有目标语言
+CREATE TABLE IF NOT EXISTS user_glossaries (
+    id UUID PRIMARY KEY,
+    userid VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    source_lang VARCHAR(20) NOT NULL,
+    target_lang VARCHAR(20) NOT NULL DEFAULT '',
+    terms JSONB NOT NULL DEFAULT '[]'::jsonb,
+    create_time TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    update_time TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_glossaries_userid ON user_glossaries(userid);
+
+-- 翻译使用的术语表，以及没有使用术语表规定译法的字符串
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS glossary_id UUID REFERENCES user_glossaries(id) ON DELETE SET NULL;
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS glossary_issues JSONB DEFAULT '[]'::jsonb;
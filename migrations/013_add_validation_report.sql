@@ -0,0 +1,2 @@
+-- 译文质量检查发现的问题：占位符和标签个数、未翻译、长度比例、首尾空白、结尾标点、换行个数和文字
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS validation_report JSONB DEFAULT '[]'::jsonb;
@@ -0,0 +1,3 @@
+-- 上传文件翻译：原文和译文保存在 Supabase Storage 中，表中只记录路径
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS origin_file TEXT DEFAULT '';
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS translated_file TEXT DEFAULT '';
@@ -0,0 +1,4 @@
+-- 翻译模式和结果状态：宽松模式下部分字符串翻译失败时状态为 partial，严格模式下为 failed，同时记录翻译成功的字符串
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS translation_mode TEXT DEFAULT 'lenient';
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS translation_status TEXT DEFAULT '';
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS translated_paths JSONB DEFAULT '[]'::jsonb;
@@ -0,0 +1,2 @@
+-- 伪本地化(qps-ploc、qps-plocm)时译文加长的百分比，为空时使用默认的 30%
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS pseudo_expansion INTEGER;
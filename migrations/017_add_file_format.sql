@@ -0,0 +1,2 @@
+-- 原文的文件格式：json、yaml、properties、po、android、strings、stringsdict 或 arb，译文使用相同的格式
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS file_format TEXT DEFAULT 'json';
@@ -0,0 +1,2 @@
+-- jsonc 和 json5 中字符串的注释是否作为翻译的上下文发送给翻译服务商
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS comment_context BOOLEAN DEFAULT FALSE;
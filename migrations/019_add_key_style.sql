@@ -0,0 +1,3 @@
+-- 译文中 key 的形状：flat 扁平的 key、nested 嵌套的对象，为空时保持原文的形状；key_separator 是扁平的 key 的分隔符，为空时为 "."
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS key_style TEXT DEFAULT '';
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS key_separator TEXT DEFAULT '';
@@ -0,0 +1,3 @@
+-- 译文的缩进(tab 或者空格数，为空时沿用原文的缩进)和是否按字母顺序排列 key
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS indent TEXT DEFAULT '';
+ALTER TABLE user_json_translations ADD COLUMN IF NOT EXISTS sort_keys BOOLEAN DEFAULT FALSE;
@@ -2,8 +2,6 @@ package models
 
 import (
 	"time"
-
-	"github.com/iancoleman/orderedmap"
 )
 
 type TranslationRequest struct {
@@ -16,6 +14,8 @@ type TranslationRequest struct {
 	GlossaryId         string   `json:"glossary_id,omitempty"`
 	TagHandling        string   `json:"tag_handling,omitempty"`
 	OutlineDetection   bool     `json:"outline_detection,omitempty"`
+	Context            string   `json:"context,omitempty"` // 整个请求共用的上下文，对应 DeepL 的 context 参数
+	Contexts           []string `json:"-"`                 // 与 Text 一一对应的上下文，用于大模型翻译
 }
 
 type TranslationResponse struct {
@@ -24,13 +24,129 @@ type TranslationResponse struct {
 }
 
 type Config struct {
-	SourceData     *orderedmap.OrderedMap
-	TranslatedFile *orderedmap.OrderedMap
-	IgnoredFields  []string
-	SourceLang     string
-	TargetLang     string
-	APIEndpoint    string
-	APIKey         string
+	SourceData        interface{} // 任意类型的 JSON 值，对象为 *orderedmap.OrderedMap，数字为 json.Number
+	TranslatedFile    interface{}
+	IgnoredFields     []string // 忽略翻译的路径规则
+	IncludedFields    []string // 只翻译的路径规则，为空时翻译全部字段
+	Placeholders      []string // 翻译前需要保护的占位符类型，为空时使用默认的类型
+	Formats           []string // 按路径指定字符串的格式，如 body=html，未指定时自动检测
+	DisabledDetectors []string // 关闭的自动跳过类型，如 number,identifier
+	SkipPatterns      []string // 自定义的跳过正则
+	SourceLang        string
+	TargetLang        string
+	APIEndpoint       string
+	APIKey            string
+	UserID            string            // 翻译记忆按用户隔离
+	DisableMemory     bool              // 不使用翻译记忆，每次都调用翻译接口
+	PreviousSource    string            // 增量翻译时上一次的原文JSON，没有变化的字符串沿用 PreviousTarget 中的译文
+	PreviousTarget    string            // 增量翻译时上一次的译文JSON
+	ExistingTarget    string            // 合并模式下已有的目标语言JSON，已有的值保留，只翻译缺少的 key
+	PruneRemoved      bool              // 合并模式下删除原文中已经不存在的 key
+	Description       string            // 请求的描述，和 key 路径一起作为翻译的上下文
+	CommentContext    bool              // JSONC 等格式中字符串的注释作为翻译的上下文
+	Comments          map[string]string // 按 a.b[0].c 形式的路径记录原文件中字符串的注释，和 key 路径一起作为翻译的上下文
+	Glossary          []GlossaryTerm    // 术语表中适用于当前语言对的术语，翻译时强制使用
+	Strict            bool              // 严格模式，任意字符串翻译失败时整个翻译失败，不返回部分翻译的结果
+	PseudoExpansion   *int              // 伪本地化时译文加长的百分比，为空时使用默认值
+	KeyStyle          string            // 译文中 key 的形状，flat 或 nested，为空时保持原文的形状
+	KeySeparator      string            // 扁平的 key 中各层之间的分隔符，为空时为 "."
+	Indent            string            // 译文的缩进，tab 或者空格数，0 输出紧凑的 JSON，为空时沿用原文的缩进
+	SortKeys          bool              // 译文中对象的 key 按字母顺序排列
+}
+
+// SkippedValue 自动跳过、没有翻译的字符串
+type SkippedValue struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// FailedValue 翻译失败、保留原文的字符串，Error 为失败的原因
+type FailedValue struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// QualityIssue 译文质量检查发现的问题，Check 为检查项，Detail 为具体的差异
+type QualityIssue struct {
+	Path   string `json:"path"`
+	Check  string `json:"check"`
+	Detail string `json:"detail"`
+}
+
+// 翻译模式，宽松模式下部分字符串翻译失败时保留原文，结果仍然可用
+const (
+	TranslationModeLenient = "lenient"
+	TranslationModeStrict  = "strict"
+)
+
+// 翻译结果的状态
+const (
+	TranslationStatusComplete = "complete" // 所有需要翻译的字符串都翻译成功
+	TranslationStatusPartial  = "partial"  // 部分字符串翻译失败，保留了原文
+	TranslationStatusFailed   = "failed"   // 翻译失败，严格模式下有字符串翻译失败时也是失败
+)
+
+// 多语言翻译任务中每个语言和整体的状态
+const (
+	JobStatusPending    = "pending"
+	JobStatusProcessing = "processing"
+	JobStatusCompleted  = "completed"
+	JobStatusFailed     = "failed"
+)
+
+// JobTranslation 多语言翻译任务中一个目标语言的翻译
+type JobTranslation struct {
+	Id               string         `json:"id"`
+	ToLang           string         `json:"to_lang"`
+	Status           string         `json:"status"`
+	TranslatedJSON   string         `json:"translated_json,omitempty"`
+	ValidationReport []QualityIssue `json:"validation_report,omitempty"` // 译文质量检查发现的问题
+	FailedPaths      []FailedValue  `json:"failed_paths,omitempty"`      // 翻译失败、保留原文的字符串
+}
+
+// JobStatus 多语言翻译任务的整体状态，全部语言结束后为 completed 或 failed
+type JobStatus struct {
+	Id           string           `json:"id"`
+	Status       string           `json:"status"`
+	FromLang     string           `json:"from_lang"`
+	Translations []JobTranslation `json:"translations"`
+}
+
+// GlossaryTerm 术语表中的一条术语，DoNotTranslate 为 true 时保留原文，例如品牌名、产品名
+type GlossaryTerm struct {
+	Source         string `json:"source"`
+	Target         string `json:"target"`
+	DoNotTranslate bool   `json:"do_not_translate"`
+	IgnoreCase     bool   `json:"ignore_case"` // 匹配原文时忽略大小写
+}
+
+// TermIssue 译文中没有使用术语表规定的译法
+type TermIssue struct {
+	Path     string `json:"path"`
+	Term     string `json:"term"`
+	Expected string `json:"expected"`
+}
+
+// MergeReport 合并模式下新翻译、保留已有的值和删除的 key 路径
+type MergeReport struct {
+	Added   []string `json:"added"`
+	Kept    []string `json:"kept"`
+	Removed []string `json:"removed"`
+}
+
+// ReviewReport 导入审校后的 XLIFF 的结果
+type ReviewReport struct {
+	Updated            []string            `json:"updated"`             // 译文被修改的 key 路径
+	Unchanged          int                 `json:"unchanged"`           // 译文没有修改的字符串个数
+	UnknownIds         []string            `json:"unknown_ids"`         // 原文中没有对应字符串的 unit id
+	PlaceholderChanges []PlaceholderChange `json:"placeholder_changes"` // 占位符和原文不一致、没有导入的译文
+}
+
+// PlaceholderChange 审校后的译文缺少原文中的占位符，或者多出了原文中没有的占位符
+type PlaceholderChange struct {
+	Path    string   `json:"path"`
+	Missing []string `json:"missing"`
+	Added   []string `json:"added"`
 }
 
 type Response struct {
@@ -1,5 +1,7 @@
 package tables
 
+import "json_trans_api/models/models"
+
 type ApiKeys struct {
 	Id          string `json:"id"`
 	Userid      string `json:"userid"`
@@ -10,17 +12,73 @@ type ApiKeys struct {
 }
 
 type UserJsonData struct {
-	Id             string `json:"id"`
-	OriginJSON     string `json:"origin_json"`
-	TranslatedJSON string `json:"translated_json"`
-	FromLang       string `json:"from_lang"`
-	ToLang         string `json:"to_lang"`
-	CreatedTime    string `json:"create_time"`
-	UpdateTime     string `json:"update_time"`
-	TaskID         string `json:"-"`              // 新增的 TaskID 字段
-	IsTranslated   bool   `json:"-"`              // 新增的翻译状态字段
-	IgnoredFields  string `json:"ignored_fields"` // 忽略翻译的字段
-	CharTotal      int    `json:"char_total"`
+	Id                string                `json:"id"`
+	OriginJSON        string                `json:"origin_json"`
+	TranslatedJSON    string                `json:"translated_json"`
+	FromLang          string                `json:"from_lang"`
+	ToLang            string                `json:"to_lang"`
+	CreatedTime       string                `json:"create_time"`
+	UpdateTime        string                `json:"update_time"`
+	TaskID            string                `json:"-"`                  // 新增的 TaskID 字段
+	IsTranslated      bool                  `json:"-"`                  // 新增的翻译状态字段
+	IgnoredFields     string                `json:"ignored_fields"`     // 忽略翻译的字段
+	IncludedFields    string                `json:"included_fields"`    // 只翻译的字段
+	Placeholders      string                `json:"placeholders"`       // 需要保护的占位符类型
+	Formats           string                `json:"formats"`            // 按路径指定的字符串格式
+	DisabledDetectors string                `json:"disabled_detectors"` // 关闭的自动跳过类型
+	SkipPatterns      []string              `json:"skip_patterns"`      // 自定义的跳过正则
+	SkippedPaths      []models.SkippedValue `json:"skipped_paths"`      // 自动跳过、没有翻译的字符串
+	FailedPaths       []models.FailedValue  `json:"failed_paths"`       // 占位符丢失等原因保留原文的字符串
+	CharTotal         int                   `json:"char_total"`
+	DisableMemory     bool                  `json:"disable_memory"`            // 不使用翻译记忆
+	JobID             string                `json:"job_id"`                    // 多语言翻译任务的父任务，单个语言的请求为空
+	BaseId            string                `json:"base_id,omitempty"`         // 增量翻译时作为基础的翻译
+	PreviousSource    string                `json:"previous_source,omitempty"` // 增量翻译时上一次的原文
+	PreviousTarget    string                `json:"previous_target,omitempty"` // 增量翻译时上一次的译文
+	ExistingTarget    string                `json:"existing_target,omitempty"` // 合并模式下已有的目标语言JSON
+	PruneRemoved      bool                  `json:"prune_removed"`             // 合并模式下删除原文中已经不存在的 key
+	MergeReport       *models.MergeReport   `json:"merge_report,omitempty"`    // 合并模式下新翻译、保留和删除的 key 路径
+	Description       string                `json:"description"`               // 请求的描述，作为翻译的上下文
+	GlossaryId        string                `json:"glossary_id"`               // 使用的术语表
+	GlossaryIssues    []models.TermIssue    `json:"glossary_issues"`           // 没有使用术语表规定译法的字符串
+	ValidationReport  []models.QualityIssue `json:"validation_report"`         // 译文质量检查发现的问题
+	OriginFile        string                `json:"origin_file,omitempty"`     // 上传文件翻译时原文在 Storage 中的路径，此时 origin_json 为空
+	TranslatedFile    string                `json:"translated_file,omitempty"` // 上传文件翻译时译文在 Storage 中的路径，此时 translated_json 为空
+	TranslationMode   string                `json:"translation_mode"`          // lenient 或 strict，严格模式下任意字符串翻译失败时整个翻译失败
+	TranslationStatus string                `json:"translation_status"`        // 翻译结束后为 complete、partial 或 failed
+	TranslatedPaths   []string              `json:"translated_paths"`          // 翻译成功的字符串
+	PseudoExpansion   *int                  `json:"pseudo_expansion"`          // 伪本地化时译文加长的百分比，为空时使用默认值
+	FileFormat        string                `json:"file_format"`               // 原文的格式，为空时是 json
+	CommentContext    bool                  `json:"comment_context"`           // jsonc 和 json5 中字符串的注释作为翻译的上下文
+	KeyStyle          string                `json:"key_style"`                 // 译文中 key 的形状，flat 或 nested，为空时保持原文的形状
+	KeySeparator      string                `json:"key_separator"`             // 扁平的 key 的分隔符，为空时为 "."
+	Indent            string                `json:"indent"`                    // 译文的缩进，tab 或者空格数，为空时沿用原文的缩进
+	SortKeys          bool                  `json:"sort_keys"`                 // 译文中对象的 key 按字母顺序排列
+}
+
+// Glossary 用户的术语表，TargetLang 为空时适用于所有目标语言
+type Glossary struct {
+	Id          string                `json:"id"`
+	Userid      string                `json:"userid"`
+	Name        string                `json:"name"`
+	SourceLang  string                `json:"source_lang"`
+	TargetLang  string                `json:"target_lang"`
+	Terms       []models.GlossaryTerm `json:"terms"`
+	CreatedTime string                `json:"create_time"`
+	UpdateTime  string                `json:"update_time"`
+}
+
+// TranslationJob 一次请求翻译成多个目标语言的父任务，每个目标语言对应一条 user_json_translations
+type TranslationJob struct {
+	Id          string   `json:"id"`
+	Userid      string   `json:"userid"`
+	FromLang    string   `json:"from_lang"`
+	ToLangs     []string `json:"to_langs"`
+	CharTotal   int      `json:"char_total"`
+	WebhookMode string   `json:"webhook_mode"` // all 全部语言完成后发送一次，each 每个语言完成后各发送一次
+	WebhookSent bool     `json:"webhook_sent"`
+	CreatedTime string   `json:"create_time"`
+	UpdateTime  string   `json:"update_time"`
 }
 
 type User struct {
@@ -0,0 +1,286 @@
+package fileformat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"json_trans_api/pkg/translate"
+	"regexp"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// androidElement strings.xml 中的一个元素，innerStart 和 innerEnd 是标签之间的内容的范围
+type androidElement struct {
+	start, end           int
+	innerStart, innerEnd int
+	text                 string // 解码后的文字，不包含子元素的标签
+	markup               bool   // 包含 <b>、<xliff:g> 这类子元素
+	children             []*androidElement
+	attrs                []xml.Attr
+	name                 string
+}
+
+func (e *androidElement) attr(name string) string {
+	for _, attr := range e.attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// parseAndroid 解析 Android strings.xml，翻译 string、string-array 的 item 和 plurals 的各个分支。
+// translatable="false" 的元素和 @string/ 这类资源引用不翻译；plurals 按目标语言的复数类别重新生成，
+// 注释和其余内容原样保留
+func parseAndroid(content string, sourceLang string) (Document, error) {
+	root, err := readAndroid(content)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTemplate(content)
+	for _, element := range root.children {
+		name := element.attr("name")
+		if name == "" || element.attr("translatable") == "false" {
+			continue
+		}
+
+		switch element.name {
+		case "string":
+			if value, encode, ok := androidValue(content, element); ok {
+				t.tree.Set(name, value)
+				t.replace(element.innerStart, element.innerEnd, []interface{}{name}, encode)
+			}
+		case "string-array":
+			var items []interface{}
+			for _, item := range element.children {
+				if value, encode, ok := androidValue(content, item); ok && item.name == "item" {
+					t.replace(item.innerStart, item.innerEnd, []interface{}{name, len(items)}, encode)
+					items = append(items, value)
+				}
+			}
+			if len(items) > 0 {
+				t.tree.Set(name, items)
+			}
+		case "plurals":
+			if forms := androidPlurals(content, element, name, t); len(forms.Keys()) > 0 {
+				t.tree.Set(name, forms)
+			}
+		}
+	}
+	return t, nil
+}
+
+// androidPlurals 收集 plurals 的各个分支，从第一个 <item> 到最后一个 </item> 整段按目标语言的复数类别重新生成
+func androidPlurals(content string, element *androidElement, name string, t *template) *orderedmap.OrderedMap {
+	forms := orderedmap.New()
+	var items []*androidElement
+	for _, item := range element.children {
+		if item.name != "item" || !translate.IsPluralCategory(item.attr("quantity")) {
+			continue
+		}
+		value, _, ok := androidValue(content, item)
+		if !ok {
+			return orderedmap.New()
+		}
+		forms.Set(item.attr("quantity"), value)
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return forms
+	}
+
+	first, last := items[0], items[len(items)-1]
+	indent := "\n" + lineIndent(content, first.start)
+	markup := false
+	quoted := false
+	for _, item := range items {
+		markup = markup || item.markup
+		quoted = quoted || isAndroidQuoted(content[item.innerStart:item.innerEnd])
+	}
+
+	t.replace(first.start, last.end, []interface{}{name}, func(value interface{}, lang string) (string, bool) {
+		translated, ok := pluralForms(value, lang)
+		if !ok {
+			return "", false
+		}
+		lines := make([]string, 0, len(translated))
+		for _, form := range translated {
+			lines = append(lines, `<item quantity="`+form.category+`">`+escapeAndroid(form.text, markup, quoted)+"</item>")
+		}
+		return strings.Join(lines, indent), true
+	})
+	return forms
+}
+
+// androidValue 返回元素需要翻译的文字和写回的 encoder，资源引用和空字符串不翻译
+func androidValue(content string, element *androidElement) (string, encoder, bool) {
+	raw := content[element.innerStart:element.innerEnd]
+	quoted := isAndroidQuoted(raw)
+	trimmed := strings.TrimSpace(raw)
+	// 开头是 @ 或 ? 的是资源引用，例如 @string/app_name
+	if strings.HasPrefix(trimmed, "@") || strings.HasPrefix(trimmed, "?") {
+		return "", nil, false
+	}
+
+	value := element.text
+	if element.markup {
+		value = raw
+	}
+	value = strings.TrimSpace(value)
+	if quoted {
+		value = value[1 : len(value)-1]
+	}
+	value = unescapeAndroid(value)
+
+	if strings.TrimSpace(value) == "" {
+		return "", nil, false
+	}
+
+	// 标签之间首尾的空白原样保留
+	leading := raw[:strings.Index(raw, trimmed)]
+	trailing := raw[len(leading)+len(trimmed):]
+	markup := element.markup
+	return value, text(func(s string) string {
+		return leading + escapeAndroid(s, markup, quoted) + trailing
+	}), true
+}
+
+func isAndroidQuoted(raw string) bool {
+	raw = strings.TrimSpace(raw)
+	return len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"'
+}
+
+// unescapeAndroid 解析 Android 字符串资源的反斜杠转义
+func unescapeAndroid(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r, size := decodeHex4(s[i+1:])
+			if size == 0 {
+				b.WriteByte('u')
+				continue
+			}
+			b.WriteRune(r)
+			i += size
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+var (
+	androidTag    = regexp.MustCompile(`<[^<>]*>`)
+	androidEntity = regexp.MustCompile(`&(#[0-9]+|#x[0-9a-fA-F]+|[A-Za-z]+);`)
+)
+
+// escapeAndroid 转义成 Android 字符串资源，包含子元素时标签原样保留，只转义标签之外的文字
+func escapeAndroid(s string, markup bool, quoted bool) string {
+	escape := func(part string) string {
+		part = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `'`, `\'`, "\n", `\n`, "\t", `\t`).Replace(part)
+		if !markup {
+			return escapeXML(part)
+		}
+		// 包含子元素时文字中的字符实体原样保留
+		var b strings.Builder
+		last := 0
+		for _, loc := range androidEntity.FindAllStringIndex(part, -1) {
+			b.WriteString(escapeXML(part[last:loc[0]]))
+			b.WriteString(part[loc[0]:loc[1]])
+			last = loc[1]
+		}
+		b.WriteString(escapeXML(part[last:]))
+		return b.String()
+	}
+
+	var b strings.Builder
+	last := 0
+	if markup {
+		for _, loc := range androidTag.FindAllStringIndex(s, -1) {
+			b.WriteString(escape(s[last:loc[0]]))
+			b.WriteString(s[loc[0]:loc[1]])
+			last = loc[1]
+		}
+	}
+	b.WriteString(escape(s[last:]))
+
+	result := b.String()
+	if quoted {
+		return `"` + result + `"`
+	}
+	// 开头的 @ 和 ? 会被当成资源引用
+	if strings.HasPrefix(result, "@") || strings.HasPrefix(result, "?") {
+		result = `\` + result
+	}
+	return result
+}
+
+// readAndroid 读取 <resources> 元素，记录每个元素在文件中的位置
+func readAndroid(content string) (*androidElement, error) {
+	dec := xml.NewDecoder(strings.NewReader(content))
+	for {
+		offset := int(dec.InputOffset())
+		token, err := dec.RawToken()
+		if err == io.EOF {
+			return nil, fmt.Errorf("invalid strings.xml: no resources element")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid strings.xml: %v", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local != "resources" {
+				return nil, fmt.Errorf("invalid strings.xml: the root must be resources")
+			}
+			return readAndroidElement(dec, start, offset)
+		}
+	}
+}
+
+func readAndroidElement(dec *xml.Decoder, start xml.StartElement, offset int) (*androidElement, error) {
+	element := &androidElement{
+		start:      offset,
+		innerStart: int(dec.InputOffset()),
+		name:       start.Name.Local,
+		attrs:      start.Attr,
+	}
+	for {
+		childOffset := int(dec.InputOffset())
+		token, err := dec.RawToken()
+		if err != nil {
+			return nil, fmt.Errorf("invalid strings.xml: %v", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := readAndroidElement(dec, token, childOffset)
+			if err != nil {
+				return nil, err
+			}
+			element.markup = true
+			element.text += child.text
+			element.children = append(element.children, child)
+		case xml.CharData:
+			element.text += string(token)
+		case xml.EndElement:
+			element.innerEnd = childOffset
+			element.end = int(dec.InputOffset())
+			return element, nil
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package fileformat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// arbLocale ARB 文件中记录语言的 key
+const arbLocale = "@@locale"
+
+// parseARB 解析 Flutter .arb，翻译不以 @ 开头的字符串，ICU 的复数和选择由 pkg/translate 按分支翻译。
+// @key 的描述和占位符等元数据不翻译，@@locale 写回时换成目标语言，格式和顺序原样保留
+func parseARB(content string, sourceLang string) (Document, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("invalid ARB: the root must be an object")
+	}
+
+	t := newTemplate(content)
+	encode := text(quoteJSON)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid ARB: %v", err)
+		}
+		key := token.(string)
+		offset := int(dec.InputOffset())
+
+		if token, err = dec.Token(); err != nil {
+			return nil, fmt.Errorf("invalid ARB: %v", err)
+		}
+		if _, ok := token.(json.Delim); ok {
+			if err := skipJSONValue(dec); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		value, ok := token.(string)
+		if !ok || (strings.HasPrefix(key, "@") && key != arbLocale) {
+			continue
+		}
+
+		// Token 不返回值的起始位置，从 key 之后找到值的引号
+		end := int(dec.InputOffset())
+		start := offset + strings.IndexByte(content[offset:end], '"')
+		if key == arbLocale {
+			t.replace(start, end, nil, func(_ interface{}, lang string) (string, bool) {
+				return quoteJSON(lang), lang != ""
+			})
+			continue
+		}
+		t.tree.Set(key, value)
+		t.replace(start, end, []interface{}{key}, encode)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("invalid ARB: %v", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid ARB: unexpected data after the top-level value")
+	}
+	return t, nil
+}
+
+// skipJSONValue 跳过对象或数组剩余的内容
+func skipJSONValue(dec *json.Decoder) error {
+	for depth := 1; depth > 0; {
+		token, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("invalid ARB: %v", err)
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
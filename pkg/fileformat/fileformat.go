@@ -0,0 +1,486 @@
+package fileformat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"json_trans_api/models/models"
+	"json_trans_api/pkg/translate"
+	"json_trans_api/pkg/xliff"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// 支持的文件格式，创建翻译时通过 file_format 字段指定，上传文件时未指定则按文件名推断
+const (
+	JSON        = "json"
+	JSONC       = "jsonc"       // 带注释和尾随逗号的 JSON
+	JSON5       = "json5"       // JSON5，另外支持单引号、不带引号的 key 和十六进制数字等
+	YAML        = "yaml"        // Rails 等使用的 YAML，根节点是源语言代码时译文中换成目标语言代码
+	Properties  = "properties"  // Java .properties
+	PO          = "po"          // gettext .po 和 .pot
+	Android     = "android"     // Android strings.xml
+	IOSStrings  = "strings"     // iOS .strings
+	StringsDict = "stringsdict" // iOS .stringsdict
+	ARB         = "arb"         // Flutter .arb
+	XLIFF       = "xliff"       // XLIFF 1.2 和 2.0，翻译 source 写入 target
+)
+
+// Document 解析后的文件。Tree 是交给 pkg/translate 翻译的有序 key/value 树，只包含需要翻译的字符串；
+// Render 把翻译后的树写回原来的格式，注释、顺序和不需要翻译的内容保持不变
+type Document interface {
+	Tree() *orderedmap.OrderedMap
+	Render(translated interface{}, targetLang string) (string, error)
+}
+
+// commented 记录了注释的文档，Comments 按 a.b[0].c 形式的路径返回字符串的注释，用作翻译的上下文
+type commented interface {
+	Comments() map[string]string
+}
+
+type parser func(content string, sourceLang string) (Document, error)
+
+var parsers = map[string]parser{
+	JSONC:       parseJSONC,
+	JSON5:       parseJSONC,
+	YAML:        parseYAML,
+	Properties:  parseProperties,
+	PO:          parsePO,
+	Android:     parseAndroid,
+	IOSStrings:  parseStrings,
+	StringsDict: parseStringsDict,
+	ARB:         parseARB,
+	XLIFF:       parseXLIFF,
+}
+
+var extensions = map[string]string{
+	".json":        JSON,
+	".jsonc":       JSONC,
+	".json5":       JSON5,
+	".yml":         YAML,
+	".yaml":        YAML,
+	".properties":  Properties,
+	".po":          PO,
+	".pot":         PO,
+	".xml":         Android,
+	".strings":     IOSStrings,
+	".stringsdict": StringsDict,
+	".arb":         ARB,
+	".xlf":         XLIFF,
+	".xliff":       XLIFF,
+}
+
+var contentTypes = map[string]string{
+	JSON:        "application/json",
+	JSONC:       "application/json",
+	JSON5:       "application/json5",
+	YAML:        "application/yaml",
+	Properties:  "text/x-java-properties",
+	PO:          "text/x-gettext-translation",
+	Android:     "application/xml",
+	IOSStrings:  "text/plain",
+	StringsDict: "application/xml",
+	ARB:         "application/json",
+	XLIFF:       xliff.ContentType,
+}
+
+// Detect 按文件的扩展名推断格式，无法识别时返回空字符串
+func Detect(filename string) string {
+	return extensions[strings.ToLower(path.Ext(filename))]
+}
+
+// IsSupported 是否支持这个格式，空字符串等同于 json
+func IsSupported(format string) bool {
+	_, ok := contentTypes[format]
+	return ok || format == ""
+}
+
+// IsJSON 是否按 JSON 翻译，空字符串等同于 json
+func IsJSON(format string) bool {
+	return format == "" || format == JSON
+}
+
+// ContentType 格式对应的 Content-Type，用于上传到 Storage 和下载译文
+func ContentType(format string) string {
+	if contentType, ok := contentTypes[format]; ok {
+		return contentType
+	}
+	return contentTypes[JSON]
+}
+
+// Extension 下载译文时文件名使用的扩展名
+func Extension(format string) string {
+	switch format {
+	case "", JSON:
+		return ".json"
+	case YAML:
+		return ".yml"
+	case Android:
+		return ".xml"
+	case XLIFF:
+		return ".xlf"
+	}
+	return "." + format
+}
+
+// Parse 按格式解析文件内容，json 格式不需要解析，直接交给 pkg/translate
+func Parse(format string, content string, sourceLang string) (Document, error) {
+	parse, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file format %q", format)
+	}
+	if !utf8.ValidString(content) {
+		return nil, errors.New("the file must be UTF-8 encoded")
+	}
+
+	// BOM 不交给各个格式的解析器，写回时再加上
+	if body, ok := strings.CutPrefix(content, "\ufeff"); ok {
+		doc, err := parse(body, sourceLang)
+		if err != nil {
+			return nil, err
+		}
+		return bomDocument{doc}, nil
+	}
+	return parse(content, sourceLang)
+}
+
+// Translate 解析文件后翻译其中的字符串，再写回原来的格式，返回的 JsonResult.JSON 是写回后的文件内容
+func Translate(ctx context.Context, format string, content string, config models.Config) (*translate.JsonResult, error) {
+	doc, err := Parse(format, content, config.SourceLang)
+	if err != nil {
+		return nil, err
+	}
+
+	config.SourceData = doc.Tree()
+	if config.CommentContext {
+		config.Comments = comments(doc)
+	}
+	translated, report, err := translate.TranslateJSON(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Strict && len(report.Failed) > 0 {
+		return nil, &translate.PartialError{Failed: report.Failed}
+	}
+
+	output, err := doc.Render(translated, config.TargetLang)
+	if err != nil {
+		return nil, err
+	}
+	return &translate.JsonResult{JSON: output, Report: *report}, nil
+}
+
+// Analyze 统计文件中需要翻译的字符数和自动跳过的字符串，与 Translate 实际翻译的字符串一致
+func Analyze(format string, content string, config models.Config) (*translate.JsonStats, error) {
+	doc, err := Parse(format, content, config.SourceLang)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(doc.Tree())
+	if err != nil {
+		return nil, err
+	}
+	return translate.AnalyzeJson(string(data), config)
+}
+
+// comments 返回文档中字符串的注释，不支持注释的格式返回 nil
+func comments(doc Document) map[string]string {
+	if bom, ok := doc.(bomDocument); ok {
+		doc = bom.Document
+	}
+	if c, ok := doc.(commented); ok {
+		return c.Comments()
+	}
+	return nil
+}
+
+type bomDocument struct {
+	Document
+}
+
+func (d bomDocument) Render(translated interface{}, targetLang string) (string, error) {
+	output, err := d.Document.Render(translated, targetLang)
+	return "\ufeff" + output, err
+}
+
+// encoder 用译文生成替换原文的内容，返回 false 时原样写回原文
+type encoder func(value interface{}, lang string) (string, bool)
+
+// replacement 原文件中需要替换的一段，path 是译文在树中的位置，string 是对象的 key，int 是数组的下标，
+// path 为空时不对应树中的值，例如 PO 文件头中的语言
+type replacement struct {
+	start, end int
+	path       []interface{}
+	encode     encoder
+}
+
+// template 记录原文件中需要替换的位置，写回时其余的内容原样保留，用于逐行或者按标签解析的格式
+type template struct {
+	tree         *orderedmap.OrderedMap
+	content      string
+	replacements []replacement
+}
+
+func newTemplate(content string) *template {
+	return &template{tree: orderedmap.New(), content: content}
+}
+
+func (t *template) Tree() *orderedmap.OrderedMap {
+	return t.tree
+}
+
+// replace 记录 content[start:end] 需要用译文替换
+func (t *template) replace(start, end int, path []interface{}, encode encoder) {
+	t.replacements = append(t.replacements, replacement{start: start, end: end, path: path, encode: encode})
+}
+
+func (t *template) Render(translated interface{}, lang string) (string, error) {
+	sort.SliceStable(t.replacements, func(i, j int) bool {
+		return t.replacements[i].start < t.replacements[j].start
+	})
+
+	var b strings.Builder
+	last := 0
+	for _, r := range t.replacements {
+		b.WriteString(t.content[last:r.start])
+		last = r.end
+
+		var value interface{}
+		if r.path != nil {
+			var ok bool
+			if value, ok = lookup(translated, r.path); !ok {
+				b.WriteString(t.content[r.start:r.end])
+				continue
+			}
+			// 没有翻译的字符串原样写回，保留原文的转义和续行
+			if source, _ := lookup(t.tree, r.path); source == value {
+				if _, ok := value.(string); ok {
+					b.WriteString(t.content[r.start:r.end])
+					continue
+				}
+			}
+		}
+		text, ok := r.encode(value, lang)
+		if !ok {
+			text = t.content[r.start:r.end]
+		}
+		b.WriteString(text)
+	}
+	b.WriteString(t.content[last:])
+	return b.String(), nil
+}
+
+// text 只接受字符串译文的 encoder
+func text(encode func(s string) string) encoder {
+	return func(value interface{}, lang string) (string, bool) {
+		s, ok := value.(string)
+		if !ok {
+			return "", false
+		}
+		return encode(s), true
+	}
+}
+
+// lookup 按路径取出翻译后的树中的值
+func lookup(value interface{}, path []interface{}) (interface{}, bool) {
+	for _, step := range path {
+		switch step := step.(type) {
+		case string:
+			obj, ok := asOrderedMap(value)
+			if !ok {
+				return nil, false
+			}
+			if value, ok = obj.Get(step); !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := value.([]interface{})
+			if !ok || step >= len(arr) {
+				return nil, false
+			}
+			value = arr[step]
+		}
+	}
+	return value, true
+}
+
+func asOrderedMap(value interface{}) (*orderedmap.OrderedMap, bool) {
+	switch v := value.(type) {
+	case *orderedmap.OrderedMap:
+		return v, true
+	case orderedmap.OrderedMap:
+		return &v, true
+	}
+	return nil, false
+}
+
+// pluralForm 复数块中一个类别的译文
+type pluralForm struct {
+	category string
+	text     string
+}
+
+// pluralForms 按目标语言的复数类别排列复数块的译文，目标语言缺少的类别使用 other 的译文，
+// 原文中有、目标语言没有的类别去掉，未知的语言保持原文的类别
+func pluralForms(value interface{}, lang string) ([]pluralForm, bool) {
+	forms, ok := asOrderedMap(value)
+	if !ok {
+		return nil, false
+	}
+
+	categories := translate.PluralCategories(lang)
+	if categories == nil {
+		categories = forms.Keys()
+	}
+	other, hasOther := forms.Get("other")
+
+	result := make([]pluralForm, 0, len(categories))
+	for _, category := range categories {
+		value, ok := forms.Get(category)
+		if !ok && hasOther {
+			value, ok = other, true
+		}
+		s, isString := value.(string)
+		if !ok || !isString {
+			return nil, false
+		}
+		result = append(result, pluralForm{category: category, text: s})
+	}
+	return result, true
+}
+
+// isPluralBlock 是否是按复数类别区分的字符串，key 都是复数类别并且包含 other
+func isPluralBlock(keys []string) bool {
+	hasOther := false
+	for _, key := range keys {
+		if !translate.IsPluralCategory(key) {
+			return false
+		}
+		hasOther = hasOther || key == "other"
+	}
+	return hasOther
+}
+
+// unescapeC 解析 PO 和 iOS .strings 中 C 风格的转义，\u 和 \U 后面是 4 位十六进制
+func unescapeC(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '0':
+			b.WriteByte(0)
+		case 'u', 'U':
+			r, size := decodeHex4(s[i+1:])
+			if size == 0 {
+				b.WriteByte(s[i])
+				continue
+			}
+			b.WriteRune(r)
+			i += size
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// decodeHex4 解析 4 位十六进制的字符，后面紧跟低代理项时组成一个字符，返回字符和使用的长度
+func decodeHex4(s string) (rune, int) {
+	r, ok := parseHex4(s)
+	if !ok {
+		return 0, 0
+	}
+	if r >= 0xD800 && r < 0xDC00 && len(s) >= 10 && s[4] == '\\' && (s[5] == 'u' || s[5] == 'U') {
+		if low, ok := parseHex4(s[6:]); ok && low >= 0xDC00 && low < 0xE000 {
+			return (r-0xD800)<<10 + (low - 0xDC00) + 0x10000, 10
+		}
+	}
+	return r, 4
+}
+
+func parseHex4(s string) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range s[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			r = r<<4 | (c - '0')
+		case c >= 'a' && c <= 'f':
+			r = r<<4 | (c - 'a' + 10)
+		case c >= 'A' && c <= 'F':
+			r = r<<4 | (c - 'A' + 10)
+		default:
+			return 0, false
+		}
+	}
+	return r, true
+}
+
+var cEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+// escapeC 转义成 PO 和 iOS .strings 的双引号字符串的内容
+func escapeC(s string) string {
+	return cEscaper.Replace(s)
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeXML 转义 XML 文本，引号不需要转义，保持和手写的文件一致
+func escapeXML(s string) string {
+	return xmlEscaper.Replace(s)
+}
+
+// quoteJSON 编码成 JSON 字符串，不转义 HTML 字符
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// lineIndent 返回 offset 所在行开头的空白
+func lineIndent(content string, offset int) string {
+	start := strings.LastIndexByte(content[:offset], '\n') + 1
+	indent := content[start:offset]
+	if strings.TrimLeft(indent, " \t") != "" {
+		return ""
+	}
+	return indent
+}
+
+// lineNumber 返回 offset 所在的行号，用于错误提示
+func lineNumber(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
+
+// newline 文件使用的换行符，生成多行内容时保持一致
+func newline(content string) string {
+	if strings.Contains(content, "\r\n") {
+		return "\r\n"
+	}
+	return "\n"
+}
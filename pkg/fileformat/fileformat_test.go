@@ -0,0 +1,541 @@
+package fileformat
+
+import (
+	"context"
+	"encoding/json"
+	"json_trans_api/models/models"
+	"json_trans_api/pkg/translate"
+	"json_trans_api/pkg/xliff"
+	"json_trans_api/utils/translateapi"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// upper 把树中的字符串转为大写，代替翻译服务商的译文
+func upper(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.ToUpper(v)
+	case *orderedmap.OrderedMap:
+		result := orderedmap.New()
+		for _, key := range v.Keys() {
+			item, _ := v.Get(key)
+			result.Set(key, upper(item))
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = upper(item)
+		}
+		return result
+	}
+	return value
+}
+
+func TestParseAndRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		input    string
+		lang     string
+		wantTree string
+		want     string
+	}{
+		{
+			name:   "properties",
+			format: Properties,
+			input: "# Greetings\n" +
+				"greeting = Hello \\\n" +
+				"    world\n" +
+				"! another comment\n" +
+				"app.title:Caf\\u00e9\n" +
+				"path=C:\\\\temp\n" +
+				"empty=\n",
+			lang:     "de",
+			wantTree: `{"greeting":"Hello world","app.title":"Café","path":"C:\\temp","empty":""}`,
+			want: "# Greetings\n" +
+				"greeting = HELLO WORLD\n" +
+				"! another comment\n" +
+				"app.title:CAF\\u00c9\n" +
+				"path=C:\\\\TEMP\n" +
+				"empty=\n",
+		},
+		{
+			name:   "jsonc",
+			format: JSONC,
+			input: "// Settings screen\n" +
+				"{\n" +
+				"  // Shown on the save button\n" +
+				"  \"save\": \"Save\", // keep it short\n" +
+				"  \"count\": 3,\n" +
+				"  /* Navigation\n" +
+				"   * menu */\n" +
+				"  \"nav\": {\"home\": \"Home\", \"links\": [\"About\", null,],},\n" +
+				"  \"tag\": \"<b>Bold</b>\",\n" +
+				"}\n",
+			lang:     "de",
+			wantTree: `{"save":"Save","nav":{"home":"Home","links":["About",null]},"tag":"\u003cb\u003eBold\u003c/b\u003e"}`,
+			want: "// Settings screen\n" +
+				"{\n" +
+				"  // Shown on the save button\n" +
+				"  \"save\": \"SAVE\", // keep it short\n" +
+				"  \"count\": 3,\n" +
+				"  /* Navigation\n" +
+				"   * menu */\n" +
+				"  \"nav\": {\"home\": \"HOME\", \"links\": [\"ABOUT\", null,],},\n" +
+				"  \"tag\": \"<B>BOLD</B>\",\n" +
+				"}\n",
+		},
+		{
+			name:   "json5",
+			format: JSON5,
+			input: "{\n" +
+				"  title: 'It\\'s \"here\"',\n" +
+				"  hex: 0x1F, inf: -Infinity, half: .5,\n" +
+				"  'long': \"one \\\n" +
+				"two\",\n" +
+				"  $caf\u00e9: '\\u00e9t\u00e9',\n" +
+				"}",
+			lang:     "de",
+			wantTree: `{"title":"It's \"here\"","long":"one two","$café":"été"}`,
+			want: "{\n" +
+				"  title: 'IT\\'S \"HERE\"',\n" +
+				"  hex: 0x1F, inf: -Infinity, half: .5,\n" +
+				"  'long': \"ONE TWO\",\n" +
+				"  $caf\u00e9: '\u00c9T\u00c9',\n" +
+				"}",
+		},
+		{
+			name:   "po",
+			format: PO,
+			input: "# Translation template\n" +
+				"msgid \"\"\n" +
+				"msgstr \"\"\n" +
+				"\"Language: en\\n\"\n" +
+				"\"Plural-Forms: nplurals=2; plural=(n != 1);\\n\"\n" +
+				"\n" +
+				"#: src/app.c:10\n" +
+				"msgctxt \"menu\"\n" +
+				"msgid \"Open\"\n" +
+				"msgstr \"\"\n" +
+				"\n" +
+				"#, c-format\n" +
+				"msgid \"%d file\"\n" +
+				"msgid_plural \"%d files\"\n" +
+				"msgstr[0] \"\"\n" +
+				"msgstr[1] \"\"\n" +
+				"\n" +
+				"msgid \"\"\n" +
+				"\"Line one\\n\"\n" +
+				"\"Line two\"\n" +
+				"msgstr \"\"\n",
+			lang:     "ru",
+			wantTree: `{"menu|Open":"Open","%d file":{"one":"%d file","other":"%d files"},"Line one\nLine two":"Line one\nLine two"}`,
+			want: "# Translation template\n" +
+				"msgid \"\"\n" +
+				"msgstr \"\"\n" +
+				"\"Language: ru\\n\"\n" +
+				"\"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\\n\"\n" +
+				"\n" +
+				"#: src/app.c:10\n" +
+				"msgctxt \"menu\"\n" +
+				"msgid \"Open\"\n" +
+				"msgstr \"OPEN\"\n" +
+				"\n" +
+				"#, c-format\n" +
+				"msgid \"%d file\"\n" +
+				"msgid_plural \"%d files\"\n" +
+				"msgstr[0] \"%D FILE\"\n" +
+				"msgstr[1] \"%D FILES\"\n" +
+				"msgstr[2] \"%D FILES\"\n" +
+				"\n" +
+				"msgid \"\"\n" +
+				"\"Line one\\n\"\n" +
+				"\"Line two\"\n" +
+				"msgstr \"\"\n" +
+				"\"LINE ONE\\n\"\n" +
+				"\"LINE TWO\"\n",
+		},
+		{
+			name:   "android",
+			format: Android,
+			input: `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <!-- Main screen -->
+    <string name="app_name" translatable="false">Acme</string>
+    <string name="title">Don\'t panic &amp; relax</string>
+    <string name="alias">@string/title</string>
+    <string name="bold">Hello <b>world</b></string>
+    <string-array name="planets">
+        <item>Mercury</item>
+        <item>Venus</item>
+    </string-array>
+    <plurals name="files">
+        <item quantity="one">%d file</item>
+        <item quantity="other">%d files</item>
+    </plurals>
+</resources>
+`,
+			lang:     "ru",
+			wantTree: `{"title":"Don't panic \u0026 relax","bold":"Hello \u003cb\u003eworld\u003c/b\u003e","planets":["Mercury","Venus"],"files":{"one":"%d file","other":"%d files"}}`,
+			want: `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <!-- Main screen -->
+    <string name="app_name" translatable="false">Acme</string>
+    <string name="title">DON\'T PANIC &amp; RELAX</string>
+    <string name="alias">@string/title</string>
+    <string name="bold">HELLO <B>WORLD</B></string>
+    <string-array name="planets">
+        <item>MERCURY</item>
+        <item>VENUS</item>
+    </string-array>
+    <plurals name="files">
+        <item quantity="one">%D FILE</item>
+        <item quantity="few">%D FILES</item>
+        <item quantity="many">%D FILES</item>
+        <item quantity="other">%D FILES</item>
+    </plurals>
+</resources>
+`,
+		},
+		{
+			name:   "ios strings",
+			format: IOSStrings,
+			input: "/* Title of the main screen */\n" +
+				"\"main.title\" = \"Welcome \\\"home\\\"\";\n" +
+				"// Button\n" +
+				"ok_button = \"OK\\nnow\";\n",
+			lang:     "de",
+			wantTree: `{"main.title":"Welcome \"home\"","ok_button":"OK\nnow"}`,
+			want: "/* Title of the main screen */\n" +
+				"\"main.title\" = \"WELCOME \\\"HOME\\\"\";\n" +
+				"// Button\n" +
+				"ok_button = \"OK\\nNOW\";\n",
+		},
+		{
+			name:   "stringsdict",
+			format: StringsDict,
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>files_count</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@files@</string>
+		<key>files</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>one</key>
+			<string>%d file</string>
+			<key>other</key>
+			<string>%d files</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`,
+			lang:     "ja",
+			wantTree: `{"files_count":{"files":{"one":"%d file","other":"%d files"}}}`,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>files_count</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@files@</string>
+		<key>files</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>other</key>
+			<string>%D FILES</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`,
+		},
+		{
+			name:   "arb",
+			format: ARB,
+			input: `{
+  "@@locale": "en",
+  "title": "Hello",
+  "@title": {
+    "description": "Greeting on the home page"
+  },
+  "count": "{n, plural, one{# item} other{# items}}"
+}
+`,
+			lang:     "fr",
+			wantTree: `{"title":"Hello","count":"{n, plural, one{# item} other{# items}}"}`,
+			want: `{
+  "@@locale": "fr",
+  "title": "HELLO",
+  "@title": {
+    "description": "Greeting on the home page"
+  },
+  "count": "{N, PLURAL, ONE{# ITEM} OTHER{# ITEMS}}"
+}
+`,
+		},
+		{
+			name:   "rails yaml",
+			format: YAML,
+			input: `# Home page
+en:
+  home:
+    title: Welcome # shown in the header
+    items:
+      - First
+      - 2
+    enabled: true
+    files:
+      one: "%{count} file"
+      other: "%{count} files"
+`,
+			lang:     "ru",
+			wantTree: `{"home":{"title":"Welcome","items":["First",null],"files":{"one":"%{count} file","other":"%{count} files"}}}`,
+			want: `# Home page
+ru:
+  home:
+    title: WELCOME # shown in the header
+    items:
+      - FIRST
+      - 2
+    enabled: true
+    files:
+      one: "%{COUNT} FILE"
+      few: "%{COUNT} FILES"
+      many: "%{COUNT} FILES"
+      other: "%{COUNT} FILES"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(tt.format, tt.input, "en")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			tree, _ := json.Marshal(doc.Tree())
+			if string(tree) != tt.wantTree {
+				t.Errorf("Tree() = %s, want %s", tree, tt.wantTree)
+			}
+
+			got, err := doc.Render(upper(doc.Tree()), tt.lang)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %s, want %s", got, tt.want)
+			}
+
+			// 译文和原文相同时写回的内容不变
+			if tt.format != YAML && tt.format != PO {
+				same, _ := doc.Render(doc.Tree(), "")
+				if same != tt.input {
+					t.Errorf("Render() without changes = %s, want %s", same, tt.input)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{PO, "msgid \"Hello\"\n\nmsgid \"World\"\nmsgstr \"\"\n"},
+		{IOSStrings, "\"key\" = \"value\""},
+		{Android, "<resources><string name=\"a\">x</resources>"},
+		{ARB, `["not", "an", "object"]`},
+		{JSONC, `["not", "an", "object"]`},
+		{JSONC, `{"a": "b" "c": "d"}`},
+		{JSON5, `{a: 'unterminated}`},
+		{JSON5, `{a: undefined}`},
+		{JSON5, `{a: 1} /* unterminated`},
+		{YAML, "- a\n- b\n"},
+		{XLIFF, `<xliff version="3.0"><file></file></xliff>`},
+		{XLIFF, `<xliff version="1.2"><file><body><trans-unit id="a"><source>a <g id="1">b</g></source></trans-unit></body></file></xliff>`},
+		{"csv", "a,b"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.format, tt.input, "en"); err == nil {
+			t.Errorf("Parse(%s, %q) error = nil, want error", tt.format, tt.input)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		"messages.PO":           PO,
+		"values/strings.xml":    Android,
+		"Localizable.strings":   IOSStrings,
+		"Plurals.stringsdict":   StringsDict,
+		"config/locales/en.yml": YAML,
+		"app_en.arb":            ARB,
+		"en.json":               JSON,
+		"tsconfig.jsonc":        JSONC,
+		"en.JSON5":              JSON5,
+		"messages.de.xlf":       XLIFF,
+		"export.xliff":          XLIFF,
+		"notes.txt":             "",
+	}
+	for filename, want := range tests {
+		if got := Detect(filename); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
+
+func TestTranslatePseudoLocale(t *testing.T) {
+	// 伪本地化不调用翻译服务商，设置服务商只用于读取批次的限制
+	translateapi.SetTranslator(&translateapi.GoogleTranslator{})
+	input := "\ufeff# Labels\nsave=Save\n"
+	got, err := Translate(context.Background(), Properties, input, models.Config{SourceLang: "en", TargetLang: translate.PseudoLocale})
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if want := "\ufeff# Labels\nsave=[\\u0160\\u00e1\\u1e7d\\u00e9 ~~]\n"; got.JSON != want {
+		t.Errorf("Translate() = %q, want %q", got.JSON, want)
+	}
+	if len(got.Translated) != 1 || got.Translated[0] != "save" {
+		t.Errorf("Translated = %v, want [save]", got.Translated)
+	}
+
+	stats, err := Analyze(Properties, input, models.Config{SourceLang: "en", TargetLang: "de"})
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if stats.Chars != 4 {
+		t.Errorf("Analyze() chars = %d, want 4", stats.Chars)
+	}
+}
+
+func TestJSONCComments(t *testing.T) {
+	input := "{\n" +
+		"  // Button labels\n" +
+		"  buttons: {\n" +
+		"    /** Confirms the dialog */\n" +
+		"    ok: 'OK', // two letters at most\n" +
+		"    cancel: 'Cancel',\n" +
+		"  },\n" +
+		"  items: [\n" +
+		"    // first\n" +
+		"    'One',\n" +
+		"  ],\n" +
+		"  plain: 'Text',\n" +
+		"}\n"
+	doc, err := Parse(JSON5, "\ufeff"+input, "en")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	got := comments(doc)
+	want := map[string]string{
+		"buttons.ok":     "Button labels Confirms the dialog two letters at most",
+		"buttons.cancel": "Button labels",
+		"items[0]":       "first",
+	}
+	if len(got) != len(want) {
+		t.Errorf("comments() = %v, want %v", got, want)
+	}
+	for path, comment := range want {
+		if got[path] != comment {
+			t.Errorf("comments()[%q] = %q, want %q", path, got[path], comment)
+		}
+	}
+}
+
+func TestXLIFFRoundTrip(t *testing.T) {
+	for _, version := range []string{xliff.Version12, xliff.Version20} {
+		t.Run(version, func(t *testing.T) {
+			// 导出的文件中已有的 target 不使用，按 source 重新翻译
+			exported, err := xliff.Encode(xliff.Document{
+				Version:    version,
+				SourceLang: "en",
+				TargetLang: "fr",
+				Original:   "app.json",
+				Units: []xliff.Unit{
+					{Id: "nav.home", Source: "Home", Target: "Accueil"},
+					{Id: "items[0]", Source: " <b>First</b>\n"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			doc, err := Parse(XLIFF, string(exported), "en")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			tree, _ := json.Marshal(doc.Tree())
+			if want := `{"nav.home":"Home","items[0]":" \u003cb\u003eFirst\u003c/b\u003e\n"}`; string(tree) != want {
+				t.Errorf("Tree() = %s, want %s", tree, want)
+			}
+
+			output, err := doc.Render(upper(doc.Tree()), "de")
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			got, err := xliff.Decode(strings.NewReader(output))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			want := &xliff.Document{
+				Version:    version,
+				SourceLang: "en",
+				TargetLang: "de",
+				Original:   "app.json",
+				Units: []xliff.Unit{
+					{Id: "nav.home", Source: "Home", Target: "HOME"},
+					{Id: "items[0]", Source: " <b>First</b>\n", Target: " <B>FIRST</B>\n"},
+				},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode(Render()) = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	// 译员工具生成的没有 target 的文件，group 中的 unit 也翻译
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="messages" source-language="en" datatype="plaintext">
+    <body>
+      <trans-unit id="greeting"><source>Hello</source></trans-unit>
+      <group id="menu">
+        <trans-unit id="menu.save"><source>Save</source></trans-unit>
+      </group>
+    </body>
+  </file>
+</xliff>`
+	doc, err := Parse(XLIFF, input, "en")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	output, err := doc.Render(upper(doc.Tree()), "de")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	got, err := xliff.Decode(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	wantUnits := []xliff.Unit{{Id: "greeting", Source: "Hello", Target: "HELLO"}, {Id: "menu.save", Source: "Save", Target: "SAVE"}}
+	if got.Original != "messages" || got.TargetLang != "de" || !reflect.DeepEqual(got.Units, wantUnits) {
+		t.Errorf("Decode(Render()) = %+v, want units %+v", got, wantUnits)
+	}
+}
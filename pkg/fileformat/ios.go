@@ -0,0 +1,287 @@
+package fileformat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"json_trans_api/pkg/translate"
+	"regexp"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// parseStrings 解析 iOS .strings，每一条是 "key" = "value";，注释和空白原样保留，只替换 value
+func parseStrings(content string, sourceLang string) (Document, error) {
+	t := newTemplate(content)
+	encode := text(func(s string) string {
+		return `"` + escapeC(s) + `"`
+	})
+
+	i := 0
+	for {
+		var err error
+		if i, err = skipStringsSpace(content, i); err != nil {
+			return nil, err
+		}
+		if i == len(content) {
+			return t, nil
+		}
+
+		key, next, err := readStringsToken(content, i)
+		if err != nil {
+			return nil, err
+		}
+		if i, err = expectStrings(content, next, '='); err != nil {
+			return nil, err
+		}
+		if i, err = skipStringsSpace(content, i); err != nil {
+			return nil, err
+		}
+		valueStart := i
+		if i >= len(content) || content[i] != '"' {
+			return nil, fmt.Errorf("line %d: expected a quoted value", lineNumber(content, i))
+		}
+		value, valueEnd, err := readStringsToken(content, i)
+		if err != nil {
+			return nil, err
+		}
+		if i, err = expectStrings(content, valueEnd, ';'); err != nil {
+			return nil, err
+		}
+
+		t.tree.Delete(key)
+		t.tree.Set(key, value)
+		t.replace(valueStart, valueEnd, []interface{}{key}, encode)
+	}
+}
+
+// skipStringsSpace 跳过空白和 /* */、// 注释
+func skipStringsSpace(content string, i int) (int, error) {
+	for i < len(content) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(content[i])):
+			i++
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				return 0, fmt.Errorf("line %d: unterminated comment", lineNumber(content, i))
+			}
+			i += end + 4
+		case strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				return len(content), nil
+			}
+			i += end + 1
+		default:
+			return i, nil
+		}
+	}
+	return i, nil
+}
+
+// expectStrings 跳过空白和注释后需要是指定的分隔符，返回分隔符之后的位置
+func expectStrings(content string, i int, delim byte) (int, error) {
+	i, err := skipStringsSpace(content, i)
+	if err != nil {
+		return 0, err
+	}
+	if i >= len(content) || content[i] != delim {
+		return 0, fmt.Errorf("line %d: expected %q", lineNumber(content, i), delim)
+	}
+	return i + 1, nil
+}
+
+var stringsIdentifier = regexp.MustCompile(`^[A-Za-z0-9_.\-$]+`)
+
+// readStringsToken 读取双引号字符串或者不带引号的 key，返回解析后的内容和结束的位置
+func readStringsToken(content string, i int) (string, int, error) {
+	if content[i] != '"' {
+		word := stringsIdentifier.FindString(content[i:])
+		if word == "" {
+			return "", 0, fmt.Errorf("line %d: expected a quoted string", lineNumber(content, i))
+		}
+		return word, i + len(word), nil
+	}
+
+	for j := i + 1; j < len(content); j++ {
+		switch content[j] {
+		case '\\':
+			j++
+		case '"':
+			return unescapeC(content[i+1 : j]), j + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("line %d: unterminated string", lineNumber(content, i))
+}
+
+// plistNode .stringsdict 中的一个 plist 值，start 和 end 是整个元素的范围，dict 的条目按顺序保存在 entries
+type plistNode struct {
+	kind       string
+	start, end int
+	text       string
+	entries    []plistEntry
+}
+
+// plistEntry dict 中的一个条目，keyStart 和 keyEnd 是 <key> 元素的范围
+type plistEntry struct {
+	key      string
+	keyStart int
+	keyEnd   int
+	value    *plistNode
+}
+
+const (
+	stringsDictFormatKey = "NSStringLocalizedFormatKey"
+	stringsDictSpecType  = "NSStringFormatSpecTypeKey"
+	stringsDictPlural    = "NSStringPluralRuleType"
+)
+
+// stringsDictVariable NSStringLocalizedFormatKey 中引用的变量，例如 %#@files@
+var stringsDictVariable = regexp.MustCompile(`%#@[^@]*@`)
+
+// parseStringsDict 解析 iOS .stringsdict，翻译 NSStringLocalizedFormatKey 和各个复数变量的分支，
+// 复数分支按目标语言的复数类别重新生成，其余内容原样保留
+func parseStringsDict(content string, sourceLang string) (Document, error) {
+	root, err := readPlist(content)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTemplate(content)
+	for _, entry := range root.entries {
+		if entry.value.kind != "dict" {
+			continue
+		}
+		keyTree := orderedmap.New()
+		for _, field := range entry.value.entries {
+			path := []interface{}{entry.key, field.key}
+			switch {
+			case field.key == stringsDictFormatKey && field.value.kind == "string":
+				// 只有变量引用的格式不需要翻译
+				if strings.TrimSpace(stringsDictVariable.ReplaceAllString(field.value.text, "")) == "" {
+					continue
+				}
+				keyTree.Set(field.key, field.value.text)
+				t.replace(field.value.start, field.value.end, path, text(func(s string) string {
+					return "<string>" + escapeXML(s) + "</string>"
+				}))
+			case field.value.kind == "dict" && plistString(field.value, stringsDictSpecType) == stringsDictPlural:
+				if forms := stringsDictPluralForms(content, field.value, path, t); len(forms.Keys()) > 0 {
+					keyTree.Set(field.key, forms)
+				}
+			}
+		}
+		if len(keyTree.Keys()) > 0 {
+			t.tree.Set(entry.key, keyTree)
+		}
+	}
+	return t, nil
+}
+
+// stringsDictPluralForms 收集复数变量的各个分支，从第一个分支的 <key> 到最后一个分支的 </string> 整段替换，
+// 夹在中间的其它条目写在分支前面
+func stringsDictPluralForms(content string, variable *plistNode, path []interface{}, t *template) *orderedmap.OrderedMap {
+	forms := orderedmap.New()
+	var categories []plistEntry
+	for _, field := range variable.entries {
+		if translate.IsPluralCategory(field.key) && field.value.kind == "string" {
+			forms.Set(field.key, field.value.text)
+			categories = append(categories, field)
+		}
+	}
+	if len(categories) == 0 {
+		return forms
+	}
+
+	first, last := categories[0], categories[len(categories)-1]
+	var others []string
+	for _, field := range variable.entries {
+		if _, ok := forms.Get(field.key); !ok && field.keyStart > first.keyStart && field.keyStart < last.keyStart {
+			others = append(others, content[field.keyStart:field.value.end])
+		}
+	}
+	indent := "\n" + lineIndent(content, first.keyStart)
+	keySep := content[first.keyEnd:first.value.start]
+
+	t.replace(first.keyStart, last.value.end, path, func(value interface{}, lang string) (string, bool) {
+		translated, ok := pluralForms(value, lang)
+		if !ok {
+			return "", false
+		}
+		lines := append([]string{}, others...)
+		for _, form := range translated {
+			lines = append(lines, "<key>"+form.category+"</key>"+keySep+"<string>"+escapeXML(form.text)+"</string>")
+		}
+		return strings.Join(lines, indent), true
+	})
+	return forms
+}
+
+// plistString 返回 dict 中字符串条目的值
+func plistString(dict *plistNode, key string) string {
+	for _, entry := range dict.entries {
+		if entry.key == key && entry.value.kind == "string" {
+			return entry.value.text
+		}
+	}
+	return ""
+}
+
+// readPlist 读取 plist 的根 dict，记录每个元素在文件中的位置
+func readPlist(content string) (*plistNode, error) {
+	dec := xml.NewDecoder(strings.NewReader(content))
+	for {
+		offset := int(dec.InputOffset())
+		token, err := dec.RawToken()
+		if err == io.EOF {
+			return nil, fmt.Errorf("invalid stringsdict: no root dict")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid stringsdict: %v", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			if start.Name.Local != "dict" {
+				return nil, fmt.Errorf("invalid stringsdict: the root must be a dict")
+			}
+			return readPlistNode(dec, start, offset)
+		}
+	}
+}
+
+func readPlistNode(dec *xml.Decoder, start xml.StartElement, offset int) (*plistNode, error) {
+	node := &plistNode{kind: start.Name.Local, start: offset}
+	var key *plistNode
+	for {
+		childOffset := int(dec.InputOffset())
+		token, err := dec.RawToken()
+		if err != nil {
+			return nil, fmt.Errorf("invalid stringsdict: %v", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := readPlistNode(dec, token, childOffset)
+			if err != nil {
+				return nil, err
+			}
+			if node.kind != "dict" {
+				continue
+			}
+			if child.kind == "key" {
+				key = child
+				continue
+			}
+			if key == nil {
+				return nil, fmt.Errorf("invalid stringsdict: dict value without a key")
+			}
+			node.entries = append(node.entries, plistEntry{key: key.text, keyStart: key.start, keyEnd: key.end, value: child})
+			key = nil
+		case xml.CharData:
+			node.text += string(token)
+		case xml.EndElement:
+			node.end = int(dec.InputOffset())
+			return node, nil
+		}
+	}
+}
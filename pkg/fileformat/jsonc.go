@@ -0,0 +1,411 @@
+package fileformat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// jsoncDocument JSONC 和 JSON5 文件，只替换字符串值，注释、尾随逗号、引号和 key 的写法原样保留
+type jsoncDocument struct {
+	*template
+	comments map[string]string
+}
+
+// Comments 按 a.b[0].c 形式的路径返回字符串前面和同一行后面的注释，包含上层 key 的注释
+func (d *jsoncDocument) Comments() map[string]string {
+	return d.comments
+}
+
+// jsoncParser 按 JSON5 的语法读取文件，JSONC 是其中的子集。pending 是还没有对应到 key 的注释
+type jsoncParser struct {
+	content  string
+	pos      int
+	t        *template
+	comments map[string]string
+	pending  []string
+}
+
+// parseJSONC 解析 JSONC 和 JSON5，支持 // 和 /* */ 注释、尾随逗号、单引号字符串、不带引号的 key、
+// 十六进制数字、Infinity 和 NaN。根节点需要是对象
+func parseJSONC(content string, sourceLang string) (Document, error) {
+	p := &jsoncParser{content: content, t: newTemplate(content), comments: map[string]string{}}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.peek() != '{' {
+		return nil, errors.New("invalid JSONC: the root must be an object")
+	}
+	// 文件开头的注释不属于任何 key
+	p.pending = nil
+
+	root, err := p.object(nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.content) {
+		return nil, fmt.Errorf("line %d: unexpected content after the root object", lineNumber(p.content, p.pos))
+	}
+	p.t.tree = root
+	return &jsoncDocument{template: p.t, comments: p.comments}, nil
+}
+
+func (p *jsoncParser) peek() byte {
+	if p.pos >= len(p.content) {
+		return 0
+	}
+	return p.content[p.pos]
+}
+
+// value 读取一个值，返回树中的值：字符串、包含字符串的对象和数组，其余为 nil。
+// comments 是这个值和上层 key 的注释，对象和数组中的字符串都会带上
+func (p *jsoncParser) value(path []interface{}, tokens []string, comments []string) (interface{}, error) {
+	switch c := p.peek(); c {
+	case '{':
+		obj, err := p.object(path, tokens, comments)
+		if err != nil || len(obj.Keys()) == 0 {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		return p.array(path, tokens, comments)
+	case '"', '\'':
+		start := p.pos
+		s, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		p.t.replace(start, p.pos, path, text(func(s string) string {
+			return quoteJSON5(s, c)
+		}))
+		return s, nil
+	case 0:
+		return nil, fmt.Errorf("line %d: unexpected end of file", lineNumber(p.content, p.pos))
+	}
+	return nil, p.literal()
+}
+
+func (p *jsoncParser) object(path []interface{}, tokens []string, comments []string) (*orderedmap.OrderedMap, error) {
+	p.pos++
+	obj := orderedmap.New()
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.peek() == '}' {
+			p.pos++
+			p.pending = nil
+			return obj, nil
+		}
+
+		own := p.pending
+		p.pending = nil
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.peek() != ':' {
+			return nil, fmt.Errorf("line %d: expected ':' after key %q", lineNumber(p.content, p.pos), key)
+		}
+		p.pos++
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		own = append(own, p.pending...)
+		p.pending = nil
+
+		childPath := append(append([]interface{}{}, path...), key)
+		childTokens := append(append([]string{}, tokens...), key)
+		value, err := p.element(childPath, childTokens, append(append([]string{}, comments...), own...), '}')
+		if err != nil {
+			return nil, err
+		}
+		// 重复的 key 以最后一个为准
+		obj.Delete(key)
+		if value != nil {
+			obj.Set(key, value)
+		}
+	}
+}
+
+// array 读取数组，数组保持下标不变，不需要翻译的元素为 nil，没有需要翻译的元素时返回 nil
+func (p *jsoncParser) array(path []interface{}, tokens []string, comments []string) (interface{}, error) {
+	p.pos++
+	var items []interface{}
+	found := false
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.peek() == ']' {
+			p.pos++
+			p.pending = nil
+			break
+		}
+
+		i := len(items)
+		childPath := append(append([]interface{}{}, path...), i)
+		childTokens := append(append([]string{}, tokens...), "["+strconv.Itoa(i)+"]")
+		value, err := p.element(childPath, childTokens, append(append([]string{}, comments...), p.pending...), ']')
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+		found = found || value != nil
+	}
+	if !found {
+		return nil, nil
+	}
+	return items, nil
+}
+
+// element 读取对象成员或数组元素的值和后面的逗号，值后面同一行的注释也属于这个值
+func (p *jsoncParser) element(path []interface{}, tokens []string, comments []string, closer byte) (interface{}, error) {
+	p.pending = nil
+	value, err := p.value(path, tokens, comments)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipInline()
+	comma := p.peek() == ','
+	if comma {
+		p.pos++
+		p.skipInline()
+	}
+	if strings.HasPrefix(p.content[p.pos:], "//") || strings.HasPrefix(p.content[p.pos:], "/*") {
+		comment, err := p.readComment()
+		if err != nil {
+			return nil, err
+		}
+		if comment != "" {
+			comments = append(comments, comment)
+		}
+	}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	switch {
+	case comma:
+	case p.peek() == ',':
+		p.pos++
+	case p.peek() != closer:
+		return nil, fmt.Errorf("line %d: expected ',' or '%c'", lineNumber(p.content, p.pos), closer)
+	}
+
+	if _, ok := value.(string); ok && len(comments) > 0 {
+		p.comments[jsoncPath(tokens)] = strings.Join(comments, " ")
+	}
+	return value, nil
+}
+
+// key 读取带引号的 key 或者 JSON5 中不带引号的标识符
+func (p *jsoncParser) key() (string, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.readString()
+	}
+	start := p.pos
+	for p.pos < len(p.content) {
+		r, size := utf8.DecodeRuneInString(p.content[p.pos:])
+		if !(r == '_' || r == '$' || unicode.IsLetter(r) || (p.pos > start && unicode.IsDigit(r))) {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return "", fmt.Errorf("line %d: expected a key", lineNumber(p.content, p.pos))
+	}
+	return p.content[start:p.pos], nil
+}
+
+// jsonLiteral 数字、布尔值和 null，数字可以是十六进制、以小数点开头或结尾、带正号，以及 Infinity 和 NaN
+var jsonLiteral = regexp.MustCompile(`^(true|false|null|[+-]?(Infinity|NaN|0[xX][0-9a-fA-F]+|(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?))$`)
+
+func (p *jsoncParser) literal() error {
+	start := p.pos
+	for p.pos < len(p.content) && !strings.ContainsRune(",:]}/ \t\r\n", rune(p.content[p.pos])) {
+		p.pos++
+	}
+	if word := p.content[start:p.pos]; !jsonLiteral.MatchString(word) {
+		return fmt.Errorf("line %d: invalid value %q", lineNumber(p.content, start), word)
+	}
+	return nil
+}
+
+// readString 读取单引号或双引号字符串，支持 JSON5 的转义和反斜杠续行
+func (p *jsoncParser) readString() (string, error) {
+	start := p.pos
+	quote := p.content[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.content) {
+		c := p.content[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\n' || c == '\r':
+			return "", fmt.Errorf("line %d: unterminated string", lineNumber(p.content, start))
+		case c != '\\':
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		p.pos++
+		if p.pos == len(p.content) {
+			break
+		}
+		e, size := utf8.DecodeRuneInString(p.content[p.pos:])
+		p.pos += size
+		switch e {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case 'u':
+			r, size := decodeHex4(p.content[p.pos:])
+			if size == 0 {
+				return "", fmt.Errorf("line %d: invalid \\u escape", lineNumber(p.content, p.pos))
+			}
+			b.WriteRune(r)
+			p.pos += size
+		case 'x':
+			n, err := strconv.ParseUint(p.content[p.pos:min(p.pos+2, len(p.content))], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("line %d: invalid \\x escape", lineNumber(p.content, p.pos))
+			}
+			b.WriteRune(rune(n))
+			p.pos += 2
+		case '\r':
+			// 续行，\r\n 一起跳过
+			if p.peek() == '\n' {
+				p.pos++
+			}
+		case '\n', '\u2028', '\u2029':
+		default:
+			b.WriteRune(e)
+		}
+	}
+	return "", fmt.Errorf("line %d: unterminated string", lineNumber(p.content, start))
+}
+
+// skipSpace 跳过空白和注释，注释记入 pending
+func (p *jsoncParser) skipSpace() error {
+	for p.pos < len(p.content) {
+		if strings.HasPrefix(p.content[p.pos:], "//") || strings.HasPrefix(p.content[p.pos:], "/*") {
+			comment, err := p.readComment()
+			if err != nil {
+				return err
+			}
+			if comment != "" {
+				p.pending = append(p.pending, comment)
+			}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(p.content[p.pos:])
+		if !unicode.IsSpace(r) && r != '\ufeff' {
+			return nil
+		}
+		p.pos += size
+	}
+	return nil
+}
+
+// skipInline 跳过同一行中的空格和制表符
+func (p *jsoncParser) skipInline() {
+	for p.pos < len(p.content) && (p.content[p.pos] == ' ' || p.content[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// readComment 读取一条注释，返回去掉注释符号、块注释每行开头的 * 和多余空白后的内容
+func (p *jsoncParser) readComment() (string, error) {
+	if strings.HasPrefix(p.content[p.pos:], "//") {
+		end := strings.IndexByte(p.content[p.pos:], '\n')
+		if end < 0 {
+			end = len(p.content) - p.pos
+		}
+		comment := p.content[p.pos+2 : p.pos+end]
+		p.pos += end
+		return strings.TrimSpace(comment), nil
+	}
+
+	end := strings.Index(p.content[p.pos+2:], "*/")
+	if end < 0 {
+		return "", fmt.Errorf("line %d: unterminated comment", lineNumber(p.content, p.pos))
+	}
+	body := p.content[p.pos+2 : p.pos+2+end]
+	p.pos += end + 4
+
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "*")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " "), nil
+}
+
+// jsoncPath 把 token 拼成和 pkg/translate 一致的 a.b[0].c 形式的路径
+func jsoncPath(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		if !strings.HasPrefix(token, "[") && b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(token)
+	}
+	return b.String()
+}
+
+// quoteJSON5 按原文的引号编码字符串，单引号字符串中双引号不需要转义
+func quoteJSON5(s string, quote byte) string {
+	quoted := quoteJSON(s)
+	if quote == '"' {
+		return quoted
+	}
+
+	var b strings.Builder
+	b.WriteByte('\'')
+	inner := quoted[1 : len(quoted)-1]
+	for i := 0; i < len(inner); i++ {
+		switch {
+		case inner[i] == '\\' && inner[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		case inner[i] == '\\':
+			b.WriteString(inner[i : i+2])
+			i++
+		case inner[i] == '\'':
+			b.WriteString(`\'`)
+		default:
+			b.WriteByte(inner[i])
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
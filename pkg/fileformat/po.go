@@ -0,0 +1,287 @@
+package fileformat
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// poEntry gettext 的一条翻译，strStart 和 strEnd 是 msgstr 所在的行在文件中的范围
+type poEntry struct {
+	ctxt, id, plural  string
+	hasPlural, hasStr bool
+	strStart, strEnd  int
+	line              int
+}
+
+// poPlural 语言的 gettext 复数形式，singular 是单数形式的下标，没有单数形式时为 -1
+type poPlural struct {
+	nplurals int
+	singular int
+	forms    string
+}
+
+// poPluralForms 常用语言的 gettext Plural-Forms，未列出的语言沿用原文件头中的复数个数
+var poPluralForms = map[string]poPlural{}
+
+func init() {
+	register := func(plural poPlural, langs ...string) {
+		for _, lang := range langs {
+			poPluralForms[lang] = plural
+		}
+	}
+	register(poPlural{1, -1, "nplurals=1; plural=0;"}, "zh", "ja", "ko", "vi", "th", "id", "ms", "lo", "km", "my")
+	register(poPlural{2, 0, "nplurals=2; plural=(n != 1);"}, "en", "de", "nl", "sv", "da", "no", "nb", "fi", "et", "el",
+		"hu", "it", "es", "pt", "ca", "bg", "tr", "he", "hi", "bn", "ur", "sw", "af", "az", "ka", "kk", "eu", "gl", "fa", "sq")
+	register(poPlural{2, 0, "nplurals=2; plural=(n > 1);"}, "fr", "fil", "tl")
+	register(poPlural{3, 0, "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);"},
+		"ru", "uk", "be", "sr", "hr", "bs")
+	register(poPlural{3, 0, "nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);"}, "pl")
+	register(poPlural{3, 0, "nplurals=3; plural=(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2;"}, "cs", "sk")
+	register(poPlural{6, 1, "nplurals=6; plural=(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5);"}, "ar")
+}
+
+var poNplurals = regexp.MustCompile(`nplurals\s*=\s*(\d+)`)
+
+// parsePO 解析 gettext .po/.pot 文件，翻译 msgid，写回时只替换 msgstr，注释和引用位置等原样保留。
+// 树的 key 是 msgid，有 msgctxt 时为 msgctxt|msgid；复数的条目是 one 和 other 两个分支，
+// 写回时按目标语言的 Plural-Forms 生成 msgstr[n]，文件头中的 Language 和 Plural-Forms 也换成目标语言
+func parsePO(content string, sourceLang string) (Document, error) {
+	entries, err := readPOEntries(content)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTemplate(content)
+	nl := newline(content)
+	sourcePlural := poPlural{nplurals: 2, singular: 0}
+	for _, entry := range entries {
+		if entry.id != "" || entry.ctxt != "" {
+			continue
+		}
+		// 文件头
+		header := entry
+		if m := poNplurals.FindStringSubmatch(header.plural); m != nil {
+			sourcePlural.nplurals, _ = strconv.Atoi(m[1])
+		}
+		t.replace(entry.strStart, entry.strEnd, nil, func(_ interface{}, lang string) (string, bool) {
+			return poField("msgstr", poHeader(header.plural, lang), nl, true), lang != ""
+		})
+	}
+
+	for _, entry := range entries {
+		if entry.id == "" && entry.ctxt == "" {
+			continue
+		}
+		key := entry.id
+		if entry.ctxt != "" {
+			key = entry.ctxt + "|" + entry.id
+		}
+		path := []interface{}{key}
+
+		if !entry.hasPlural {
+			t.tree.Set(key, entry.id)
+			t.replace(entry.strStart, entry.strEnd, path, text(func(s string) string {
+				return poField("msgstr", s, nl, false)
+			}))
+			continue
+		}
+
+		forms := orderedmap.New()
+		forms.Set("one", entry.id)
+		forms.Set("other", entry.plural)
+		t.tree.Set(key, forms)
+		t.replace(entry.strStart, entry.strEnd, path, func(value interface{}, lang string) (string, bool) {
+			forms, ok := asOrderedMap(value)
+			if !ok {
+				return "", false
+			}
+			one, _ := forms.Get("one")
+			other, _ := forms.Get("other")
+			singular, okOne := one.(string)
+			plural, okOther := other.(string)
+			if !okOne || !okOther {
+				return "", false
+			}
+
+			target, ok := poPluralForms[baseLang(lang)]
+			if !ok {
+				target = sourcePlural
+			}
+			lines := make([]string, target.nplurals)
+			for i := range lines {
+				s := plural
+				if i == target.singular {
+					s = singular
+				}
+				lines[i] = poField(fmt.Sprintf("msgstr[%d]", i), s, nl, false)
+			}
+			return strings.Join(lines, nl), true
+		})
+	}
+	return t, nil
+}
+
+// readPOEntries 逐行读取 PO 文件中的条目，原有的 msgstr 不需要保存，只有文件头的内容保存在 plural 中
+func readPOEntries(content string) ([]*poEntry, error) {
+	var entries []*poEntry
+	var entry *poEntry
+	var field *string
+	inStr := false
+
+	finish := func() error {
+		if entry != nil && !entry.hasStr {
+			return fmt.Errorf("line %d: entry has no msgstr", entry.line)
+		}
+		entry, field, inStr = nil, nil, false
+		return nil
+	}
+
+	for pos, lineNo := 0, 1; pos < len(content); lineNo++ {
+		end := strings.IndexByte(content[pos:], '\n')
+		if end < 0 {
+			end = len(content)
+		} else {
+			end += pos
+		}
+		line := strings.TrimSpace(content[pos:end])
+		lineStart, lineEnd := pos, pos+len(strings.TrimRight(content[pos:end], "\r"))
+		pos = end + 1
+
+		switch {
+		case line == "":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			continue
+		case strings.HasPrefix(line, "#"):
+			// 注释在 msgstr 之后时属于下一条
+			if inStr {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return nil, fmt.Errorf("line %d: unexpected string", lineNo)
+			}
+			s, err := poUnquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			*field += s
+			if inStr {
+				entry.strEnd = lineEnd
+			}
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		value, err := poUnquote(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+
+		if (keyword == "msgctxt" || keyword == "msgid") && (entry == nil || inStr) {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			entry = &poEntry{line: lineNo}
+			entries = append(entries, entry)
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("line %d: unexpected %s", lineNo, keyword)
+		}
+
+		switch {
+		case keyword == "msgctxt":
+			entry.ctxt = value
+			field = &entry.ctxt
+		case keyword == "msgid":
+			entry.id = value
+			field = &entry.id
+		case keyword == "msgid_plural":
+			entry.hasPlural = true
+			entry.plural = value
+			field = &entry.plural
+		case keyword == "msgstr" || strings.HasPrefix(keyword, "msgstr["):
+			if !inStr {
+				entry.strStart = lineStart
+			}
+			entry.hasStr, inStr = true, true
+			entry.strEnd = lineEnd
+			// 文件头的内容在 msgstr 中，保存在 plural 里用于写回
+			if entry.id == "" && entry.ctxt == "" {
+				entry.plural = value
+				field = &entry.plural
+			} else {
+				var discard string
+				field = &discard
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword %q", lineNo, keyword)
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func poUnquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("expected a quoted string, got %q", s)
+	}
+	return unescapeC(s[1 : len(s)-1]), nil
+}
+
+// poField 生成 msgstr 行，包含换行的内容按行拆成多行字符串，文件头总是使用多行的形式
+func poField(keyword string, s string, nl string, multiline bool) string {
+	if !multiline && !strings.Contains(strings.TrimSuffix(s, "\n"), "\n") {
+		return keyword + ` "` + escapeC(s) + `"`
+	}
+
+	var b strings.Builder
+	b.WriteString(keyword + ` ""`)
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			b.WriteString(nl + `"` + escapeC(line) + `"`)
+		}
+	}
+	return b.String()
+}
+
+// poHeader 把文件头中的 Language 和 Plural-Forms 换成目标语言的
+func poHeader(header string, lang string) string {
+	plural, hasPlural := poPluralForms[baseLang(lang)]
+	lines := strings.SplitAfter(header, "\n")
+	for i, line := range lines {
+		name, _, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		eol := line[len(strings.TrimRight(line, "\n")):]
+		switch strings.TrimSpace(name) {
+		case "Language":
+			lines[i] = "Language: " + lang + eol
+		case "Plural-Forms":
+			if hasPlural {
+				lines[i] = "Plural-Forms: " + plural.forms + eol
+			}
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// baseLang 去掉语言代码中的地区，例如 pt-BR 返回 pt
+func baseLang(lang string) string {
+	base := strings.ToLower(lang)
+	if i := strings.IndexAny(base, "-_"); i > 0 {
+		base = base[:i]
+	}
+	return base
+}
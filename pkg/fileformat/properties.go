@@ -0,0 +1,165 @@
+package fileformat
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// parseProperties 解析 Java .properties，key 原样作为树的一层，不按点号拆分。
+// 注释、空行和续行原样保留，只替换每一行的值
+func parseProperties(content string, sourceLang string) (Document, error) {
+	t := newTemplate(content)
+	// 原文只有 ASCII 时译文也用 \uXXXX 转义，兼容按 ISO-8859-1 读取的程序
+	ascii := isASCII(content)
+	encode := text(func(s string) string {
+		return escapeProperties(s, ascii)
+	})
+
+	for pos := 0; pos < len(content); {
+		end := propertiesLineEnd(content, pos)
+		line := strings.TrimSuffix(content[pos:end], "\r")
+
+		i := skipPropertiesSpace(line, 0)
+		if i < len(line) && line[i] != '#' && line[i] != '!' {
+			keyStart := i
+			for i < len(line) && !strings.ContainsRune("=: \t\f", rune(line[i])) {
+				if line[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i > len(line) {
+				return nil, fmt.Errorf("line %d: unexpected end of key", lineNumber(content, pos))
+			}
+			key := unescapeProperties(line[keyStart:i])
+
+			i = skipPropertiesSpace(line, i)
+			if i < len(line) && (line[i] == '=' || line[i] == ':') {
+				i = skipPropertiesSpace(line, i+1)
+			}
+
+			// 重复的 key 以最后一个值为准，每一处都写入同一个译文
+			value := unescapeProperties(line[i:])
+			t.tree.Delete(key)
+			t.tree.Set(key, value)
+			t.replace(pos+i, pos+len(line), []interface{}{key}, encode)
+		}
+		pos = end + 1
+	}
+	return t, nil
+}
+
+// propertiesLineEnd 返回从 pos 开始的逻辑行的换行符位置，行尾有奇数个反斜杠时和下一行连在一起，注释行不续行
+func propertiesLineEnd(content string, pos int) int {
+	comment := false
+	if i := skipPropertiesSpace(content[pos:], 0); pos+i < len(content) {
+		comment = content[pos+i] == '#' || content[pos+i] == '!'
+	}
+
+	for {
+		end := strings.IndexByte(content[pos:], '\n')
+		if end < 0 {
+			return len(content)
+		}
+		end += pos
+		if comment || trailingBackslashes(strings.TrimSuffix(content[pos:end], "\r"))%2 == 0 {
+			return end
+		}
+		pos = end + 1
+	}
+}
+
+func trailingBackslashes(s string) int {
+	n := 0
+	for n < len(s) && s[len(s)-1-n] == '\\' {
+		n++
+	}
+	return n
+}
+
+func skipPropertiesSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\f') {
+		i++
+	}
+	return i
+}
+
+// unescapeProperties 解析 .properties 的转义和续行，续行开头的空白会被去掉
+func unescapeProperties(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, size := decodeHex4(s[i+1:])
+			if size == 0 {
+				b.WriteByte('u')
+				continue
+			}
+			b.WriteRune(r)
+			i += size
+		case '\r', '\n':
+			if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			i = skipPropertiesSpace(s, i+1) - 1
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// escapeProperties 转义成 .properties 的值，开头的空格需要转义，否则读取时会被去掉
+func escapeProperties(s string, ascii bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && i == 0:
+			b.WriteString(`\ `)
+		case r > 0x7f && ascii:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04x`, unit)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package fileformat
+
+import (
+	"json_trans_api/pkg/xliff"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// xliffDocument XLIFF 1.2 和 2.0，翻译每个 unit 的 source，写回时按原来的版本重新生成文件，
+// 目标语言换成翻译的语言，译文写入 target
+type xliffDocument struct {
+	doc  *xliff.Document
+	tree *orderedmap.OrderedMap
+}
+
+// parseXLIFF 用 pkg/xliff 解析文件，unit 的 id 原样作为树的一层。已有的 target 不使用，全部按 source 重新翻译
+func parseXLIFF(content string, sourceLang string) (Document, error) {
+	doc, err := xliff.DecodeSource(strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	tree := orderedmap.New()
+	for _, unit := range doc.Units {
+		tree.Set(unit.Id, unit.Source)
+	}
+	return &xliffDocument{doc: doc, tree: tree}, nil
+}
+
+func (d *xliffDocument) Tree() *orderedmap.OrderedMap {
+	return d.tree
+}
+
+// Render 没有译文的 unit 的 target 写入原文，2.x 的文件按 2.0 输出，其余按 1.2 输出
+func (d *xliffDocument) Render(translated interface{}, targetLang string) (string, error) {
+	doc := xliff.Document{
+		Version:    xliff.Version12,
+		SourceLang: d.doc.SourceLang,
+		TargetLang: targetLang,
+		Original:   d.doc.Original,
+	}
+	if strings.HasPrefix(d.doc.Version, "2.") {
+		doc.Version = xliff.Version20
+	}
+	for _, unit := range d.doc.Units {
+		target := unit.Source
+		if value, ok := lookup(translated, []interface{}{unit.Id}); ok {
+			if s, ok := value.(string); ok {
+				target = s
+			}
+		}
+		doc.Units = append(doc.Units, xliff.Unit{Id: unit.Id, Source: unit.Source, Target: target})
+	}
+
+	data, err := xliff.Encode(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,232 @@
+package fileformat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlDocument YAML 文件，写回时重新解析原文再替换字符串，注释随节点保留
+type yamlDocument struct {
+	content    string
+	sourceLang string
+	tree       *orderedmap.OrderedMap
+}
+
+// parseYAML 解析 YAML，翻译所有字符串标量，数字、布尔值和别名不翻译。
+// Rails 的文件以源语言代码为唯一的根节点，这时只翻译根节点之下的内容，写回时根节点换成目标语言代码；
+// key 都是复数类别的 mapping 按目标语言的复数类别重新生成
+func parseYAML(content string, sourceLang string) (Document, error) {
+	_, root, _, err := decodeYAML(content, sourceLang)
+	if err != nil {
+		return nil, err
+	}
+	return &yamlDocument{content: content, sourceLang: sourceLang, tree: yamlMapping(root)}, nil
+}
+
+func (d *yamlDocument) Tree() *orderedmap.OrderedMap {
+	return d.tree
+}
+
+func (d *yamlDocument) Render(translated interface{}, targetLang string) (string, error) {
+	doc, root, localeKey, err := decodeYAML(d.content, d.sourceLang)
+	if err != nil {
+		return "", err
+	}
+	applyYAML(root, translated, targetLang)
+	if localeKey != nil && targetLang != "" {
+		localeKey.Value = targetLang
+	}
+
+	var buf bytes.Buffer
+	if strings.HasPrefix(d.content, "---") {
+		buf.WriteString("---\n")
+	}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(yamlIndent(doc.Content[0]))
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// decodeYAML 解析出文档节点和需要翻译的 mapping，Rails 格式时同时返回语言代码的 key 节点
+func decodeYAML(content string, sourceLang string) (*yaml.Node, *yaml.Node, *yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid YAML: %v", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil, nil, errors.New("invalid YAML: the document is empty")
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, nil, errors.New("invalid YAML: the root must be a mapping")
+	}
+	if len(root.Content) == 2 && root.Content[1].Kind == yaml.MappingNode && isSourceLocale(root.Content[0].Value, sourceLang) {
+		return &doc, root.Content[1], root.Content[0], nil
+	}
+	return &doc, root, nil, nil
+}
+
+// isSourceLocale 根节点的 key 是否是源语言代码，en 也匹配 en-US
+func isSourceLocale(key string, sourceLang string) bool {
+	normalize := func(lang string) string {
+		return strings.ReplaceAll(strings.ToLower(lang), "_", "-")
+	}
+	key, sourceLang = normalize(key), normalize(sourceLang)
+	return sourceLang != "" && (key == sourceLang || key == baseLang(sourceLang))
+}
+
+// yamlMapping 把 mapping 转换成树，只保留字符串和包含字符串的节点
+func yamlMapping(node *yaml.Node) *orderedmap.OrderedMap {
+	tree := orderedmap.New()
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode || key.Value == "<<" {
+			continue
+		}
+		if v := yamlValue(value); v != nil {
+			tree.Set(key.Value, v)
+		}
+	}
+	return tree
+}
+
+func yamlValue(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" {
+			return node.Value
+		}
+	case yaml.MappingNode:
+		if tree := yamlMapping(node); len(tree.Keys()) > 0 {
+			return tree
+		}
+	case yaml.SequenceNode:
+		// 数组保持下标不变，不需要翻译的元素为 null
+		items := make([]interface{}, len(node.Content))
+		found := false
+		for i, item := range node.Content {
+			items[i] = yamlValue(item)
+			found = found || items[i] != nil
+		}
+		if found {
+			return items
+		}
+	}
+	return nil
+}
+
+// applyYAML 把译文写回节点，复数块按目标语言的复数类别重新生成
+func applyYAML(node *yaml.Node, value interface{}, lang string) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if s, ok := value.(string); ok && node.ShortTag() == "!!str" {
+			node.Value = s
+		}
+	case yaml.MappingNode:
+		tree, ok := asOrderedMap(value)
+		if !ok {
+			return
+		}
+		if isYAMLPluralBlock(node) {
+			if content, ok := yamlPluralForms(node, tree, lang); ok {
+				node.Content = content
+				return
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if v, ok := tree.Get(node.Content[i].Value); ok && node.Content[i].Kind == yaml.ScalarNode {
+				applyYAML(node.Content[i+1], v, lang)
+			}
+		}
+	case yaml.SequenceNode:
+		items, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for i, item := range node.Content {
+			if i < len(items) {
+				applyYAML(item, items[i], lang)
+			}
+		}
+	}
+}
+
+// isYAMLPluralBlock key 都是复数类别、值都是字符串的 mapping
+func isYAMLPluralBlock(node *yaml.Node) bool {
+	keys := make([]string, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Kind != yaml.ScalarNode || node.Content[i+1].Kind != yaml.ScalarNode || node.Content[i+1].ShortTag() != "!!str" {
+			return false
+		}
+		keys = append(keys, node.Content[i].Value)
+	}
+	return isPluralBlock(keys)
+}
+
+// yamlPluralForms 按目标语言的复数类别生成复数块的 key 和值，已有的类别保留原来的节点和注释，
+// 新增的类别沿用 other 的样式
+func yamlPluralForms(node *yaml.Node, tree *orderedmap.OrderedMap, lang string) ([]*yaml.Node, bool) {
+	forms, ok := pluralForms(tree, lang)
+	if !ok {
+		return nil, false
+	}
+
+	existing := map[string][2]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		existing[node.Content[i].Value] = [2]*yaml.Node{node.Content[i], node.Content[i+1]}
+	}
+	other := existing["other"]
+
+	content := make([]*yaml.Node, 0, len(forms)*2)
+	for _, form := range forms {
+		pair, ok := existing[form.category]
+		if !ok {
+			key := *other[0]
+			value := *other[1]
+			key.Value = form.category
+			key.HeadComment, key.LineComment, key.FootComment = "", "", ""
+			value.HeadComment, value.LineComment, value.FootComment = "", "", ""
+			pair = [2]*yaml.Node{&key, &value}
+		}
+		pair[1].Value = form.text
+		content = append(content, pair[0], pair[1])
+	}
+	return content, true
+}
+
+// yamlIndent 按第一个嵌套的 mapping 推断缩进的空格数，默认 2
+func yamlIndent(node *yaml.Node) int {
+	if indent := findYAMLIndent(node); indent > 0 {
+		return indent
+	}
+	return 2
+}
+
+func findYAMLIndent(node *yaml.Node) int {
+	if node.Kind != yaml.MappingNode {
+		return 0
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 && value.Style&yaml.FlowStyle == 0 {
+			if indent := value.Content[0].Column - key.Column; indent > 0 {
+				return indent
+			}
+		}
+		if indent := findYAMLIndent(value); indent > 0 {
+			return indent
+		}
+	}
+	return 0
+}
@@ -0,0 +1,200 @@
+package glossary
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"json_trans_api/models/models"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 导入术语表支持的文件格式
+const (
+	FormatCSV = "csv"
+	FormatTBX = "tbx"
+)
+
+// 单个术语表最多的术语数量
+const MaxTerms = 5000
+
+// DetectFormat 按文件扩展名判断导入的格式
+func DetectFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".tbx", ".xml":
+		return FormatTBX
+	}
+	return ""
+}
+
+// Parse 按格式解析导入的术语，TBX 按语言对选取原文和译文
+func Parse(format string, r io.Reader, sourceLang string, targetLang string) ([]models.GlossaryTerm, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r)
+	case FormatTBX:
+		return ParseTBX(r, sourceLang, targetLang)
+	}
+	return nil, fmt.Errorf("unsupported glossary format: %s", format)
+}
+
+// ParseCSV 每行为 原文,译文[,do_not_translate[,ignore_case]]，译文为空时不翻译，第一行可以是表头
+func ParseCSV(r io.Reader) ([]models.GlossaryTerm, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var terms []models.GlossaryTerm
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %v", err)
+		}
+
+		if line == 1 && strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(record[0]), "\ufeff"), "source") {
+			continue
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		term := models.GlossaryTerm{Source: strings.TrimPrefix(strings.TrimSpace(record[0]), "\ufeff")}
+		if len(record) > 1 {
+			term.Target = strings.TrimSpace(record[1])
+		}
+		if len(record) > 2 {
+			if term.DoNotTranslate, err = parseBool(record[2]); err != nil {
+				return nil, fmt.Errorf("invalid csv line %d: %v", line, err)
+			}
+		}
+		if len(record) > 3 {
+			if term.IgnoreCase, err = parseBool(record[3]); err != nil {
+				return nil, fmt.Errorf("invalid csv line %d: %v", line, err)
+			}
+		}
+		if term.Target == "" {
+			term.DoNotTranslate = true
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+func parseBool(value string) (bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+type tbxDocument struct {
+	Entries []tbxEntry `xml:"text>body>termEntry"`
+	// TBX v3 使用 conceptEntry/langSec/termSec
+	Concepts []tbxEntry `xml:"text>body>conceptEntry"`
+}
+
+type tbxEntry struct {
+	LangSets []tbxLangSet `xml:"langSet"`
+	LangSecs []tbxLangSet `xml:"langSec"`
+}
+
+type tbxLangSet struct {
+	Lang  string   `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Terms []string `xml:"tig>term"`
+	NTigs []string `xml:"ntig>termGrp>term"`
+	Secs  []string `xml:"termSec>term"`
+}
+
+func (l tbxLangSet) firstTerm() string {
+	for _, terms := range [][]string{l.Terms, l.NTigs, l.Secs} {
+		for _, term := range terms {
+			if term = strings.TrimSpace(term); term != "" {
+				return term
+			}
+		}
+	}
+	return ""
+}
+
+// ParseTBX 每个 termEntry 取原文语言和目标语言的第一个术语，只有原文语言的术语作为不翻译的术语
+func ParseTBX(r io.Reader, sourceLang string, targetLang string) ([]models.GlossaryTerm, error) {
+	var doc tbxDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid tbx: %v", err)
+	}
+
+	var terms []models.GlossaryTerm
+	for _, entry := range append(doc.Entries, doc.Concepts...) {
+		var source, target string
+		for _, langSet := range append(entry.LangSets, entry.LangSecs...) {
+			switch {
+			case matchLang(langSet.Lang, sourceLang) && source == "":
+				source = langSet.firstTerm()
+			case targetLang != "" && matchLang(langSet.Lang, targetLang) && target == "":
+				target = langSet.firstTerm()
+			}
+		}
+		if source == "" {
+			continue
+		}
+		terms = append(terms, models.GlossaryTerm{Source: source, Target: target, DoNotTranslate: target == ""})
+	}
+	return terms, nil
+}
+
+// matchLang en 可以匹配 en-US，zh-tw 这类带地区的语言需要完全一致
+func matchLang(lang string, want string) bool {
+	lang = strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	want = strings.ToLower(want)
+	return lang == want || (!strings.Contains(want, "-") && strings.HasPrefix(lang, want+"-"))
+}
+
+// ValidateTerms 检查术语：原文不能为空、不能重复，需要翻译的术语必须有译文
+func ValidateTerms(terms []models.GlossaryTerm) error {
+	if len(terms) > MaxTerms {
+		return fmt.Errorf("too many terms: %d, the limit is %d", len(terms), MaxTerms)
+	}
+
+	seen := make(map[string]bool, len(terms))
+	for i, term := range terms {
+		source := strings.TrimSpace(term.Source)
+		if source == "" {
+			return fmt.Errorf("term %d: source is empty", i+1)
+		}
+		if !term.DoNotTranslate && strings.TrimSpace(term.Target) == "" {
+			return fmt.Errorf("term %q: target is empty", source)
+		}
+
+		key := source
+		if term.IgnoreCase {
+			key = strings.ToLower(source)
+		}
+		if seen[key] {
+			return fmt.Errorf("term %q is duplicated", source)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Normalize 去掉术语首尾的空白，不翻译的术语不需要译文
+func Normalize(terms []models.GlossaryTerm) []models.GlossaryTerm {
+	normalized := make([]models.GlossaryTerm, len(terms))
+	for i, term := range terms {
+		term.Source = strings.TrimSpace(term.Source)
+		term.Target = strings.TrimSpace(term.Target)
+		if term.DoNotTranslate {
+			term.Target = ""
+		}
+		normalized[i] = term
+	}
+	return normalized
+}
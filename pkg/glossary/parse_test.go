@@ -0,0 +1,90 @@
+package glossary
+
+import (
+	"json_trans_api/models/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := "source,target,do_not_translate,ignore_case\n" +
+		"Acme,,,\n" +
+		"Pro Plan,Pro-Tarif\n" +
+		"cloud,Wolke,false,true\n" +
+		"\"Acme, Inc.\",Acme Inc.,true\n"
+
+	got, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	want := []models.GlossaryTerm{
+		{Source: "Acme", DoNotTranslate: true},
+		{Source: "Pro Plan", Target: "Pro-Tarif"},
+		{Source: "cloud", Target: "Wolke", IgnoreCase: true},
+		{Source: "Acme, Inc.", Target: "Acme Inc.", DoNotTranslate: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCSV() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseCSV(strings.NewReader("a,b,maybe\n")); err == nil {
+		t.Error("ParseCSV() error = nil, want invalid do_not_translate")
+	}
+}
+
+func TestParseTBX(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<martif type="TBX" xml:lang="en">
+  <text><body>
+    <termEntry id="1">
+      <langSet xml:lang="en-US"><tig><term>Pro Plan</term></tig></langSet>
+      <langSet xml:lang="de"><tig><term>Pro-Tarif</term></tig></langSet>
+      <langSet xml:lang="fr"><tig><term>Forfait Pro</term></tig></langSet>
+    </termEntry>
+    <termEntry id="2">
+      <langSet xml:lang="en"><ntig><termGrp><term>Acme</term></termGrp></ntig></langSet>
+    </termEntry>
+    <termEntry id="3">
+      <langSet xml:lang="de"><tig><term>Nur Deutsch</term></tig></langSet>
+    </termEntry>
+  </body></text>
+</martif>`
+
+	got, err := ParseTBX(strings.NewReader(input), "en", "de")
+	if err != nil {
+		t.Fatalf("ParseTBX() error = %v", err)
+	}
+	want := []models.GlossaryTerm{
+		{Source: "Pro Plan", Target: "Pro-Tarif"},
+		{Source: "Acme", DoNotTranslate: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTBX() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseTBX(strings.NewReader("<martif>"), "en", "de"); err == nil {
+		t.Error("ParseTBX() error = nil, want invalid xml")
+	}
+}
+
+func TestValidateTerms(t *testing.T) {
+	tests := []struct {
+		name    string
+		terms   []models.GlossaryTerm
+		wantErr bool
+	}{
+		{name: "valid", terms: []models.GlossaryTerm{{Source: "Acme", DoNotTranslate: true}, {Source: "cloud", Target: "Wolke"}}},
+		{name: "empty source", terms: []models.GlossaryTerm{{Source: " ", Target: "x"}}, wantErr: true},
+		{name: "missing target", terms: []models.GlossaryTerm{{Source: "cloud"}}, wantErr: true},
+		{name: "duplicate ignore case", terms: []models.GlossaryTerm{{Source: "Cloud", Target: "Wolke", IgnoreCase: true}, {Source: "cloud", Target: "Wolke", IgnoreCase: true}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTerms(tt.terms); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTerms() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
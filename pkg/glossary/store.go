@@ -0,0 +1,169 @@
+package glossary
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"json_trans_api/config"
+	"json_trans_api/models/tables"
+	"json_trans_api/pkg/httpclient"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const table = "user_glossaries"
+
+// Get 根据 ID 获取用户的术语表，不存在时返回 nil
+func Get(id string, userid string) (*tables.Glossary, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, nil
+	}
+
+	queryParams := url.Values{}
+	queryParams.Add("select", "*")
+	queryParams.Add("id", "eq."+id)
+	queryParams.Add("userid", "eq."+userid)
+	queryParams.Add("limit", "1")
+
+	var glossaries []tables.Glossary
+	if err := supabaseRequest(http.MethodGet, queryParams, nil, &glossaries); err != nil {
+		return nil, err
+	}
+	if len(glossaries) == 0 {
+		return nil, nil
+	}
+	return &glossaries[0], nil
+}
+
+// List 获取用户所有的术语表，不返回术语
+func List(userid string) ([]tables.Glossary, error) {
+	queryParams := url.Values{}
+	queryParams.Add("select", "id,userid,name,source_lang,target_lang,create_time,update_time")
+	queryParams.Add("userid", "eq."+userid)
+	queryParams.Add("order", "create_time.desc")
+
+	glossaries := []tables.Glossary{}
+	if err := supabaseRequest(http.MethodGet, queryParams, nil, &glossaries); err != nil {
+		return nil, err
+	}
+	return glossaries, nil
+}
+
+// Create 创建术语表
+func Create(glossary *tables.Glossary) (*tables.Glossary, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	glossary.Id = uuid.New().String()
+	glossary.CreatedTime = now
+	glossary.UpdateTime = now
+
+	var created []tables.Glossary
+	if err := supabaseRequest(http.MethodPost, url.Values{}, glossary, &created); err != nil {
+		return nil, err
+	}
+	if len(created) == 0 {
+		return glossary, nil
+	}
+	return &created[0], nil
+}
+
+// Update 更新术语表的名称、语言和术语，不存在时返回 nil
+func Update(glossary *tables.Glossary) (*tables.Glossary, error) {
+	queryParams := url.Values{}
+	queryParams.Add("id", "eq."+glossary.Id)
+	queryParams.Add("userid", "eq."+glossary.Userid)
+
+	updateData := map[string]interface{}{
+		"name":        glossary.Name,
+		"source_lang": glossary.SourceLang,
+		"target_lang": glossary.TargetLang,
+		"terms":       glossary.Terms,
+		"update_time": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var updated []tables.Glossary
+	if err := supabaseRequest(http.MethodPatch, queryParams, updateData, &updated); err != nil {
+		return nil, err
+	}
+	if len(updated) == 0 {
+		return nil, nil
+	}
+	return &updated[0], nil
+}
+
+// Delete 删除术语表，使用过该术语表的翻译记录的 glossary_id 会被置空，返回是否删除了术语表
+func Delete(id string, userid string) (bool, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return false, nil
+	}
+
+	queryParams := url.Values{}
+	queryParams.Add("id", "eq."+id)
+	queryParams.Add("userid", "eq."+userid)
+
+	var deleted []tables.Glossary
+	if err := supabaseRequest(http.MethodDelete, queryParams, nil, &deleted); err != nil {
+		return false, err
+	}
+	return len(deleted) > 0, nil
+}
+
+// Applies 术语表是否适用于该语言对，TargetLang 为空时适用于所有目标语言
+func Applies(glossary *tables.Glossary, from string, to string) bool {
+	if !strings.EqualFold(glossary.SourceLang, from) {
+		return false
+	}
+	return glossary.TargetLang == "" || strings.EqualFold(glossary.TargetLang, to)
+}
+
+func supabaseRequest(method string, queryParams url.Values, body interface{}, out interface{}) error {
+	fullURL := fmt.Sprintf("%s/rest/v1/%s?%s", config.Cfg.Supabase.SupabaseUrl, table, queryParams.Encode())
+
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %v", err)
+		}
+		reqBody = bytes.NewBuffer(payload)
+	}
+
+	req, err := http.NewRequest(method, fullURL, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %v", method, err)
+	}
+
+	req.Header.Set("apikey", config.Cfg.Supabase.SupabaseSecretKey)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.Cfg.Supabase.SupabaseSecretKey))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if method != http.MethodGet {
+		req.Header.Set("Prefer", "return=representation")
+	}
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("supabase request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("supabase request failed: %s, %s", resp.Status, string(bodyBytes))
+	}
+
+	if out != nil && len(bodyBytes) > 0 {
+		if err := json.Unmarshal(bodyBytes, out); err != nil {
+			return fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
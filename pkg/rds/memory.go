@@ -0,0 +1,66 @@
+package rds
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemoryStore 基于Redis的翻译记忆缓存，实现 translate.MemoryStore
+type MemoryStore struct {
+	ttl       time.Duration
+	keyPrefix func(userID string) string
+}
+
+// NewMemoryStore keyPrefix 返回用户翻译记忆的key前缀，用于清除用户自己的记录
+func NewMemoryStore(ttl time.Duration, keyPrefix func(userID string) string) *MemoryStore {
+	return &MemoryStore{ttl: ttl, keyPrefix: keyPrefix}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, userID string, keys []string) ([]string, error) {
+	results, err := redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(keys))
+	for i, result := range results {
+		if value, ok := result.(string); ok {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, userID string, entries map[string]string) error {
+	_, err := redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range entries {
+			pipe.Set(ctx, key, value, s.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// Purge 使用 SCAN 分批删除，避免 KEYS 阻塞 Redis
+func (s *MemoryStore) Purge(ctx context.Context, userID string) error {
+	var cursor uint64
+	for {
+		keys, next, err := redisClient.Scan(ctx, cursor, s.keyPrefix(userID)+"*", 1000).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := redisClient.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
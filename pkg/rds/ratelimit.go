@@ -0,0 +1,137 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"json_trans_api/pkg/logger"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimitPrefix 翻译接口分布式限流的key前缀，后面接翻译服务商的名字
+const RateLimitPrefix = "translate:ratelimit:"
+
+// acquireScript 在同一个秒级窗口内同时检查总量和语言对的计数，两者都未超限时才占用一个令牌
+var acquireScript = redis.NewScript(`
+local total = tonumber(redis.call('HGET', KEYS[1], 'total') or '0')
+local pair = tonumber(redis.call('HGET', KEYS[1], ARGV[3]) or '0')
+if total >= tonumber(ARGV[1]) then
+	return 0
+end
+if tonumber(ARGV[2]) > 0 and pair >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], 'total', 1)
+redis.call('HINCRBY', KEYS[1], ARGV[3], 1)
+redis.call('EXPIRE', KEYS[1], 5)
+return 1
+`)
+
+// RateLimiter 基于Redis的分布式限流器，多个 worker 进程共享同一个秒级窗口的计数
+type RateLimiter struct {
+	prefix    string
+	limit     int
+	pairLimit int
+}
+
+// RateLimitUsage 某个秒级窗口内的令牌使用情况
+type RateLimitUsage struct {
+	Window    int64            `json:"window"`
+	Limit     int              `json:"limit"`
+	PairLimit int              `json:"pair_limit"`
+	Total     int64            `json:"total"`
+	Pairs     map[string]int64 `json:"pairs"`
+}
+
+// NewRateLimiter 创建分布式限流器，limit 为每秒总调用次数，pairLimit 为每个语言对每秒的调用次数(0不限制)
+func NewRateLimiter(prefix string, limit int, pairLimit int) *RateLimiter {
+	return &RateLimiter{
+		prefix:    prefix,
+		limit:     limit,
+		pairLimit: pairLimit,
+	}
+}
+
+// Allow 尝试占用当前窗口的一个令牌
+func (l *RateLimiter) Allow(ctx context.Context, from string, to string) (bool, error) {
+	window := time.Now().Unix()
+	result, err := acquireScript.Run(ctx, redisClient, []string{l.windowKey(window)}, l.limit, l.pairLimit, pairField(from, to)).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire rate limit token: %v", err)
+	}
+	return result == 1, nil
+}
+
+// Wait 阻塞到拿到令牌，窗口已满时等待到下一秒再试
+func (l *RateLimiter) Wait(ctx context.Context, from string, to string) error {
+	for {
+		ok, err := l.Allow(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		// 加一点随机抖动，避免所有 worker 在窗口切换的瞬间一起请求
+		now := time.Now()
+		delay := now.Truncate(time.Second).Add(time.Second).Sub(now) + time.Duration(rand.Intn(50))*time.Millisecond
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Usage 获取上一个完整秒级窗口的令牌使用情况
+func (l *RateLimiter) Usage(ctx context.Context) (*RateLimitUsage, error) {
+	window := time.Now().Unix() - 1
+	values, err := redisClient.HGetAll(ctx, l.windowKey(window)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &RateLimitUsage{
+		Window:    window,
+		Limit:     l.limit,
+		PairLimit: l.pairLimit,
+		Pairs:     make(map[string]int64),
+	}
+	for field, value := range values {
+		count, _ := strconv.ParseInt(value, 10, 64)
+		if field == "total" {
+			usage.Total = count
+			continue
+		}
+		usage.Pairs[field] = count
+	}
+	return usage, nil
+}
+
+// LogUsage 定时记录令牌使用情况，实时的使用情况管理员可以通过 /user/rate_limit 查询
+func (l *RateLimiter) LogUsage() {
+	for {
+		time.Sleep(time.Minute * 1)
+		usage, err := l.Usage(context.Background())
+		if err != nil {
+			logger.Logger.Error("Error getting rate limit usage", "error", err.Error())
+			continue
+		}
+		logger.Logger.Info("translate rate limit usage", "usage", usage)
+	}
+}
+
+func (l *RateLimiter) windowKey(window int64) string {
+	return fmt.Sprintf("%s:%d", l.prefix, window)
+}
+
+func pairField(from string, to string) string {
+	return from + ">" + to
+}
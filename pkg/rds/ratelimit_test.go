@@ -0,0 +1,103 @@
+package rds
+
+import (
+	"context"
+	"json_trans_api/config"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// miniRedis 包级变量先于 redis.go 的 init 初始化，init 中连接的就是这个进程内的Redis
+var miniRedis = startMiniRedis()
+
+func startMiniRedis() *miniredis.Miniredis {
+	m, err := miniredis.Run()
+	if err != nil {
+		panic(err)
+	}
+
+	host, port, _ := net.SplitHostPort(m.Addr())
+	config.Cfg.Redis.Host = host
+	config.Cfg.Redis.Port, _ = strconv.Atoi(port)
+	config.Cfg.Redis.Password = ""
+	return m
+}
+
+// waitNextWindow 等到下一秒开始，避免测试中途切换窗口
+func waitNextWindow() {
+	now := time.Now()
+	time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now) + 10*time.Millisecond)
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	miniRedis.FlushAll()
+	limiter := NewRateLimiter("test:ratelimit", 3, 2)
+	ctx := context.Background()
+
+	waitNextWindow()
+	steps := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{"en", "zh", true},
+		{"en", "zh", true},
+		{"en", "zh", false}, // 语言对超限
+		{"en", "de", true},
+		{"en", "ja", false}, // 总量超限
+	}
+	for i, step := range steps {
+		got, err := limiter.Allow(ctx, step.from, step.to)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if got != step.want {
+			t.Errorf("step %d Allow(%s, %s) = %v, want %v", i, step.from, step.to, got, step.want)
+		}
+	}
+
+	// 窗口的key很快过期，不会在Redis中堆积
+	key := limiter.windowKey(time.Now().Unix())
+	if ttl := miniRedis.TTL(key); ttl <= 0 || ttl > 5*time.Second {
+		t.Errorf("TTL(%s) = %v, want (0, 5s]", key, ttl)
+	}
+}
+
+func TestRateLimiterUsage(t *testing.T) {
+	miniRedis.FlushAll()
+	limiter := NewRateLimiter("test:ratelimit", 10, 0)
+	ctx := context.Background()
+
+	waitNextWindow()
+	for _, to := range []string{"zh", "zh", "de"} {
+		if ok, err := limiter.Allow(ctx, "en", to); err != nil || !ok {
+			t.Fatalf("Allow() = %v, %v", ok, err)
+		}
+	}
+	waitNextWindow()
+
+	usage, err := limiter.Usage(ctx)
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if usage.Total != 3 || usage.Pairs["en>zh"] != 2 || usage.Pairs["en>de"] != 1 {
+		t.Errorf("Usage() = %+v, want total 3, en>zh 2, en>de 1", usage)
+	}
+	if usage.Limit != 10 || usage.PairLimit != 0 {
+		t.Errorf("Usage() limits = %d/%d, want 10/0", usage.Limit, usage.PairLimit)
+	}
+}
+
+func TestRateLimiterAllowRedisDown(t *testing.T) {
+	miniRedis.SetError("LOADING")
+	defer miniRedis.SetError("")
+
+	// Redis出错时返回错误，由 translate 退回到进程内的令牌桶
+	if _, err := NewRateLimiter("test:ratelimit", 10, 0).Allow(context.Background(), "en", "zh"); err == nil {
+		t.Error("Allow() error = nil, want redis error")
+	}
+}
@@ -15,8 +15,9 @@ var redisClient *redis.Client
 
 func init() {
 	var err error
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: fmt.Sprintf("%s:%d", config.Cfg.Redis.Host, config.Cfg.Redis.Port),
+	redisClient = redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", config.Cfg.Redis.Host, config.Cfg.Redis.Port),
+		Password: config.Cfg.Redis.Password,
 	})
 
 	err = redisClient.Ping(context.Background()).Err()
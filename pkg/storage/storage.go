@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"json_trans_api/config"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	bucket string
+	client *http.Client
+)
+
+func init() {
+	bucket = config.Cfg.Supabase.StorageBucket
+	if bucket == "" {
+		bucket = "translations"
+	}
+
+	// 上传和下载几十 MB 的文件，不设置请求的总超时时间，由调用方的 context 控制
+	client = &http.Client{
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: time.Second * 3,
+			}).DialContext,
+			MaxIdleConnsPerHost:   50,
+			ResponseHeaderTimeout: time.Minute,
+		},
+	}
+}
+
+// OriginPath 上传文件翻译的原文路径
+func OriginPath(userid string, id string) string {
+	return fmt.Sprintf("%s/%s/origin.json", userid, id)
+}
+
+// TranslatedPath 上传文件翻译的译文路径
+func TranslatedPath(userid string, id string) string {
+	return fmt.Sprintf("%s/%s/translated.json", userid, id)
+}
+
+// Upload 把内容流式上传到 Supabase Storage，已存在时覆盖
+func Upload(ctx context.Context, path string, r io.Reader, contentType string) error {
+	req, err := newRequest(ctx, http.MethodPost, path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-upsert", "true")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("storage upload error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("storage upload failed: %s, %s", resp.Status, string(bodyBytes))
+	}
+	return nil
+}
+
+// Download 流式下载文件，调用方负责关闭返回的 ReadCloser
+func Download(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage download error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("storage download failed: %s, %s", resp.Status, string(bodyBytes))
+	}
+	return resp.Body, nil
+}
+
+// Remove 删除文件
+func Remove(ctx context.Context, path string) error {
+	req, err := newRequest(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("storage remove error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("storage remove failed: %s, %s", resp.Status, string(bodyBytes))
+	}
+	return nil
+}
+
+func newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	fullURL := fmt.Sprintf("%s/storage/v1/object/%s/%s", config.Cfg.Supabase.SupabaseUrl, bucket, strings.TrimPrefix(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %v", method, err)
+	}
+
+	req.Header.Set("apikey", config.Cfg.Supabase.SupabaseSecretKey)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.Cfg.Supabase.SupabaseSecretKey))
+	return req, nil
+}
@@ -0,0 +1,246 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"json_trans_api/models/tables"
+	"json_trans_api/pkg/httpclient"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// 多语言翻译任务的 webhook 发送方式
+const (
+	WebhookModeAll  = "all"  // 全部语言结束后发送一次
+	WebhookModeEach = "each" // 每个语言结束后各发送一次
+)
+
+// jobTranslationRow 查询子任务状态时需要的字段。task_id 每次尝试失败后都会写入，
+// translation_status 只在不再重试时写入 failed，asynq 重试期间子任务仍然没有结束
+type jobTranslationRow struct {
+	Id                string                `json:"id"`
+	ToLang            string                `json:"to_lang"`
+	TranslatedJSON    string                `json:"translated_json"`
+	IsTranslated      bool                  `json:"is_translated"`
+	TranslationStatus string                `json:"translation_status"`
+	ValidationReport  []models.QualityIssue `json:"validation_report"`
+	FailedPaths       []models.FailedValue  `json:"failed_paths"`
+}
+
+func (r jobTranslationRow) status() string {
+	switch {
+	case r.IsTranslated:
+		return models.JobStatusCompleted
+	case r.TranslationStatus == models.TranslationStatusFailed:
+		return models.JobStatusFailed
+	default:
+		return models.JobStatusPending
+	}
+}
+
+// GetJobStatus 汇总每个目标语言的翻译状态，withContent 为 true 时返回已完成的译文、译文质量检查的问题和翻译失败的字符串
+func GetJobStatus(job *tables.TranslationJob, withContent bool) (*models.JobStatus, error) {
+	queryParams := url.Values{}
+	queryParams.Add("select", "id,to_lang,translated_json,is_translated,translation_status,validation_report,failed_paths")
+	queryParams.Add("job_id", "eq."+job.Id)
+
+	var rows []jobTranslationRow
+	if err := supabaseRequest(http.MethodGet, "user_json_translations", queryParams, nil, &rows); err != nil {
+		return nil, err
+	}
+	return summarizeJob(job, rows, withContent), nil
+}
+
+// summarizeJob 按子任务的状态汇总多语言翻译任务的状态，全部子任务结束后才是 completed 或 failed
+func summarizeJob(job *tables.TranslationJob, rows []jobTranslationRow, withContent bool) *models.JobStatus {
+	status := &models.JobStatus{
+		Id:           job.Id,
+		FromLang:     job.FromLang,
+		Translations: []models.JobTranslation{},
+	}
+
+	finished, failed := 0, 0
+	for _, row := range rows {
+		translation := models.JobTranslation{
+			Id:     row.Id,
+			ToLang: row.ToLang,
+			Status: row.status(),
+		}
+		if withContent && translation.Status == models.JobStatusCompleted {
+			translation.TranslatedJSON = row.TranslatedJSON
+			translation.ValidationReport = row.ValidationReport
+		}
+		if withContent && translation.Status != models.JobStatusPending {
+			translation.FailedPaths = row.FailedPaths
+		}
+		status.Translations = append(status.Translations, translation)
+
+		switch translation.Status {
+		case models.JobStatusCompleted:
+			finished++
+		case models.JobStatusFailed:
+			finished++
+			failed++
+		}
+	}
+
+	switch {
+	case finished == 0:
+		status.Status = models.JobStatusPending
+	case finished < len(rows):
+		status.Status = models.JobStatusProcessing
+	case failed > 0:
+		status.Status = models.JobStatusFailed
+	default:
+		status.Status = models.JobStatusCompleted
+	}
+	return status
+}
+
+// FetchJobById 根据 ID 获取多语言翻译任务，userid 不为空时只查询该用户的任务
+func FetchJobById(id string, userid string) (*tables.TranslationJob, error) {
+	queryParams := url.Values{}
+	queryParams.Add("select", "*")
+	queryParams.Add("id", "eq."+id)
+	if userid != "" {
+		queryParams.Add("userid", "eq."+userid)
+	}
+	queryParams.Add("limit", "1")
+
+	var jobs []tables.TranslationJob
+	if err := supabaseRequest(http.MethodGet, "user_json_translation_jobs", queryParams, nil, &jobs); err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+// claimJobWebhook 把 webhook_sent 从 false 改成 true，多个子任务同时结束时只有一个能改成功并发送 webhook
+func claimJobWebhook(id string) (bool, error) {
+	queryParams := url.Values{}
+	queryParams.Add("id", "eq."+id)
+	queryParams.Add("webhook_sent", "is.false")
+
+	updateData := map[string]interface{}{
+		"webhook_sent": true,
+		"update_time":  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var jobs []tables.TranslationJob
+	if err := supabaseRequest(http.MethodPatch, "user_json_translation_jobs", queryParams, updateData, &jobs); err != nil {
+		return false, err
+	}
+	return len(jobs) > 0, nil
+}
+
+// isFinalAttempt 失败的任务还会被 asynq 重试时不算结束
+func isFinalAttempt(ctx context.Context) bool {
+	retried, ok := asynq.GetRetryCount(ctx)
+	if !ok {
+		return true
+	}
+	maxRetry, ok := asynq.GetMaxRetry(ctx)
+	return !ok || retried >= maxRetry
+}
+
+// notifyJob 多语言翻译任务的一个语言结束后，按 webhook_mode 发送这个语言的结果，或者在全部语言结束后发送汇总的结果
+func notifyJob(userData *tables.UserJsonData, taskID string, translatedJson string, success bool) {
+	job, err := FetchJobById(userData.JobID, "")
+	if err != nil || job == nil {
+		log.Printf("failed to fetch translation job: id=%s, error=%v", userData.JobID, err)
+		return
+	}
+
+	if job.WebhookMode == WebhookModeEach {
+		translation := models.JobTranslation{
+			Id:          userData.Id,
+			ToLang:      userData.ToLang,
+			Status:      models.JobStatusFailed,
+			FailedPaths: userData.FailedPaths,
+		}
+		if success {
+			translation.Status = models.JobStatusCompleted
+			translation.TranslatedJSON = translatedJson
+			translation.ValidationReport = userData.ValidationReport
+		}
+		sendQueue <- TranslationTask{UserID: job.Userid, TranslationResult: translation, TaskID: taskID}
+		return
+	}
+
+	status, err := GetJobStatus(job, true)
+	if err != nil {
+		log.Printf("failed to get translation job status: id=%s, error=%v", job.Id, err)
+		return
+	}
+	if status.Status != models.JobStatusCompleted && status.Status != models.JobStatusFailed {
+		return
+	}
+
+	claimed, err := claimJobWebhook(job.Id)
+	if err != nil {
+		log.Printf("failed to claim translation job webhook: id=%s, error=%v", job.Id, err)
+		return
+	}
+	if claimed {
+		sendQueue <- TranslationTask{UserID: job.Userid, TranslationResult: status, TaskID: taskID}
+	}
+}
+
+func supabaseRequest(method string, table string, queryParams url.Values, body interface{}, out interface{}) error {
+	fullURL := fmt.Sprintf("%s/rest/v1/%s?%s", config.Cfg.Supabase.SupabaseUrl, table, queryParams.Encode())
+
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %v", err)
+		}
+		reqBody = bytes.NewBuffer(payload)
+	}
+
+	req, err := http.NewRequest(method, fullURL, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %v", method, err)
+	}
+
+	req.Header.Set("apikey", config.Cfg.Supabase.SupabaseSecretKey)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.Cfg.Supabase.SupabaseSecretKey))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Prefer", "return=representation")
+	}
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("supabase request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("supabase request failed: %s, %s", resp.Status, string(bodyBytes))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(bodyBytes, out); err != nil {
+			return fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package tasks
+
+import (
+	"json_trans_api/models/models"
+	"json_trans_api/models/tables"
+	"testing"
+)
+
+func TestSummarizeJobRetry(t *testing.T) {
+	job := &tables.TranslationJob{Id: "job-1", FromLang: "en"}
+	done := jobTranslationRow{Id: "t-1", ToLang: "de", TranslatedJSON: `{"a":"A"}`, IsTranslated: true, TranslationStatus: models.TranslationStatusComplete}
+
+	// 第一次尝试失败后还会重试，只写入了 task_id 和 is_translated=false，子任务和整个任务都没有结束
+	retrying := jobTranslationRow{Id: "t-2", ToLang: "fr"}
+	status := summarizeJob(job, []jobTranslationRow{done, retrying}, true)
+	if status.Status != models.JobStatusProcessing {
+		t.Errorf("Status = %s, want %s", status.Status, models.JobStatusProcessing)
+	}
+	if got := status.Translations[1].Status; got != models.JobStatusPending {
+		t.Errorf("retrying translation Status = %s, want %s", got, models.JobStatusPending)
+	}
+
+	// 重试成功后整个任务完成
+	retried := retrying
+	retried.TranslatedJSON, retried.IsTranslated, retried.TranslationStatus = `{"a":"À"}`, true, models.TranslationStatusComplete
+	status = summarizeJob(job, []jobTranslationRow{done, retried}, true)
+	if status.Status != models.JobStatusCompleted {
+		t.Errorf("Status = %s, want %s", status.Status, models.JobStatusCompleted)
+	}
+	if got := status.Translations[1].TranslatedJSON; got != `{"a":"À"}` {
+		t.Errorf("TranslatedJSON = %s, want the retried translation", got)
+	}
+
+	// 最后一次尝试失败后才算失败
+	failed := retrying
+	failed.TranslationStatus = models.TranslationStatusFailed
+	status = summarizeJob(job, []jobTranslationRow{done, failed}, false)
+	if status.Status != models.JobStatusFailed {
+		t.Errorf("Status = %s, want %s", status.Status, models.JobStatusFailed)
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"json_trans_api/config"
 	"json_trans_api/models/models"
 	"json_trans_api/models/tables"
+	"json_trans_api/pkg/fileformat"
+	"json_trans_api/pkg/glossary"
 	"json_trans_api/pkg/httpclient"
 	"json_trans_api/pkg/logger"
+	"json_trans_api/pkg/storage"
 	"json_trans_api/pkg/translate"
 	"log"
 	"net/http"
@@ -53,9 +57,19 @@ type TranslateResponse struct {
 }
 
 type WebhookResponse struct {
-	Msg  string `json:"msg"`
-	Code int    `json:"code"`
-	Data string `json:"data"`
+	Msg  string      `json:"msg"`
+	Code int         `json:"code"`
+	Data interface{} `json:"data"` // 单个翻译是译文字符串，上传文件翻译是下载地址，多语言翻译任务是任务或语言的状态
+	// 单个翻译的译文质量检查发现的问题、结果状态和翻译失败的字符串，多语言翻译任务的在每个语言的状态中
+	ValidationReport []models.QualityIssue `json:"validation_report,omitempty"`
+	Status           string                `json:"status,omitempty"`
+	FailedPaths      []models.FailedValue  `json:"failed_paths,omitempty"`
+}
+
+// FileTranslationResult 上传文件翻译完成时 webhook 的内容，译文通过下载接口获取
+type FileTranslationResult struct {
+	Id          string `json:"id"`
+	DownloadURL string `json:"download_url"`
 }
 
 type SendRetry struct {
@@ -70,8 +84,11 @@ type SendRetry struct {
 
 type TranslationTask struct {
 	UserID            string
-	TranslationResult string
+	TranslationResult interface{}
 	TaskID            string // 添加 taskID 字段
+	ValidationReport  []models.QualityIssue
+	Status            string // 单个翻译的结果状态，complete、partial 或 failed
+	FailedPaths       []models.FailedValue
 }
 
 func init() {
@@ -86,7 +103,7 @@ func StartSendQueue() {
 	go func() {
 		for task := range sendQueue {
 			// 处理发送任务并进行重试
-			retrySendTranslationResult(task.UserID, task.TranslationResult, task.TaskID, 3)
+			retrySendTranslationResult(task, 3)
 		}
 	}()
 }
@@ -115,21 +132,96 @@ func HandleTranslateCreateTask(ctx context.Context, t *asynq.Task) error {
 	}
 
 	// 执行JSON翻译
-	translatedJson, err := translate.TranslateJson(userData.OriginJSON, userData.FromLang, userData.ToLang, userData.IgnoredFields)
+	translate_config := models.Config{
+		SourceLang:        userData.FromLang,
+		TargetLang:        userData.ToLang,
+		IgnoredFields:     translate.GetIgnoredFields(userData.IgnoredFields),
+		IncludedFields:    translate.GetIncludedFields(userData.IncludedFields),
+		Placeholders:      translate.GetPlaceholders(userData.Placeholders),
+		Formats:           translate.GetFormats(userData.Formats),
+		DisabledDetectors: translate.GetDisabledDetectors(userData.DisabledDetectors),
+		SkipPatterns:      userData.SkipPatterns,
+		UserID:            p.Userid,
+		DisableMemory:     userData.DisableMemory,
+		PreviousSource:    userData.PreviousSource,
+		PreviousTarget:    userData.PreviousTarget,
+		ExistingTarget:    userData.ExistingTarget,
+		PruneRemoved:      userData.PruneRemoved,
+		Description:       userData.Description,
+		Strict:            userData.TranslationMode == models.TranslationModeStrict,
+		PseudoExpansion:   userData.PseudoExpansion,
+		CommentContext:    userData.CommentContext,
+		KeyStyle:          userData.KeyStyle,
+		KeySeparator:      userData.KeySeparator,
+		Indent:            userData.Indent,
+		SortKeys:          userData.SortKeys,
+	}
+	// 术语表在翻译前被删除时不再使用术语表
+	if userData.GlossaryId != "" {
+		userGlossary, err := glossary.Get(userData.GlossaryId, p.Userid)
+		if err != nil {
+			return fmt.Errorf("failed to fetch glossary: %v", err)
+		}
+		if userGlossary != nil {
+			translate_config.Glossary = userGlossary.Terms
+		}
+	}
+	// JSON 之外的格式解析后翻译，再写回原来的格式
+	var result *translate.JsonResult
+	switch {
+	case userData.OriginFile != "":
+		result, err = translateFile(ctx, userData, translate_config)
+	case !fileformat.IsJSON(userData.FileFormat):
+		result, err = fileformat.Translate(ctx, userData.FileFormat, userData.OriginJSON, translate_config)
+	default:
+		result, err = translate.TranslateJson(ctx, userData.OriginJSON, translate_config)
+	}
 
 	// 更新用户 JSON 数据的翻译状态
 	if err != nil {
 		updateUserJsonDataStatus(userData, p.TaskID, false) // 更新翻译失败的状态
+		// 不再重试时记录失败的状态并通知，严格模式下同时记录翻译失败的字符串
+		if isFinalAttempt(ctx) {
+			markTranslationFailed(userData, err)
+			if webhook_config_list, _ := getWebhookConfig(p.Userid); len(webhook_config_list) > 0 {
+				if userData.JobID != "" {
+					notifyJob(userData, p.TaskID, "", false)
+				} else {
+					sendQueue <- TranslationTask{
+						UserID:      p.Userid,
+						TaskID:      p.TaskID,
+						Status:      models.TranslationStatusFailed,
+						FailedPaths: userData.FailedPaths,
+					}
+				}
+			}
+		}
 		return fmt.Errorf("translation failed: %v", err)
 	}
 
-	// Json Encoder 会在末尾换行符号，手动去掉
-	translatedJson = strings.TrimRight(translatedJson, "\n")
-
-	// 准备更新数据
+	// 准备更新数据，按路径记录翻译成功、失败和跳过的字符串，有字符串失败时状态为 partial，
+	// 译文质量检查的问题记录在 validation_report
 	updateData := map[string]interface{}{
-		"translated_json": translatedJson,
-		"update_time":     time.Now().UTC().Format(time.RFC3339),
+		"translated_json":    result.JSON,
+		"translation_status": result.Status(),
+		"translated_paths":   result.Translated,
+		"failed_paths":       result.Failed,
+		"skipped_paths":      result.Skipped,
+		"merge_report":       result.Merge,
+		"glossary_issues":    result.Terms,
+		"validation_report":  result.Issues,
+		"update_time":        time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// 上传文件的译文已经写入 Storage，表中只记录路径，webhook 中发送下载地址
+	var translationResult interface{} = result.JSON
+	if userData.OriginFile != "" {
+		delete(updateData, "translated_json")
+		updateData["translated_file"] = storage.TranslatedPath(p.Userid, p.Id)
+		translationResult = FileTranslationResult{
+			Id:          p.Id,
+			DownloadURL: fmt.Sprintf("/json/v1/translate/%s/download", p.Id),
+		}
 	}
 
 	// 执行Supabase更新
@@ -168,17 +260,84 @@ func HandleTranslateCreateTask(ctx context.Context, t *asynq.Task) error {
 		return err
 	}
 
-	if len(webhook_config_list) > 0 {
+	userData.ValidationReport = result.Issues
+	userData.FailedPaths = result.Failed
+	if len(webhook_config_list) > 0 && userData.JobID != "" {
+		notifyJob(userData, p.TaskID, result.JSON, true)
+	} else if len(webhook_config_list) > 0 {
 		sendQueue <- TranslationTask{
 			UserID:            p.Userid,
-			TranslationResult: translatedJson,
+			TranslationResult: translationResult,
 			TaskID:            p.TaskID, // 从 p 中获取 TaskID
+			ValidationReport:  result.Issues,
+			Status:            result.Status(),
+			FailedPaths:       result.Failed,
 		}
 	}
 
 	return nil
 }
 
+// translateFile 从 Storage 流式读取上传的原文，边翻译边把译文上传到 Storage，不把整个文件读入内存。
+// JSON 之外的格式需要读取整个文件解析后翻译，再把写回的文件上传
+func translateFile(ctx context.Context, userData *tables.UserJsonData, config models.Config) (*translate.JsonResult, error) {
+	origin, err := storage.Download(ctx, userData.OriginFile)
+	if err != nil {
+		return nil, err
+	}
+	defer origin.Close()
+
+	if !fileformat.IsJSON(userData.FileFormat) {
+		content, err := io.ReadAll(origin)
+		if err != nil {
+			return nil, err
+		}
+		result, err := fileformat.Translate(ctx, userData.FileFormat, string(content), config)
+		if err != nil {
+			return nil, err
+		}
+		if err := storage.Upload(ctx, storage.TranslatedPath(config.UserID, userData.Id), strings.NewReader(result.JSON), fileformat.ContentType(userData.FileFormat)); err != nil {
+			return nil, err
+		}
+		return &translate.JsonResult{Report: result.Report}, nil
+	}
+
+	pr, pw := io.Pipe()
+	uploaded := make(chan error, 1)
+	go func() {
+		err := storage.Upload(ctx, storage.TranslatedPath(config.UserID, userData.Id), pr, "application/json")
+		pr.CloseWithError(err)
+		uploaded <- err
+	}()
+
+	report, err := translate.TranslateStream(ctx, origin, pw, config)
+	pw.CloseWithError(err)
+	if uploadErr := <-uploaded; err == nil {
+		err = uploadErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &translate.JsonResult{Report: *report}, nil
+}
+
+// markTranslationFailed 记录翻译失败的状态，严格模式下有字符串翻译失败时同时记录这些字符串
+func markTranslationFailed(userData *tables.UserJsonData, err error) {
+	updateData := map[string]interface{}{
+		"translation_status": models.TranslationStatusFailed,
+		"update_time":        time.Now().UTC().Format(time.RFC3339),
+	}
+	var partial *translate.PartialError
+	if errors.As(err, &partial) {
+		userData.FailedPaths = partial.Failed
+		updateData["failed_paths"] = partial.Failed
+	}
+
+	if _, err := updateUserJsonTranslations(userData.Id, updateData); err != nil {
+		log.Printf("failed to update translation status: id=%s, error=%v", userData.Id, err)
+	}
+}
+
 // 更新用户 JSON 数据的翻译状态
 func updateUserJsonDataStatus(userData *tables.UserJsonData, taskID string, isSuccess bool) error {
 	updateData := map[string]interface{}{
@@ -235,9 +394,9 @@ func fetchDataById(id string) (*tables.UserJsonData, error) {
 }
 
 // retrySendTranslationResult 尝试发送翻译结果并进行重试
-func retrySendTranslationResult(userID string, translationResult string, taskID string, maxRetries int) {
+func retrySendTranslationResult(task TranslationTask, maxRetries int) {
 	// 获取用户的Webhook配置
-	webhookConfig, err := getWebhookConfig(userID)
+	webhookConfig, err := getWebhookConfig(task.UserID)
 	if err != nil {
 		log.Println("获取Webhook配置出错:", err)
 		return
@@ -249,9 +408,17 @@ func retrySendTranslationResult(userID string, translationResult string, taskID
 
 	// 准备要发送的内容
 	payload := WebhookResponse{
-		Code: 200,
-		Msg:  "Success",
-		Data: translationResult,
+		Code:             200,
+		Msg:              "Success",
+		Data:             task.TranslationResult,
+		ValidationReport: task.ValidationReport,
+		Status:           task.Status,
+		FailedPaths:      task.FailedPaths,
+	}
+	if task.Status == models.TranslationStatusFailed {
+		payload.Code = http.StatusUnprocessableEntity
+		payload.Msg = "Translation failed"
+		payload.Data = map[string]interface{}{}
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -266,7 +433,7 @@ func retrySendTranslationResult(userID string, translationResult string, taskID
 		response, err := http.Post(webhookConfig[0].WebhookURL, "application/json", bytes.NewBuffer(payloadBytes))
 		if err != nil || response.StatusCode != http.StatusOK {
 			// 记录失败的重试
-			if recordErr := recordSendRetry(webhookConfig[0].ID, taskID, "failed", attempt, payloadBytes); recordErr != nil {
+			if recordErr := recordSendRetry(webhookConfig[0].ID, task.TaskID, "failed", attempt, payloadBytes); recordErr != nil {
 				log.Println("记录发送重试出错:", recordErr)
 			}
 			log.Printf("第%d/%d次尝试：发送翻译结果出错: %v", attempt, maxRetries, err)
@@ -275,10 +442,10 @@ func retrySendTranslationResult(userID string, translationResult string, taskID
 		}
 
 		// 记录成功的发送并退出重试循环
-		if recordErr := recordSendRetry(webhookConfig[0].ID, taskID, "success", attempt, payloadBytes); recordErr != nil {
+		if recordErr := recordSendRetry(webhookConfig[0].ID, task.TaskID, "success", attempt, payloadBytes); recordErr != nil {
 			log.Println("记录发送重试出错:", recordErr)
 		}
-		log.Printf("成功发送翻译结果: userid=%s", userID)
+		log.Printf("成功发送翻译结果: userid=%s", task.UserID)
 		return
 	}
 }
@@ -0,0 +1,562 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"json_trans_api/pkg/logger"
+	"json_trans_api/utils/translateapi"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+var (
+	concurrency  int
+	maxRetries   int
+	limiter      Limiter
+	localLimiter Limiter
+)
+
+func init() {
+	cfg := config.Cfg.Translate
+
+	concurrency = cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	maxRetries = cfg.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+
+	qps := cfg.QPS
+	if qps <= 0 {
+		qps = 10
+	}
+	localLimiter = NewTokenBucket(qps, cfg.Burst)
+	limiter = localLimiter
+}
+
+// segment 一个待翻译的字符串叶子节点，set 用于把译文写回复制出来的结果树
+type segment struct {
+	path     string
+	text     string
+	format   string   // plain、html 或 markdown
+	key      string   // 在所在对象中的 key，数组元素为空
+	siblings []string // 所在对象的全部 key，作为翻译的上下文
+	context  string   // 原文件中的注释和请求的描述，相同原文只有这部分相同时才合并翻译
+	hint     string   // key 路径和相邻的 key，只在服务商逐条接收上下文时发送，不参与去重
+	set      func(string) error
+}
+
+// sourceKey 原文、格式和上下文都相同的字符串只翻译一次，按路径指定了不同格式的相同原文分开翻译。
+// 上下文不包含 key 路径，不同 key 下的相同原文只翻译一次
+type sourceKey struct {
+	format  string
+	text    string
+	context string
+}
+
+func (s *segment) source() sourceKey {
+	return sourceKey{format: s.format, text: s.text, context: s.context}
+}
+
+// batchKey HTML 和普通文本分开批量翻译，HTML 使用翻译接口的 html 模式。
+// 翻译服务商支持上下文时，注释或描述不同的相同原文分开翻译
+type batchKey struct {
+	tagHandling string
+	context     string
+	text        string
+}
+
+// 上下文中最多列出的相邻 key 个数
+const maxContextSiblings = 10
+
+// segmentHint 生成字符串所在位置的提示，例如 "Key: nav.home" 和 "Sibling keys: about, back"，
+// 让 "Home"、"Back" 这类有歧义的短文本按界面中的含义翻译
+func segmentHint(seg *segment) string {
+	lines := []string{"Key: " + seg.path}
+
+	var siblings []string
+	for _, key := range seg.siblings {
+		if len(siblings) == maxContextSiblings {
+			break
+		}
+		if key != seg.key {
+			siblings = append(siblings, key)
+		}
+	}
+	if len(siblings) > 0 {
+		lines = append(lines, "Sibling keys: "+strings.Join(siblings, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sharedContext 生成原文件中写给译者的注释和请求的描述组成的上下文，不同 key 下的相同原文共用
+func sharedContext(description string, comment string) string {
+	var lines []string
+	if comment = strings.TrimSpace(comment); comment != "" {
+		lines = append(lines, "Comment: "+comment)
+	}
+	if description = strings.TrimSpace(description); description != "" {
+		lines = append(lines, "Description: "+description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// joinContext 拼接位置提示和共用的上下文，跳过空的部分
+func joinContext(parts ...string) string {
+	var lines []string
+	for _, part := range parts {
+		if part != "" {
+			lines = append(lines, part)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// document 复制的整个文档，根节点是字符串时写回译文会替换根节点
+type document struct {
+	root interface{}
+}
+
+// collectDocument 复制任意类型的根节点并收集需要翻译的字符串
+func collectDocument(data interface{}, rules *fieldRules, segments *[]*segment) (*document, error) {
+	doc := &document{}
+	if text, ok := data.(string); ok {
+		doc.root = text
+		addSegment(segments, nil, "", nil, rules, text, func(translated string) error {
+			doc.root = translated
+			return nil
+		})
+		return doc, nil
+	}
+
+	root, err := collectElement(data, nil, rules, segments)
+	if err != nil {
+		return nil, err
+	}
+	doc.root = root
+	return doc, nil
+}
+
+// collectElement 复制元素，同时收集其中需要翻译的字符串
+func collectElement(elem interface{}, tokens []string, rules *fieldRules, segments *[]*segment) (interface{}, error) {
+	switch v := elem.(type) {
+	case *orderedmap.OrderedMap:
+		return collectNestedJSON(v, tokens, rules, segments)
+	case orderedmap.OrderedMap:
+		return collectNestedJSON(&v, tokens, rules, segments)
+	case []interface{}:
+		return collectArray(v, tokens, rules, segments)
+	case string, json.Number, bool, nil:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %v", reflect.TypeOf(elem))
+	}
+}
+
+func collectNestedJSON(data *orderedmap.OrderedMap, tokens []string, rules *fieldRules, segments *[]*segment) (*orderedmap.OrderedMap, error) {
+	translatedMap := orderedmap.New()
+	for _, key := range data.Keys() {
+		value, _ := data.Get(key)
+
+		keyTokens := appendToken(tokens, key)
+		if rules.isIgnored(keyTokens) {
+			translatedMap.Set(key, value)
+			continue
+		}
+
+		if text, ok := value.(string); ok {
+			translatedMap.Set(key, text)
+			addSegment(segments, keyTokens, key, data.Keys(), rules, text, func(translated string) error {
+				translatedMap.Set(key, translated)
+				return nil
+			})
+			continue
+		}
+
+		translatedValue, err := collectElement(value, keyTokens, rules, segments)
+		if err != nil {
+			return nil, fmt.Errorf("error translating key %s: %v", key, err)
+		}
+		translatedMap.Set(key, translatedValue)
+	}
+	return translatedMap, nil
+}
+
+func collectArray(arr []interface{}, tokens []string, rules *fieldRules, segments *[]*segment) ([]interface{}, error) {
+	translatedArr := make([]interface{}, len(arr))
+	for i, item := range arr {
+		itemTokens := appendToken(tokens, indexToken(i))
+		if rules.isIgnored(itemTokens) {
+			translatedArr[i] = item
+			continue
+		}
+
+		if text, ok := item.(string); ok {
+			translatedArr[i] = text
+			index := i
+			addSegment(segments, itemTokens, "", nil, rules, text, func(translated string) error {
+				translatedArr[index] = translated
+				return nil
+			})
+			continue
+		}
+
+		translatedItem, err := collectElement(item, itemTokens, rules, segments)
+		if err != nil {
+			return nil, err
+		}
+		translatedArr[i] = translatedItem
+	}
+	return translatedArr, nil
+}
+
+// addSegment 空白字符串、不在只翻译规则内的字符串和 URL、UUID 这类不需要翻译的值直接保留原值，
+// 自动跳过的字符串记录在 rules.skipped
+func addSegment(segments *[]*segment, tokens []string, key string, siblings []string, rules *fieldRules, text string, set func(string) error) {
+	if strings.TrimSpace(text) == "" || !rules.isIncluded(tokens) {
+		return
+	}
+	if reason := rules.skip.classify(text); reason != "" {
+		rules.skipped = append(rules.skipped, models.SkippedValue{Path: formatPath(tokens), Reason: reason})
+		return
+	}
+	*segments = append(*segments, &segment{path: formatPath(tokens), text: text, format: rules.formatOf(tokens, text), key: key, siblings: siblings, set: set})
+}
+
+// segmentMasker 按字符串的格式替换占位符，HTML 的标签交给翻译接口的 html 模式处理，不作为占位符
+type segmentMasker struct {
+	plain    *regexp.Regexp
+	html     *regexp.Regexp
+	glossary *glossaryMatcher
+}
+
+func newSegmentMasker(placeholders []string, glossary []models.GlossaryTerm) (*segmentMasker, error) {
+	plain, err := compilePlaceholders(placeholders)
+	if err != nil {
+		return nil, err
+	}
+	html, err := compilePlaceholders(placeholders, PlaceholderTag)
+	if err != nil {
+		return nil, err
+	}
+	return &segmentMasker{plain: plain, html: html, glossary: newGlossaryMatcher(glossary)}, nil
+}
+
+// mask 返回替换后的文本和调用翻译接口时的 TagHandling，术语表中的术语也替换成占位符标记
+func (s *segmentMasker) mask(text string, format string) (maskedText, string) {
+	var m maskedText
+	tagHandling := ""
+	switch format {
+	case FormatHTML:
+		m, tagHandling = maskPlaceholders(text, s.html), "html"
+	case FormatMarkdown:
+		m = maskMarkdown(text, s.plain)
+	default:
+		m = maskPlaceholders(text, s.plain)
+	}
+	s.glossary.mask(&m)
+	return m, tagHandling
+}
+
+// translateSegments 对收集到的字符串去重后分批并发翻译，再写回到各自的位置。
+// 翻译请求失败、占位符丢失或者无法写回的字符串保留原文，按路径返回翻译成功和失败的字符串、
+// 没有使用术语表规定译法的字符串和译文质量检查的问题
+func translateSegments(ctx context.Context, segments []*segment, config models.Config) (*Report, error) {
+	// 伪本地化的译文不写入翻译记忆，也不做译文质量检查
+	pseudo := IsPseudoLocale(config.TargetLang)
+	if pseudo {
+		config.DisableMemory = true
+	}
+
+	// 翻译服务商支持上下文时一起发送，不支持时上下文只用于翻译记忆的消歧
+	contextMode := translateapi.ContextMode()
+	for _, seg := range segments {
+		seg.context = sharedContext(config.Description, config.Comments[seg.path])
+		if contextMode == translateapi.ContextPerText {
+			seg.hint = segmentHint(seg)
+		}
+	}
+
+	// 相同的原文只翻译一次，逐条发送的上下文取第一次出现的位置
+	var sources []sourceKey
+	hints := make(map[sourceKey]string)
+	seen := make(map[sourceKey]bool)
+	for _, seg := range segments {
+		source := seg.source()
+		if !seen[source] {
+			seen[source] = true
+			hints[source] = seg.hint
+			sources = append(sources, source)
+		}
+	}
+
+	// 占位符和术语替换成不透明的标记后再翻译，不同原文替换后相同的只翻译一次。
+	// HTML 的标签交给翻译接口的 html 模式处理，Markdown 只翻译正文
+	masker, err := newSegmentMasker(config.Placeholders, config.Glossary)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先查翻译记忆，只有未命中的文本才调用翻译接口
+	translations, sources := lookupMemory(ctx, sources, config, masker.glossary.fingerprint())
+
+	type maskedSource struct {
+		maskedText
+		key batchKey
+	}
+
+	masked := make(map[sourceKey]maskedSource, len(sources))
+	groups := make(map[batchGroup][]batchKey)
+	contexts := make(map[batchKey]string)
+	seenMasked := make(map[batchKey]bool)
+	for _, source := range sources {
+		m, tagHandling := masker.mask(source.text, source.format)
+		key := batchKey{tagHandling: tagHandling, text: m.text}
+		if contextMode != "" {
+			key.context = source.context
+		}
+		masked[source] = maskedSource{maskedText: m, key: key}
+
+		if m.onlyPlaceholders() || seenMasked[key] {
+			continue
+		}
+		seenMasked[key] = true
+
+		// 按请求接收上下文时注释和描述相同的放在同一批，逐条接收时每条带上各自的位置
+		group := batchGroup{tagHandling: tagHandling}
+		switch contextMode {
+		case translateapi.ContextPerRequest:
+			group.context = key.context
+		case translateapi.ContextPerText:
+			contexts[key] = joinContext(hints[source], key.context)
+		}
+		groups[group] = append(groups[group], key)
+	}
+
+	maxTexts, maxChars := translateapi.BatchLimit()
+	jobs := make(chan batchJob)
+	go func() {
+		defer close(jobs)
+		for group, keys := range groups {
+			texts := make([]string, len(keys))
+			for i, key := range keys {
+				texts[i] = key.text
+			}
+
+			// 切分后的批次按顺序首尾相接，按偏移取回对应的 key
+			offset := 0
+			for _, batch := range splitBatches(texts, maxTexts, maxChars) {
+				job := batchJob{batchGroup: group, keys: keys[offset : offset+len(batch)]}
+				if contextMode == translateapi.ContextPerText {
+					job.contexts = make([]string, len(job.keys))
+					for i, key := range job.keys {
+						job.contexts[i] = contexts[key]
+					}
+				}
+				offset += len(batch)
+				select {
+				case jobs <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[batchKey]string, len(seenMasked))
+	batchErrors := make(map[batchKey]error)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				batchResults, err := translateBatch(ctx, job, contextMode, config)
+				if err != nil {
+					// 翻译失败的批次保留原文，记录到每个字符串的失败原因中
+					logger.Logger.Error("Error with BatchTranslate", "error", err.Error(), "size", len(job.keys))
+					mu.Lock()
+					for _, key := range job.keys {
+						batchErrors[key] = fmt.Errorf("translation request failed: %v", err)
+					}
+					mu.Unlock()
+					continue
+				}
+
+				mu.Lock()
+				for i, key := range job.keys {
+					results[key] = batchResults[i]
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	translated := make(map[sourceKey]string, len(sources))
+	sourceErrors := make(map[sourceKey]error)
+	for _, source := range sources {
+		m := masked[source]
+		if m.onlyPlaceholders() {
+			// 只有占位符和术语的字符串不需要翻译，术语直接写回规定的译法
+			translations[source], _ = m.unmask(source.text, m.text)
+			continue
+		}
+
+		result, ok := results[m.key]
+		if !ok {
+			if err, ok := batchErrors[m.key]; ok {
+				sourceErrors[source] = err
+			}
+			continue
+		}
+
+		restored, err := m.unmask(source.text, result)
+		if err != nil {
+			// 占位符丢失的字符串保留原文，不写入翻译记忆
+			logger.Logger.Error("Error with placeholder restore", "error", err.Error())
+			sourceErrors[source] = err
+			continue
+		}
+		translated[source] = restored
+	}
+
+	saveMemory(ctx, translated, config, masker.glossary.fingerprint())
+	for source, translation := range translated {
+		translations[source] = translation
+	}
+
+	// 写回译文的同时检查译文的质量，保留原文的字符串已经记录在 Failed 中，不再检查
+	report := &Report{Translated: []string{}, Failed: []models.FailedValue{}, Skipped: []models.SkippedValue{}, Terms: []models.TermIssue{}, Issues: []models.QualityIssue{}}
+	validator := newValidator(masker, config)
+	// ICU 消息拆分后的多个字符串路径相同，任意一个失败时整个路径算失败
+	failedPaths := map[string]bool{}
+	for _, seg := range segments {
+		output := seg.text
+		if err, ok := sourceErrors[seg.source()]; ok {
+			report.Failed = append(report.Failed, models.FailedValue{Path: seg.path, Error: err.Error()})
+			failedPaths[seg.path] = true
+		} else if translated, ok := translations[seg.source()]; ok {
+			if err := seg.set(translated); err != nil {
+				report.Failed = append(report.Failed, models.FailedValue{Path: seg.path, Error: err.Error()})
+				failedPaths[seg.path] = true
+			} else {
+				output = translated
+				if !pseudo {
+					report.Issues = append(report.Issues, validator.check(seg, translated)...)
+				}
+			}
+		}
+		report.Terms = append(report.Terms, masker.glossary.check(seg.path, seg.text, output)...)
+	}
+
+	seenPaths := map[string]bool{}
+	for _, seg := range segments {
+		if _, ok := translations[seg.source()]; ok && !failedPaths[seg.path] && !seenPaths[seg.path] {
+			seenPaths[seg.path] = true
+			report.Translated = append(report.Translated, seg.path)
+		}
+	}
+	return report, nil
+}
+
+// batchGroup 可以放在同一批中翻译的文本，服务商按请求接收上下文时上下文也需要相同
+type batchGroup struct {
+	tagHandling string
+	context     string
+}
+
+type batchJob struct {
+	batchGroup
+	keys     []batchKey
+	contexts []string // 服务商逐条接收上下文时每条文本的上下文
+}
+
+// translateBatch 每次请求前先从限流器拿令牌，限流或临时错误时退避重试
+func translateBatch(ctx context.Context, job batchJob, contextMode string, config models.Config) ([]string, error) {
+	batch := make([]string, len(job.keys))
+	for i, key := range job.keys {
+		batch[i] = key.text
+	}
+
+	req := models.TranslationRequest{
+		Text:        batch,
+		SourceLang:  config.SourceLang,
+		TargetLang:  config.TargetLang,
+		TagHandling: job.tagHandling,
+	}
+	switch contextMode {
+	case translateapi.ContextPerRequest:
+		req.Context = job.context
+	case translateapi.ContextPerText:
+		req.Contexts = job.contexts
+	}
+
+	// 伪本地化在本地生成译文，不调用翻译服务商，也不需要限流
+	if IsPseudoLocale(config.TargetLang) {
+		return pseudoTranslate(batch, job.tagHandling, config), nil
+	}
+
+	var responses []models.TranslationResponse
+	err := withRetry(ctx, maxRetries, func() error {
+		if err := limiter.Wait(ctx, config.SourceLang, config.TargetLang); err != nil {
+			return err
+		}
+
+		var err error
+		responses, err = translateapi.BatchTranslate(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(responses) != len(batch) {
+		return nil, fmt.Errorf("batch translate returned %d results for %d texts", len(responses), len(batch))
+	}
+
+	results := make([]string, len(responses))
+	for i, resp := range responses {
+		results[i] = resp.Text
+	}
+	return results, nil
+}
+
+// splitBatches 按服务商的条数和字符数上限切分批次，单条超过字符上限的文本单独成批
+func splitBatches(texts []string, maxTexts int, maxChars int) [][]string {
+	var batches [][]string
+	var current []string
+	currentChars := 0
+
+	for _, text := range texts {
+		chars := utf8.RuneCountInString(text)
+		if len(current) > 0 && (len(current) >= maxTexts || currentChars+chars > maxChars) {
+			batches = append(batches, current)
+			current = nil
+			currentChars = 0
+		}
+		current = append(current, text)
+		currentChars += chars
+	}
+
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
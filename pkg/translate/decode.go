@@ -0,0 +1,74 @@
+package translate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// decodeJSON 解析任意类型的 JSON 值，对象保持 key 的顺序解析为 *orderedmap.OrderedMap，
+// 数字解析为 json.Number，原样写回，避免大整数和 1.10 这类小数经过 float64 后被改写
+func decodeJSON(data string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+
+	value, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: unexpected data after the top-level value")
+	}
+	return value, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	if delim == '[' {
+		arr := []interface{}{}
+		for dec.More() {
+			item, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, item)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return arr, nil
+	}
+
+	// 重复的 key 和 json.Unmarshal 一样以最后一个值为准，位置也移到最后
+	obj := orderedmap.New()
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		key := token.(string)
+		value, err := decodeValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		obj.Delete(key)
+		obj.Set(key, value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,103 @@
+package translate
+
+import (
+	"fmt"
+	"json_trans_api/models/models"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// previousTranslation 上一次翻译的原文和译文，按路径记录字符串叶子节点
+type previousTranslation struct {
+	source map[string]string
+	target map[string]string
+}
+
+// ValidatePrevious 检查增量翻译的上一次原文和译文，两者需要同时提供且都是 JSON 对象
+func ValidatePrevious(previousSource string, previousTarget string) error {
+	_, err := newPreviousTranslation(models.Config{PreviousSource: previousSource, PreviousTarget: previousTarget})
+	return err
+}
+
+func newPreviousTranslation(config models.Config) (*previousTranslation, error) {
+	if config.PreviousSource == "" && config.PreviousTarget == "" {
+		return nil, nil
+	}
+	if config.PreviousSource == "" || config.PreviousTarget == "" {
+		return nil, fmt.Errorf("previous source and previous target must be provided together")
+	}
+
+	source, err := leafStrings(config.PreviousSource, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid previous source: %v", err)
+	}
+	target, err := leafStrings(config.PreviousTarget, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid previous target: %v", err)
+	}
+	return &previousTranslation{source: source, target: target}, nil
+}
+
+// reuse 原文没有变化、上一次的译文中也存在的字符串直接写回上一次的译文，只返回新增或修改的字符串。
+// 需要翻译的字符串上一次的译文和原文相同时，多半是上一次翻译失败保留了原文，重新翻译
+func (p *previousTranslation) reuse(segments []*segment) ([]*segment, int) {
+	if p == nil {
+		return segments, 0
+	}
+
+	var changed []*segment
+	reused := 0
+	for _, seg := range segments {
+		source, ok := p.source[seg.path]
+		if !ok || source != seg.text {
+			changed = append(changed, seg)
+			continue
+		}
+		target, ok := p.target[seg.path]
+		if !ok || target == seg.text {
+			changed = append(changed, seg)
+			continue
+		}
+		if err := seg.set(target); err != nil {
+			changed = append(changed, seg)
+			continue
+		}
+		reused++
+	}
+	return changed, reused
+}
+
+// leafStrings 按 a.b[0].c 形式的路径收集 JSON 中所有的字符串叶子节点，指定了 key 的形状时按嵌套的路径
+func leafStrings(json_data string, config models.Config) (map[string]string, error) {
+	data, err := decodeKeys(json_data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := map[string]string{}
+	collectLeaves(data, nil, func(tokens []string, value interface{}) {
+		if text, ok := value.(string); ok {
+			leaves[formatPath(tokens)] = text
+		}
+	})
+	return leaves, nil
+}
+
+// collectLeaves 遍历 JSON 中所有不是对象和数组的叶子节点
+func collectLeaves(elem interface{}, tokens []string, visit func(tokens []string, value interface{})) {
+	switch v := elem.(type) {
+	case *orderedmap.OrderedMap:
+		for _, key := range v.Keys() {
+			value, _ := v.Get(key)
+			collectLeaves(value, appendToken(tokens, key), visit)
+		}
+	case orderedmap.OrderedMap:
+		collectLeaves(&v, tokens, visit)
+	case []interface{}:
+		for i, item := range v {
+			collectLeaves(item, appendToken(tokens, indexToken(i)), visit)
+		}
+	default:
+		visit(tokens, v)
+	}
+}
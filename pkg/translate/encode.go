@@ -0,0 +1,235 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"json_trans_api/models/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// 请求中 indent 的取值，tab 使用制表符缩进，0 输出紧凑的 JSON，1-8 是缩进的空格数
+const (
+	IndentTab       = "tab"
+	MaxIndentSpaces = 8
+)
+
+// jsonStyle 输出 JSON 的格式，从原文中检测，译文提交到 git 时只有翻译的字符串产生差异
+type jsonStyle struct {
+	multiline    bool   // 每个 key 和数组元素单独一行
+	indent       string // 每一层的缩进
+	newline      string // 换行符，\n 或 \r\n
+	finalNewline bool   // 末尾是否有换行
+	colon        string // key 和值之间的分隔，": " 或 ":"
+	escapeASCII  bool   // 非 ASCII 字符写成 \uXXXX
+	escapeHTML   bool   // <、>、& 写成 \u003c 这类转义
+	upperHex     bool   // \uXXXX 使用大写的十六进制
+	sortKeys     bool   // 对象的 key 按字母顺序排列
+}
+
+// ValidateIndent 检查请求中的缩进，为空时沿用原文的缩进
+func ValidateIndent(indent string) error {
+	if indent == "" || indent == IndentTab {
+		return nil
+	}
+	if n, err := strconv.Atoi(indent); err != nil || n < 0 || n > MaxIndentSpaces {
+		return fmt.Errorf("indent must be tab or a number of spaces between 0 and %d", MaxIndentSpaces)
+	}
+	return nil
+}
+
+var (
+	// firstKeySeparator 根对象第一个 key 后面的冒号和空白
+	firstKeySeparator = regexp.MustCompile(`^\s*\{\s*"(?:[^"\\]|\\.)*"\s*:([ \t]*)`)
+	// unicodeEscape 没有被反斜杠转义的 \uXXXX
+	unicodeEscape = regexp.MustCompile(`(?:^|[^\\])(?:\\\\)*\\u([0-9a-fA-F]{4})`)
+)
+
+// detectStyle 检测原文的缩进、换行、末尾的换行和 \uXXXX 转义的习惯，再按请求覆盖缩进和 key 的顺序。
+// 原文只有一行时输出紧凑的 JSON，缩进取第一个缩进的行开头的空白
+func detectStyle(data string, config models.Config) jsonStyle {
+	style := jsonStyle{newline: "\n", colon: ":", sortKeys: config.SortKeys}
+
+	body := strings.TrimRight(data, " \t\r\n")
+	style.finalNewline = len(body) < len(data) && strings.ContainsAny(data[len(body):], "\n")
+	if strings.Contains(data, "\r\n") {
+		style.newline = "\r\n"
+	}
+	if style.multiline = strings.Contains(strings.TrimSpace(data), "\n"); style.multiline {
+		for _, line := range strings.Split(body, "\n")[1:] {
+			if content := strings.TrimLeft(line, " \t"); content != "" && content != "\r" && len(content) < len(line) {
+				style.indent = line[:len(line)-len(content)]
+				break
+			}
+		}
+	}
+	// 没有 key 时多行输出和 json.MarshalIndent 一样冒号后面加空格
+	if m := firstKeySeparator.FindStringSubmatch(data); m != nil {
+		style.colon = ":" + m[1]
+	} else if style.multiline {
+		style.colon = ": "
+	}
+
+	rawNonASCII := false
+	for _, r := range data {
+		rawNonASCII = rawNonASCII || r >= utf8.RuneSelf
+	}
+	for _, m := range unicodeEscape.FindAllStringSubmatch(data, -1) {
+		code, _ := strconv.ParseUint(m[1], 16, 32)
+		switch {
+		case code >= utf8.RuneSelf:
+			style.escapeASCII = !rawNonASCII
+		case code == '<' || code == '>' || code == '&':
+			style.escapeHTML = true
+		}
+		if strings.ContainsAny(m[1], "ABCDEF") {
+			style.upperHex = true
+		}
+	}
+
+	switch indent := config.Indent; {
+	case indent == IndentTab:
+		style.multiline, style.indent, style.colon = true, "\t", ": "
+	case indent == "0":
+		style.multiline, style.colon = false, ":"
+	case indent != "":
+		n, _ := strconv.Atoi(indent)
+		style.multiline, style.indent, style.colon = true, strings.Repeat(" ", n), ": "
+	}
+	return style
+}
+
+// encode 按检测到的格式输出 JSON，数字按原文写回
+func (s jsonStyle) encode(value interface{}) (string, error) {
+	var b strings.Builder
+	if err := s.write(&b, value, 0); err != nil {
+		return "", err
+	}
+	if s.finalNewline {
+		b.WriteString(s.newline)
+	}
+	return b.String(), nil
+}
+
+func (s jsonStyle) write(b *strings.Builder, value interface{}, depth int) error {
+	switch v := value.(type) {
+	case *orderedmap.OrderedMap:
+		keys := v.Keys()
+		if s.sortKeys {
+			keys = append([]string{}, keys...)
+			sort.Strings(keys)
+		}
+		if len(keys) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				s.writeComma(b)
+			}
+			s.breakLine(b, depth+1)
+			s.writeString(b, key)
+			b.WriteString(s.colon)
+			item, _ := v.Get(key)
+			if err := s.write(b, item, depth+1); err != nil {
+				return err
+			}
+		}
+		s.breakLine(b, depth)
+		b.WriteByte('}')
+	case orderedmap.OrderedMap:
+		return s.write(b, &v, depth)
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				s.writeComma(b)
+			}
+			s.breakLine(b, depth+1)
+			if err := s.write(b, item, depth+1); err != nil {
+				return err
+			}
+		}
+		s.breakLine(b, depth)
+		b.WriteByte(']')
+	case string:
+		s.writeString(b, v)
+	case json.Number:
+		b.WriteString(v.String())
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	}
+	return nil
+}
+
+// writeComma 单行输出时和冒号一样，原文冒号后面有空格时逗号后面也加空格
+func (s jsonStyle) writeComma(b *strings.Builder) {
+	b.WriteByte(',')
+	if !s.multiline && s.colon != ":" {
+		b.WriteByte(' ')
+	}
+}
+
+func (s jsonStyle) breakLine(b *strings.Builder, depth int) {
+	if !s.multiline {
+		return
+	}
+	b.WriteString(s.newline)
+	for i := 0; i < depth; i++ {
+		b.WriteString(s.indent)
+	}
+}
+
+// writeString 编码字符串，U+2028 和 U+2029 和 encoding/json 一样总是转义
+func (s jsonStyle) writeString(b *strings.Builder, text string) {
+	b.WriteByte('"')
+	for _, r := range text {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20, r == '\u2028', r == '\u2029',
+			s.escapeHTML && (r == '<' || r == '>' || r == '&'):
+			s.writeEscape(b, r)
+		case s.escapeASCII && r >= utf8.RuneSelf:
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				s.writeEscape(b, r1)
+				s.writeEscape(b, r2)
+			} else {
+				s.writeEscape(b, r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+}
+
+func (s jsonStyle) writeEscape(b *strings.Builder, r rune) {
+	if s.upperHex {
+		fmt.Fprintf(b, `\u%04X`, r)
+		return
+	}
+	fmt.Fprintf(b, `\u%04x`, r)
+}
@@ -0,0 +1,114 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 字符串的格式，创建翻译任务时可以通过 formats 字段按路径指定，未指定时自动检测
+const (
+	FormatPlain    = "plain"
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+)
+
+// formatRule 按路径指定字符串的格式，例如 body=html
+type formatRule struct {
+	pattern []string
+	format  string
+}
+
+// GetFormats 把逗号分隔的格式规则拆分成列表，每条为 路径=格式
+func GetFormats(formatsStr string) []string {
+	return GetIgnoredFields(formatsStr)
+}
+
+// ValidateFormats 检查格式规则的路径和格式是否支持
+func ValidateFormats(formats []string) error {
+	_, err := parseFormatRules(formats)
+	return err
+}
+
+func parseFormatRules(formats []string) ([]formatRule, error) {
+	var rules []formatRule
+	for _, item := range formats {
+		i := strings.LastIndex(item, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid format rule %q: expected path=format", item)
+		}
+
+		format := strings.TrimSpace(item[i+1:])
+		if format != FormatPlain && format != FormatHTML && format != FormatMarkdown {
+			return nil, fmt.Errorf("invalid format rule %q: unsupported format %s", item, format)
+		}
+
+		pattern, err := parseFieldRule(item[:i])
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, formatRule{pattern: pattern, format: format})
+	}
+	return rules, nil
+}
+
+// htmlTag 常见的 HTML 标签，<username> 这类占位符不会被当成 HTML
+var htmlTag = regexp.MustCompile(`(?i)</?(a|abbr|b|blockquote|br|button|center|code|del|div|em|font|h[1-6]|hr|i|img|ins|kbd|label|li|mark|ol|p|pre|q|s|small|span|strong|sub|sup|table|tbody|td|th|thead|tr|u|ul)(\s[^<>]*)?/?>`)
+
+var htmlEntity = regexp.MustCompile(`&(#\d+|#x[0-9a-fA-F]+|[a-zA-Z]+);`)
+
+var markdownSyntax = regexp.MustCompile("(?m)!?\\[[^\\[\\]]*\\]\\([^()\\s]+\\)|`[^`]+`|\\*\\*[^*]+\\*\\*|__[^_]+__|^#{1,6}\\s|^\\s*([-*+]|\\d+\\.)\\s+\\S")
+
+// detectFormat 包含 HTML 标签或实体时按 HTML 翻译，包含链接、代码、标题等语法时按 Markdown 翻译
+func detectFormat(text string) string {
+	if htmlTag.MatchString(text) || htmlEntity.MatchString(text) {
+		return FormatHTML
+	}
+	if markdownSyntax.MatchString(text) {
+		return FormatMarkdown
+	}
+	return FormatPlain
+}
+
+// formatOf 路径匹配的第一条规则优先，没有匹配的规则时自动检测
+func (r *fieldRules) formatOf(tokens []string, text string) string {
+	for _, rule := range r.formats {
+		if matchPath(rule.pattern, tokens) {
+			return rule.format
+		}
+	}
+	return detectFormat(text)
+}
+
+// Markdown 中不需要翻译的部分，按顺序处理，代码中的链接不会再被处理
+var (
+	markdownFence      = regexp.MustCompile("(?s)```.*?```")
+	markdownCode       = regexp.MustCompile("`[^`\n]+`")
+	markdownLink       = regexp.MustCompile(`(!?\[)([^\[\]]*)(\]\([^()\s]+(?:\s+"[^"]*")?\))`)
+	markdownRefLink    = regexp.MustCompile(`(?m)^\s*\[[^\[\]]+\]:\s+\S+.*$`)
+	markdownAutoLink   = regexp.MustCompile(`<(https?://|mailto:)[^<>\s]+>`)
+	markdownURL        = regexp.MustCompile(`https?://[^\s<>()\[\]]+`)
+	markdownLinePrefix = regexp.MustCompile(`(?m)^[ \t]*(#{1,6}[ \t]+|>[ \t]?|[-*+][ \t]+|\d+\.[ \t]+)`)
+	markdownEmphasis   = regexp.MustCompile(`\*\*|__|~~`)
+)
+
+// maskMarkdown 把代码、链接地址、URL 和行首的标记替换成占位符标记，只留下正文交给翻译接口，
+// 链接的文字仍然翻译，最后再按 re 替换正文中的占位符
+func maskMarkdown(text string, re *regexp.Regexp) maskedText {
+	var m maskedText
+	for _, syntax := range []*regexp.Regexp{markdownFence, markdownCode, markdownRefLink} {
+		text = m.mask(text, syntax)
+	}
+
+	text = markdownLink.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownLink.FindStringSubmatch(match)
+		return m.add(parts[1]) + parts[2] + m.add(parts[3])
+	})
+
+	for _, syntax := range []*regexp.Regexp{markdownAutoLink, markdownURL, markdownLinePrefix, markdownEmphasis} {
+		text = m.mask(text, syntax)
+	}
+
+	m.text = m.mask(text, re)
+	return m
+}
@@ -0,0 +1,141 @@
+package translate
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"json_trans_api/models/models"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// glossaryMatcher 在原文中查找术语，翻译前替换成占位符标记，翻译后写回术语表规定的译法
+type glossaryMatcher struct {
+	re    *regexp.Regexp
+	exact map[string]models.GlossaryTerm // 区分大小写的术语，按原文查找
+	fold  map[string]models.GlossaryTerm // 忽略大小写的术语，按小写的原文查找
+}
+
+func newGlossaryMatcher(terms []models.GlossaryTerm) *glossaryMatcher {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	// 长的术语优先匹配，例如 "Pro Plan" 优先于 "Pro"
+	sorted := make([]models.GlossaryTerm, 0, len(terms))
+	for _, term := range terms {
+		if strings.TrimSpace(term.Source) != "" {
+			sorted = append(sorted, term)
+		}
+	}
+	if len(sorted) == 0 {
+		return nil
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return utf8.RuneCountInString(sorted[i].Source) > utf8.RuneCountInString(sorted[j].Source)
+	})
+
+	g := &glossaryMatcher{exact: map[string]models.GlossaryTerm{}, fold: map[string]models.GlossaryTerm{}}
+	alternatives := make([]string, 0, len(sorted))
+	for _, term := range sorted {
+		pattern := regexp.QuoteMeta(term.Source)
+		// 以字母或数字开头结尾的术语按整个单词匹配，"Pro" 不会匹配 "Product"
+		if isWordRune(firstRune(term.Source)) {
+			pattern = `\b` + pattern
+		}
+		if isWordRune(lastRune(term.Source)) {
+			pattern += `\b`
+		}
+		if term.IgnoreCase {
+			pattern = "(?i:" + pattern + ")"
+			g.fold[strings.ToLower(term.Source)] = term
+		} else {
+			g.exact[term.Source] = term
+		}
+		alternatives = append(alternatives, pattern)
+	}
+	g.re = regexp.MustCompile(strings.Join(alternatives, "|"))
+	return g
+}
+
+// lookup 找到匹配的文字对应的术语
+func (g *glossaryMatcher) lookup(match string) (models.GlossaryTerm, bool) {
+	if term, ok := g.exact[match]; ok {
+		return term, true
+	}
+	term, ok := g.fold[strings.ToLower(match)]
+	return term, ok
+}
+
+// expected 术语在译文中应有的写法，不翻译的术语保留原文中的写法
+func (g *glossaryMatcher) expected(match string) string {
+	term, ok := g.lookup(match)
+	if !ok || term.DoNotTranslate || term.Target == "" {
+		return match
+	}
+	return term.Target
+}
+
+// mask 把原文中的术语替换成占位符标记，写回时直接使用术语表规定的译法
+func (g *glossaryMatcher) mask(m *maskedText) {
+	if g == nil {
+		return
+	}
+	m.text = g.re.ReplaceAllStringFunc(m.text, func(match string) string {
+		return m.add(g.expected(match))
+	})
+}
+
+// check 检查译文是否使用了原文中每个术语规定的译法
+func (g *glossaryMatcher) check(path string, source string, translated string) []models.TermIssue {
+	if g == nil {
+		return nil
+	}
+
+	var issues []models.TermIssue
+	seen := map[string]bool{}
+	for _, match := range g.re.FindAllString(source, -1) {
+		expected := g.expected(match)
+		if seen[expected] {
+			continue
+		}
+		seen[expected] = true
+		if !strings.Contains(translated, expected) {
+			issues = append(issues, models.TermIssue{Path: path, Term: match, Expected: expected})
+		}
+	}
+	return issues
+}
+
+// fingerprint 术语表的摘要，术语表变化后不再使用之前的翻译记忆
+func (g *glossaryMatcher) fingerprint() string {
+	if g == nil {
+		return ""
+	}
+
+	var entries []string
+	for _, terms := range []map[string]models.GlossaryTerm{g.exact, g.fold} {
+		for source, term := range terms {
+			entries = append(entries, source+"\x00"+g.expected(term.Source))
+		}
+	}
+	sort.Strings(entries)
+	sum := sha1.Sum([]byte(strings.Join(entries, "\x01")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
@@ -0,0 +1,495 @@
+package translate
+
+import (
+	"fmt"
+	"json_trans_api/pkg/logger"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type icuNodeKind int
+
+const (
+	icuText       icuNodeKind = iota // 普通文字，需要翻译
+	icuPound                         // plural 分支中的 #
+	icuSimpleArg                     // {name} {n, number} 等参数，原样保留
+	icuComplexArg                    // plural select selectordinal
+)
+
+// icuNode ICU MessageFormat 的语法节点
+type icuNode struct {
+	kind     icuNodeKind
+	text     string // icuText 为去掉引号转义后的文字，icuSimpleArg 为原样的参数
+	name     string
+	argType  string
+	offset   string
+	branches []*icuBranch
+}
+
+type icuBranch struct {
+	key     string
+	message *icuMessage
+}
+
+// icuMessage 一段消息，translated 不为空时使用翻译后的节点
+type icuMessage struct {
+	nodes      []*icuNode
+	translated []*icuNode
+}
+
+// icuToken 替换参数和 # 的标记，与占位符的标记区分开
+var icuToken = regexp.MustCompile(`⟪\s*(\d+)\s*⟫`)
+
+func formatICUToken(index int) string {
+	return "⟪" + strconv.Itoa(index) + "⟫"
+}
+
+// parseICU 只有包含 plural、select 或 selectordinal 的字符串才按 ICU MessageFormat 处理，
+// 只有简单参数的字符串交给占位符处理
+func parseICU(text string) (*icuMessage, bool) {
+	if !strings.Contains(text, "{") {
+		return nil, false
+	}
+
+	p := &icuParser{s: text}
+	msg, err := p.parseMessage(false)
+	if err != nil || p.pos != len(p.s) || !msg.hasComplexArg() {
+		return nil, false
+	}
+	return msg, true
+}
+
+func (m *icuMessage) hasComplexArg() bool {
+	for _, node := range m.nodes {
+		if node.kind == icuComplexArg {
+			return true
+		}
+	}
+	return false
+}
+
+type icuParser struct {
+	s   string
+	pos int
+}
+
+// parseMessage 解析到未匹配的 } 或字符串结尾为止，} 由调用方处理
+func (p *icuParser) parseMessage(inPlural bool) (*icuMessage, error) {
+	msg := &icuMessage{}
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			msg.nodes = append(msg.nodes, &icuNode{kind: icuText, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '}':
+			flush()
+			return msg, nil
+		case c == '{':
+			flush()
+			node, err := p.parseArg(inPlural)
+			if err != nil {
+				return nil, err
+			}
+			msg.nodes = append(msg.nodes, node)
+		case c == '#' && inPlural:
+			flush()
+			msg.nodes = append(msg.nodes, &icuNode{kind: icuPound})
+			p.pos++
+		case c == '\'':
+			p.parseQuoted(&text, inPlural)
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+	flush()
+	return msg, nil
+}
+
+// parseQuoted 处理单引号转义：两个单引号表示一个单引号，单引号后面是 { } # 时开始一段原样的文字，其他情况单引号就是普通字符
+func (p *icuParser) parseQuoted(text *strings.Builder, inPlural bool) {
+	if p.pos+1 >= len(p.s) {
+		text.WriteByte('\'')
+		p.pos++
+		return
+	}
+
+	next := p.s[p.pos+1]
+	if next == '\'' {
+		text.WriteByte('\'')
+		p.pos += 2
+		return
+	}
+	if next != '{' && next != '}' && !(next == '#' && inPlural) {
+		text.WriteByte('\'')
+		p.pos++
+		return
+	}
+
+	p.pos++
+	for p.pos < len(p.s) {
+		if p.s[p.pos] == '\'' {
+			if p.pos+1 < len(p.s) && p.s[p.pos+1] == '\'' {
+				text.WriteByte('\'')
+				p.pos += 2
+				continue
+			}
+			p.pos++
+			return
+		}
+		text.WriteByte(p.s[p.pos])
+		p.pos++
+	}
+}
+
+func (p *icuParser) parseArg(inPlural bool) (*icuNode, error) {
+	start := p.pos
+	p.pos++
+	p.skipSpace()
+
+	name := p.readWord()
+	if name == "" {
+		return nil, fmt.Errorf("missing argument name at %d", start)
+	}
+	p.skipSpace()
+
+	if p.peek() == '}' {
+		p.pos++
+		return &icuNode{kind: icuSimpleArg, text: p.s[start:p.pos], name: name}, nil
+	}
+	if p.peek() != ',' {
+		return nil, fmt.Errorf("unexpected character at %d", p.pos)
+	}
+	p.pos++
+	p.skipSpace()
+
+	argType := p.readWord()
+	p.skipSpace()
+	switch p.peek() {
+	case '}':
+		p.pos++
+		return &icuNode{kind: icuSimpleArg, text: p.s[start:p.pos], name: name}, nil
+	case ',':
+		p.pos++
+	default:
+		return nil, fmt.Errorf("unexpected character at %d", p.pos)
+	}
+
+	switch argType {
+	case "plural", "selectordinal":
+		return p.parseBranches(name, argType, true)
+	case "select":
+		return p.parseBranches(name, argType, inPlural)
+	}
+
+	// number date time 等参数的样式原样保留，样式中可能有成对的大括号
+	depth := 1
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		p.pos++
+		if depth == 0 {
+			return &icuNode{kind: icuSimpleArg, text: p.s[start:p.pos], name: name}, nil
+		}
+	}
+	return nil, fmt.Errorf("unclosed argument at %d", start)
+}
+
+func (p *icuParser) parseBranches(name string, argType string, inPlural bool) (*icuNode, error) {
+	node := &icuNode{kind: icuComplexArg, name: name, argType: argType}
+	p.skipSpace()
+
+	if argType != "select" && strings.HasPrefix(p.s[p.pos:], "offset:") {
+		p.pos += len("offset:")
+		p.skipSpace()
+		node.offset = "offset:" + p.readWord()
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unclosed %s argument %s", argType, name)
+		}
+		if p.peek() == '}' {
+			p.pos++
+			break
+		}
+
+		key := p.readWord()
+		if key == "" {
+			return nil, fmt.Errorf("missing %s key at %d", argType, p.pos)
+		}
+		p.skipSpace()
+		if p.peek() != '{' {
+			return nil, fmt.Errorf("missing message for %s key %s", argType, key)
+		}
+		p.pos++
+
+		message, err := p.parseMessage(inPlural)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != '}' {
+			return nil, fmt.Errorf("unclosed message for %s key %s", argType, key)
+		}
+		p.pos++
+		node.branches = append(node.branches, &icuBranch{key: key, message: message})
+	}
+
+	if len(node.branches) == 0 {
+		return nil, fmt.Errorf("%s argument %s has no branches", argType, name)
+	}
+	return node, nil
+}
+
+func (p *icuParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *icuParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// readWord 读取参数名、类型或分支的 key，到空白或语法字符为止
+func (p *icuParser) readWord() string {
+	start := p.pos
+	for p.pos < len(p.s) && !unicode.IsSpace(rune(p.s[p.pos])) && !strings.ContainsRune("{},'#", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// icuUnit 一段消息中需要翻译的文字，参数和 # 替换成 ⟪i⟫，refs[i] 为对应的节点
+type icuUnit struct {
+	message *icuMessage
+	text    string
+	refs    []*icuNode
+}
+
+// collectICUUnits 每个分支的消息单独翻译，嵌套的 plural/select 在外层消息中替换成一个标记
+func collectICUUnits(msg *icuMessage, units *[]*icuUnit) {
+	unit := &icuUnit{message: msg}
+	var b strings.Builder
+	for _, node := range msg.nodes {
+		if node.kind == icuText {
+			b.WriteString(node.text)
+			continue
+		}
+
+		b.WriteString(formatICUToken(len(unit.refs)))
+		unit.refs = append(unit.refs, node)
+		for _, branch := range node.branches {
+			collectICUUnits(branch.message, units)
+		}
+	}
+	unit.text = b.String()
+
+	if strings.IndexFunc(icuToken.ReplaceAllString(unit.text, ""), unicode.IsLetter) >= 0 {
+		*units = append(*units, unit)
+	}
+}
+
+// apply 按译文中的标记重新组装节点，标记丢失时返回 PlaceholderError，保留原文
+func (u *icuUnit) apply(translated string) error {
+	found := make([]bool, len(u.refs))
+	var nodes []*icuNode
+	var unknown []string
+
+	last := 0
+	for _, loc := range icuToken.FindAllStringSubmatchIndex(translated, -1) {
+		if loc[0] > last {
+			nodes = append(nodes, &icuNode{kind: icuText, text: translated[last:loc[0]]})
+		}
+		last = loc[1]
+
+		index, err := strconv.Atoi(translated[loc[2]:loc[3]])
+		if err != nil || index >= len(u.refs) {
+			unknown = append(unknown, translated[loc[0]:loc[1]])
+			continue
+		}
+		found[index] = true
+		nodes = append(nodes, u.refs[index])
+	}
+	if last < len(translated) {
+		nodes = append(nodes, &icuNode{kind: icuText, text: translated[last:]})
+	}
+
+	var missing []string
+	for i, ok := range found {
+		if !ok {
+			missing = append(missing, u.refs[i].describe())
+		}
+	}
+	if len(missing) > 0 || len(unknown) > 0 {
+		return &PlaceholderError{Text: u.text, Missing: missing, Unknown: unknown}
+	}
+
+	u.message.translated = nodes
+	return nil
+}
+
+func (n *icuNode) describe() string {
+	switch n.kind {
+	case icuPound:
+		return "#"
+	case icuComplexArg:
+		return "{" + n.name + ", " + n.argType + "}"
+	default:
+		return n.text
+	}
+}
+
+// icuRenderer 把消息重新输出为 ICU MessageFormat，plural 的分支按目标语言的 CLDR 复数类别增删
+type icuRenderer struct {
+	lang string
+}
+
+func (r icuRenderer) render(msg *icuMessage, inPlural bool) string {
+	nodes := msg.translated
+	if nodes == nil {
+		nodes = msg.nodes
+	}
+
+	var b strings.Builder
+	for i, node := range nodes {
+		switch node.kind {
+		case icuText:
+			b.WriteString(escapeICUText(node.text, inPlural, i < len(nodes)-1))
+		case icuPound:
+			b.WriteByte('#')
+		case icuSimpleArg:
+			b.WriteString(node.text)
+		case icuComplexArg:
+			b.WriteString(r.renderComplexArg(node, inPlural))
+		}
+	}
+	return b.String()
+}
+
+func (r icuRenderer) renderComplexArg(node *icuNode, inPlural bool) string {
+	var b strings.Builder
+	b.WriteString("{" + node.name + ", " + node.argType + ",")
+	if node.offset != "" {
+		b.WriteString(" " + node.offset)
+	}
+
+	branchInPlural := inPlural || node.argType != "select"
+	for _, branch := range r.branches(node) {
+		b.WriteString(" " + branch.key + " {" + r.render(branch.message, branchInPlural) + "}")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// branches 保留 =0 这类精确匹配的分支，去掉目标语言没有的类别，缺少的类别复制 other 分支
+func (r icuRenderer) branches(node *icuNode) []*icuBranch {
+	if node.argType == "select" {
+		return node.branches
+	}
+
+	categories := pluralCategories(r.lang, node.argType == "selectordinal")
+	if categories == nil {
+		return node.branches
+	}
+
+	var result []*icuBranch
+	byCategory := make(map[string]*icuBranch)
+	for _, branch := range node.branches {
+		if IsPluralCategory(branch.key) {
+			byCategory[branch.key] = branch
+			continue
+		}
+		result = append(result, branch)
+	}
+
+	other, ok := byCategory["other"]
+	if !ok {
+		return node.branches
+	}
+
+	for _, category := range categories {
+		if branch, ok := byCategory[category]; ok {
+			result = append(result, branch)
+			continue
+		}
+		result = append(result, &icuBranch{key: category, message: other.message})
+	}
+	return result
+}
+
+// escapeICUText 文字中的大括号和 plural 分支中的 # 需要用单引号转义，
+// 单引号后面是语法字符或者后面紧跟参数时写成两个单引号
+func escapeICUText(text string, inPlural bool, beforeArg bool) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '{' || c == '}' || (c == '#' && inPlural):
+			b.WriteString("'" + string(c) + "'")
+		case c == '\'':
+			if (i+1 < len(text) && strings.ContainsRune("{}#'", rune(text[i+1]))) || (i+1 == len(text) && beforeArg) {
+				b.WriteString("''")
+			} else {
+				b.WriteByte('\'')
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// expandICUSegments 把 ICU MessageFormat 的字符串拆成各个分支中的文字分别翻译，
+// 每段译文写回后按目标语言重新组装整条消息
+func expandICUSegments(segments []*segment, targetLang string) []*segment {
+	renderer := icuRenderer{lang: targetLang}
+
+	var expanded []*segment
+	for _, seg := range segments {
+		msg, ok := parseICU(seg.text)
+		if !ok {
+			expanded = append(expanded, seg)
+			continue
+		}
+
+		var units []*icuUnit
+		collectICUUnits(msg, &units)
+
+		parent := seg
+		for _, unit := range units {
+			unit := unit
+			expanded = append(expanded, &segment{
+				path:     parent.path,
+				text:     unit.text,
+				format:   parent.format,
+				key:      parent.key,
+				siblings: parent.siblings,
+				set: func(translated string) error {
+					if err := unit.apply(translated); err != nil {
+						logger.Logger.Error("Error with ICU message restore", "path", parent.path, "error", err.Error())
+						return err
+					}
+					return parent.set(renderer.render(msg, false))
+				},
+			})
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,161 @@
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"json_trans_api/models/models"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// 译文中 key 的形状，为空时保持原文的形状
+const (
+	KeyStyleFlat   = "flat"   // 扁平的 key，如 {"home.title": "Home"}
+	KeyStyleNested = "nested" // 嵌套的对象，如 {"home": {"title": "Home"}}
+)
+
+// DefaultKeySeparator 扁平的 key 中各层之间默认的分隔符
+const DefaultKeySeparator = "."
+
+// KeyCollisionError 转换 key 的形状时两个 key 对应同一个路径，例如同时存在 a 和 a.b
+type KeyCollisionError struct {
+	Key  string // 发生冲突的 key
+	Path string // 冲突的路径
+}
+
+func (e *KeyCollisionError) Error() string {
+	return fmt.Sprintf("key %q collides with another key at %s", e.Key, e.Path)
+}
+
+// ValidateKeyStyle 检查 key 的形状和分隔符，分隔符只能和形状一起指定
+func ValidateKeyStyle(style string, separator string) error {
+	switch style {
+	case "", KeyStyleFlat, KeyStyleNested:
+	default:
+		return fmt.Errorf("unsupported key style %q", style)
+	}
+	if separator != "" && style == "" {
+		return errors.New("key separator requires a key style")
+	}
+	return nil
+}
+
+func keySeparator(config models.Config) string {
+	if config.KeySeparator != "" {
+		return config.KeySeparator
+	}
+	return DefaultKeySeparator
+}
+
+// decodeKeys 解析 JSON，指定了 key 的形状时把扁平的 key 展开成嵌套的对象。
+// 路径规则、增量翻译、合并模式和各种报告都按嵌套的路径处理，两种形状的结果一致
+func decodeKeys(data string, config models.Config) (interface{}, error) {
+	value, err := decodeJSON(data)
+	if err != nil || config.KeyStyle == "" {
+		return value, err
+	}
+	return unflattenKeys(value, keySeparator(config))
+}
+
+// shapeKeys 按指定的 key 的形状输出翻译后的根节点，decodeKeys 已经展开成嵌套的对象
+func shapeKeys(value interface{}, config models.Config) (interface{}, error) {
+	if config.KeyStyle != KeyStyleFlat {
+		return value, nil
+	}
+	obj, ok := asOrderedMap(value)
+	if !ok {
+		return value, nil
+	}
+	flat := orderedmap.New()
+	if err := flattenInto(flat, obj, "", nil, keySeparator(config)); err != nil {
+		return nil, err
+	}
+	return flat, nil
+}
+
+// unflattenKeys 按分隔符把对象的 key 拆成多层，已经嵌套的对象和扁平的 key 可以混用，
+// 指向同一个字符串或者一个是字符串、一个是对象时返回 KeyCollisionError。数组中的对象不展开
+func unflattenKeys(value interface{}, separator string) (interface{}, error) {
+	obj, ok := asOrderedMap(value)
+	if !ok {
+		return value, nil
+	}
+	nested := orderedmap.New()
+	if err := unflattenInto(nested, obj, nil, separator); err != nil {
+		return nil, err
+	}
+	return nested, nil
+}
+
+func unflattenInto(dst *orderedmap.OrderedMap, src *orderedmap.OrderedMap, tokens []string, separator string) error {
+	for _, key := range src.Keys() {
+		value, _ := src.Get(key)
+		parts := strings.Split(key, separator)
+
+		obj := dst
+		path := tokens
+		for _, part := range parts[:len(parts)-1] {
+			path = appendToken(path, part)
+			next, ok := obj.Get(part)
+			if !ok {
+				child := orderedmap.New()
+				obj.Set(part, child)
+				obj = child
+				continue
+			}
+			child, isObject := next.(*orderedmap.OrderedMap)
+			if !isObject {
+				return &KeyCollisionError{Key: key, Path: formatPath(path)}
+			}
+			obj = child
+		}
+
+		last := parts[len(parts)-1]
+		path = appendToken(path, last)
+		existing, exists := obj.Get(last)
+		if child, isObject := asOrderedMap(value); isObject {
+			if !exists {
+				existing = orderedmap.New()
+				obj.Set(last, existing)
+			}
+			target, ok := existing.(*orderedmap.OrderedMap)
+			if !ok {
+				return &KeyCollisionError{Key: key, Path: formatPath(path)}
+			}
+			if err := unflattenInto(target, child, path, separator); err != nil {
+				return err
+			}
+			continue
+		}
+		if exists {
+			return &KeyCollisionError{Key: key, Path: formatPath(path)}
+		}
+		obj.Set(last, value)
+	}
+	return nil
+}
+
+// flattenInto 把嵌套的对象用分隔符拼成一层的 key，空对象和数组作为值保留
+func flattenInto(dst *orderedmap.OrderedMap, src *orderedmap.OrderedMap, prefix string, tokens []string, separator string) error {
+	for _, key := range src.Keys() {
+		value, _ := src.Get(key)
+		name := key
+		if prefix != "" {
+			name = prefix + separator + key
+		}
+		path := appendToken(tokens, key)
+
+		if child, ok := asOrderedMap(value); ok && len(child.Keys()) > 0 {
+			if err := flattenInto(dst, child, name, path, separator); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, exists := dst.Get(name); exists {
+			return &KeyCollisionError{Key: name, Path: formatPath(path)}
+		}
+		dst.Set(name, value)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"json_trans_api/pkg/logger"
+	"json_trans_api/utils/translateapi"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Limiter 限制调用翻译接口的速率，分布式实现见 rds.RateLimiter
+type Limiter interface {
+	Wait(ctx context.Context, from string, to string) error
+}
+
+// SetLimiter 替换默认的进程内限流器，多个 worker 部署时使用基于Redis的分布式限流。
+// 分布式限流器出错(例如Redis不可用)时退回到进程内的令牌桶，不让整批翻译失败
+func SetLimiter(l Limiter) {
+	limiter = &fallbackLimiter{primary: l, fallback: localLimiter}
+}
+
+// fallbackLimiter primary 返回 ctx 以外的错误时改用 fallback
+type fallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+}
+
+func (f *fallbackLimiter) Wait(ctx context.Context, from string, to string) error {
+	err := f.primary.Wait(ctx, from, to)
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	logger.Logger.Error("Error with rate limiter, falling back to local token bucket", "error", err.Error())
+	return f.fallback.Wait(ctx, from, to)
+}
+
+// TokenBucket 令牌桶限流，按 qps 匀速生成令牌，最多积攒 burst 个
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(qps float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   qps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait 阻塞到拿到一个令牌，或者 ctx 被取消。进程内只限制总速率，不区分语言对
+func (b *TokenBucket) Wait(ctx context.Context, from string, to string) error {
+	for {
+		delay := b.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 有令牌时取走一个并返回0，否则返回需要等待的时间
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+var (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// withRetry 只对限流和临时错误重试，使用带随机抖动的指数退避
+func withRetry(ctx context.Context, maxRetries int, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if attempt >= maxRetries || !translateapi.IsRetryable(err) {
+			return err
+		}
+
+		backoff := retryBaseDelay << attempt
+		if backoff > retryMaxDelay || backoff <= 0 {
+			backoff = retryMaxDelay
+		}
+		// full jitter: 在 [0, backoff) 之间随机等待，避免多个任务同时重试
+		delay := time.Duration(rand.Int63n(int64(backoff)))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
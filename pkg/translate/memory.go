@@ -0,0 +1,377 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"json_trans_api/pkg/httpclient"
+	"json_trans_api/pkg/logger"
+	"json_trans_api/utils/translateapi"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// MemoryStore 翻译记忆的存储，Get 返回的结果与 keys 一一对应，未命中为空字符串
+type MemoryStore interface {
+	Get(ctx context.Context, userID string, keys []string) ([]string, error)
+	Set(ctx context.Context, userID string, entries map[string]string) error
+	Purge(ctx context.Context, userID string) error
+}
+
+var (
+	memoryStore  MemoryStore
+	memoryHits   int64
+	memoryMisses int64
+)
+
+// SetMemoryStore 设置翻译记忆的存储，未设置时不使用翻译记忆
+func SetMemoryStore(store MemoryStore) {
+	memoryStore = store
+}
+
+// SetupMemory 按配置启用翻译记忆，cache 为Redis缓存，开启 durable 时未命中再查 translation_memory 表
+func SetupMemory(cache MemoryStore) {
+	cfg := config.Cfg.Translate.Memory
+	if !cfg.Enabled {
+		return
+	}
+
+	if cfg.Durable {
+		SetMemoryStore(NewTieredMemoryStore(cache, NewSupabaseMemoryStore()))
+		return
+	}
+	SetMemoryStore(cache)
+}
+
+// MemoryStats 当前进程翻译记忆的命中和未命中次数
+func MemoryStats() (hits int64, misses int64) {
+	return atomic.LoadInt64(&memoryHits), atomic.LoadInt64(&memoryMisses)
+}
+
+// LogMemoryStats 定时记录当前进程翻译记忆的命中和未命中次数，用于监控
+func LogMemoryStats() {
+	for {
+		time.Sleep(time.Minute * 1)
+		hits, misses := MemoryStats()
+		logger.Logger.Info("translation memory stats", "hits", hits, "misses", misses)
+	}
+}
+
+// PurgeMemory 清除用户自己的翻译记忆
+func PurgeMemory(ctx context.Context, userID string) error {
+	if memoryStore == nil {
+		return nil
+	}
+	return memoryStore.Purge(ctx, userID)
+}
+
+// MemoryKeyPrefix 用户翻译记忆的key前缀
+func MemoryKeyPrefix(userID string) string {
+	return "tm:" + userID + ":"
+}
+
+// memoryKey 翻译记忆的key：用户、翻译服务商、语言对和规范化后原文的哈希，
+// 按 HTML 或 Markdown 翻译的结果与纯文本不同，哈希中包含格式。
+// 使用术语表时 glossary 为术语表的摘要，术语表变化后不再命中之前的译文
+func memoryKey(config models.Config, glossary string, source sourceKey) string {
+	normalized := normalizeText(source.text)
+	if source.format != FormatPlain && source.format != "" {
+		normalized = source.format + ":" + normalized
+	}
+	if glossary != "" {
+		normalized = "glossary:" + glossary + ":" + normalized
+	}
+	sum := sha1.Sum([]byte(normalized))
+	return fmt.Sprintf("%s%s:%s:%s:%s", MemoryKeyPrefix(config.UserID), translateapi.DefaultTranslator.Name(), config.SourceLang, config.TargetLang, hex.EncodeToString(sum[:]))
+}
+
+// contextMemoryKey 按注释和描述区分的翻译记忆，由支持上下文的翻译服务商写入，不区分服务商。
+// 不支持上下文的服务商优先使用其中的译文。上下文不包含 key 路径，不同 key 下的相同原文共用一条记录
+func contextMemoryKey(config models.Config, glossary string, source sourceKey) string {
+	if source.context == "" {
+		return ""
+	}
+	normalized := glossary + ":" + source.format + ":" + source.context + "\x00" + normalizeText(source.text)
+	sum := sha1.Sum([]byte(normalized))
+	return fmt.Sprintf("%sctx:%s:%s:%s", MemoryKeyPrefix(config.UserID), config.SourceLang, config.TargetLang, hex.EncodeToString(sum[:]))
+}
+
+// normalizeText 只去掉首尾空白，"Save" 和 " Save " 共用一条翻译记忆，中间的换行和空格保持原样
+func normalizeText(text string) string {
+	return strings.TrimSpace(text)
+}
+
+// withOuterSpace 命中翻译记忆时，按原文补回首尾的空白
+func withOuterSpace(source string, translated string) string {
+	leading := source[:len(source)-len(strings.TrimLeftFunc(source, unicode.IsSpace))]
+	trailing := source[len(strings.TrimRightFunc(source, unicode.IsSpace)):]
+	return leading + translated + trailing
+}
+
+func useMemory(config models.Config) bool {
+	return memoryStore != nil && !config.DisableMemory && config.UserID != ""
+}
+
+// lookupMemory 查询翻译记忆，返回命中的译文和仍需调用翻译接口的文本
+func lookupMemory(ctx context.Context, sources []sourceKey, config models.Config, glossary string) (map[sourceKey]string, []sourceKey) {
+	hits := make(map[sourceKey]string)
+	if !useMemory(config) || len(sources) == 0 {
+		return hits, sources
+	}
+
+	// 先查按上下文区分的记录，服务商不支持上下文时再查只按原文的记录
+	withContext := translateapi.ContextMode() != ""
+	var keys []string
+	contextIndexes := make([]int, len(sources))
+	plainIndexes := make([]int, len(sources))
+	for i, source := range sources {
+		contextIndexes[i], plainIndexes[i] = -1, -1
+		if key := contextMemoryKey(config, glossary, source); key != "" {
+			contextIndexes[i] = len(keys)
+			keys = append(keys, key)
+		}
+		if !withContext || source.context == "" {
+			plainIndexes[i] = len(keys)
+			keys = append(keys, memoryKey(config, glossary, source))
+		}
+	}
+
+	values, err := memoryStore.Get(ctx, config.UserID, keys)
+	if err != nil {
+		logger.Logger.Error("Error with translation memory lookup", "error", err.Error())
+		return hits, sources
+	}
+
+	var misses []sourceKey
+	for i, source := range sources {
+		value := ""
+		if contextIndexes[i] >= 0 {
+			value = values[contextIndexes[i]]
+		}
+		if value == "" && plainIndexes[i] >= 0 {
+			value = values[plainIndexes[i]]
+		}
+		if value == "" {
+			misses = append(misses, source)
+			continue
+		}
+		hits[source] = withOuterSpace(source.text, value)
+	}
+
+	atomic.AddInt64(&memoryHits, int64(len(hits)))
+	atomic.AddInt64(&memoryMisses, int64(len(misses)))
+	logger.Logger.Info("translation memory lookup", "user_id", config.UserID, "hits", len(hits), "misses", len(misses))
+
+	return hits, misses
+}
+
+// saveMemory 保存新翻译的结果，写入失败不影响翻译任务
+func saveMemory(ctx context.Context, translations map[sourceKey]string, config models.Config, glossary string) {
+	if !useMemory(config) || len(translations) == 0 {
+		return
+	}
+
+	// 按上下文翻译的结果只写入按上下文区分的记录，避免覆盖只按原文的记录
+	withContext := translateapi.ContextMode() != ""
+	entries := make(map[string]string, len(translations))
+	for source, translated := range translations {
+		key := memoryKey(config, glossary, source)
+		if withContext && source.context != "" {
+			key = contextMemoryKey(config, glossary, source)
+		}
+		entries[key] = strings.TrimSpace(translated)
+	}
+
+	if err := memoryStore.Set(ctx, config.UserID, entries); err != nil {
+		logger.Logger.Error("Error with translation memory save", "error", err.Error())
+	}
+}
+
+// TieredMemoryStore 先查缓存，未命中再查持久化存储并回填缓存
+type TieredMemoryStore struct {
+	cache   MemoryStore
+	durable MemoryStore
+}
+
+func NewTieredMemoryStore(cache MemoryStore, durable MemoryStore) *TieredMemoryStore {
+	return &TieredMemoryStore{cache: cache, durable: durable}
+}
+
+func (s *TieredMemoryStore) Get(ctx context.Context, userID string, keys []string) ([]string, error) {
+	values, err := s.cache.Get(ctx, userID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var missKeys []string
+	var missIndexes []int
+	for i, value := range values {
+		if value == "" {
+			missKeys = append(missKeys, keys[i])
+			missIndexes = append(missIndexes, i)
+		}
+	}
+	if len(missKeys) == 0 {
+		return values, nil
+	}
+
+	durableValues, err := s.durable.Get(ctx, userID, missKeys)
+	if err != nil {
+		// 持久化存储不可用时只使用缓存的结果
+		logger.Logger.Error("Error with durable translation memory lookup", "error", err.Error())
+		return values, nil
+	}
+
+	backfill := make(map[string]string)
+	for i, value := range durableValues {
+		values[missIndexes[i]] = value
+		if value != "" {
+			backfill[missKeys[i]] = value
+		}
+	}
+
+	if len(backfill) > 0 {
+		if err := s.cache.Set(ctx, userID, backfill); err != nil {
+			logger.Logger.Error("Error with translation memory backfill", "error", err.Error())
+		}
+	}
+	return values, nil
+}
+
+// Set 持久化存储中的记录按用户写入，缓存中的记录由 key 前缀区分用户
+func (s *TieredMemoryStore) Set(ctx context.Context, userID string, entries map[string]string) error {
+	if err := s.cache.Set(ctx, userID, entries); err != nil {
+		return err
+	}
+	return s.durable.Set(ctx, userID, entries)
+}
+
+func (s *TieredMemoryStore) Purge(ctx context.Context, userID string) error {
+	if err := s.cache.Purge(ctx, userID); err != nil {
+		return err
+	}
+	return s.durable.Purge(ctx, userID)
+}
+
+// SupabaseMemoryStore 翻译记忆的持久化存储，对应 translation_memory 表
+type SupabaseMemoryStore struct{}
+
+type memoryRecord struct {
+	Key        string `json:"key"`
+	UserID     string `json:"user_id"`
+	Translated string `json:"translated"`
+	UpdateTime string `json:"update_time,omitempty"`
+}
+
+func NewSupabaseMemoryStore() *SupabaseMemoryStore {
+	return &SupabaseMemoryStore{}
+}
+
+// Get 按每次最多100个key分批查询，避免URL过长
+func (s *SupabaseMemoryStore) Get(ctx context.Context, userID string, keys []string) ([]string, error) {
+	translations := make(map[string]string, len(keys))
+	for start := 0; start < len(keys); start += 100 {
+		end := start + 100
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		queryParams := url.Values{}
+		queryParams.Add("select", "key,translated")
+		queryParams.Add("user_id", "eq."+userID)
+		queryParams.Add("key", "in.("+strings.Join(keys[start:end], ",")+")")
+
+		var records []memoryRecord
+		if err := supabaseMemoryRequest(ctx, http.MethodGet, queryParams, nil, &records); err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			translations[record.Key] = record.Translated
+		}
+	}
+
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = translations[key]
+	}
+	return values, nil
+}
+
+func (s *SupabaseMemoryStore) Set(ctx context.Context, userID string, entries map[string]string) error {
+	records := make([]memoryRecord, 0, len(entries))
+	for key, translated := range entries {
+		records = append(records, memoryRecord{
+			Key:        key,
+			UserID:     userID,
+			Translated: translated,
+			UpdateTime: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	return supabaseMemoryRequest(ctx, http.MethodPost, url.Values{}, records, nil)
+}
+
+func (s *SupabaseMemoryStore) Purge(ctx context.Context, userID string) error {
+	queryParams := url.Values{}
+	queryParams.Add("user_id", "eq."+userID)
+	return supabaseMemoryRequest(ctx, http.MethodDelete, queryParams, nil, nil)
+}
+
+func supabaseMemoryRequest(ctx context.Context, method string, queryParams url.Values, body interface{}, out interface{}) error {
+	fullURL := fmt.Sprintf("%s/rest/v1/translation_memory?%s", config.Cfg.Supabase.SupabaseUrl, queryParams.Encode())
+
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal translation memory: %v", err)
+		}
+		reqBody = bytes.NewBuffer(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("apikey", config.Cfg.Supabase.SupabaseSecretKey)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.Cfg.Supabase.SupabaseSecretKey))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		// key 冲突时更新已有的记录
+		req.Header.Set("Prefer", "resolution=merge-duplicates,return=minimal")
+	}
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("supabase request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("translation memory request failed: %s, %s", resp.Status, string(bodyBytes))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(bodyBytes, out); err != nil {
+			return fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package translate
+
+import (
+	"fmt"
+	"json_trans_api/models/models"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// existingTarget 合并模式下已有的目标语言JSON，已有的值全部保留，只翻译缺少的 key
+type existingTarget struct {
+	data   interface{}
+	leaves map[string]bool
+	prune  bool // 删除原文中已经不存在的 key
+}
+
+// ValidateExistingTarget 检查合并模式下已有的目标语言JSON，指定了 key 的形状时按同样的方式展开
+func ValidateExistingTarget(config models.Config) error {
+	_, err := newExistingTarget(config)
+	return err
+}
+
+func newExistingTarget(config models.Config) (*existingTarget, error) {
+	if config.ExistingTarget == "" {
+		return nil, nil
+	}
+
+	data, err := decodeKeys(config.ExistingTarget, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid existing target: %w", err)
+	}
+
+	leaves := map[string]bool{}
+	collectLeaves(data, nil, func(tokens []string, value interface{}) {
+		leaves[formatPath(tokens)] = true
+	})
+	return &existingTarget{data: data, leaves: leaves, prune: config.PruneRemoved}, nil
+}
+
+// filter 目标语言中已经有值的字符串不需要翻译，也不计费
+func (e *existingTarget) filter(segments []*segment) []*segment {
+	if e == nil {
+		return segments
+	}
+
+	var missing []*segment
+	for _, seg := range segments {
+		if !e.leaves[seg.path] {
+			missing = append(missing, seg)
+		}
+	}
+	return missing
+}
+
+// merge 把已有的目标语言的值合并到按原文顺序生成的结果中，原文中不存在的 key 追加到所在对象的末尾或者删除，返回合并后的根节点
+func (e *existingTarget) merge(result interface{}) (interface{}, *models.MergeReport) {
+	if e == nil {
+		return result, nil
+	}
+
+	report := &models.MergeReport{Added: []string{}, Kept: []string{}, Removed: []string{}}
+	return e.mergeValue(result, e.data, nil, report), report
+}
+
+func (e *existingTarget) mergeMap(result *orderedmap.OrderedMap, existing *orderedmap.OrderedMap, tokens []string, report *models.MergeReport) {
+	for _, key := range result.Keys() {
+		value, _ := result.Get(key)
+		keyTokens := appendToken(tokens, key)
+		existingValue, ok := existing.Get(key)
+		if !ok {
+			reportLeaves(value, keyTokens, &report.Added)
+			continue
+		}
+		result.Set(key, e.mergeValue(value, existingValue, keyTokens, report))
+	}
+
+	for _, key := range existing.Keys() {
+		if _, ok := result.Get(key); ok {
+			continue
+		}
+		value, _ := existing.Get(key)
+		if e.prune {
+			reportLeaves(value, appendToken(tokens, key), &report.Removed)
+			continue
+		}
+		reportLeaves(value, appendToken(tokens, key), &report.Kept)
+		result.Set(key, value)
+	}
+}
+
+func (e *existingTarget) mergeArray(result []interface{}, existing []interface{}, tokens []string, report *models.MergeReport) []interface{} {
+	for i := range result {
+		itemTokens := appendToken(tokens, indexToken(i))
+		if i >= len(existing) {
+			reportLeaves(result[i], itemTokens, &report.Added)
+			continue
+		}
+		result[i] = e.mergeValue(result[i], existing[i], itemTokens, report)
+	}
+
+	for i := len(result); i < len(existing); i++ {
+		itemTokens := appendToken(tokens, indexToken(i))
+		if e.prune {
+			reportLeaves(existing[i], itemTokens, &report.Removed)
+			continue
+		}
+		reportLeaves(existing[i], itemTokens, &report.Kept)
+		result = append(result, existing[i])
+	}
+	return result
+}
+
+// mergeValue 对象和数组逐层合并，两边都是叶子节点时保留已有的值，类型不一致时使用原文翻译的结果
+func (e *existingTarget) mergeValue(value interface{}, existing interface{}, tokens []string, report *models.MergeReport) interface{} {
+	resultMap, resultIsMap := asOrderedMap(value)
+	existingMap, existingIsMap := asOrderedMap(existing)
+	if resultIsMap && existingIsMap {
+		e.mergeMap(resultMap, existingMap, tokens, report)
+		return resultMap
+	}
+
+	resultArr, resultIsArr := value.([]interface{})
+	existingArr, existingIsArr := existing.([]interface{})
+	if resultIsArr && existingIsArr {
+		return e.mergeArray(resultArr, existingArr, tokens, report)
+	}
+
+	if !resultIsMap && !resultIsArr && !existingIsMap && !existingIsArr {
+		report.Kept = append(report.Kept, formatPath(tokens))
+		return existing
+	}
+
+	reportLeaves(value, tokens, &report.Added)
+	return value
+}
+
+func asOrderedMap(value interface{}) (*orderedmap.OrderedMap, bool) {
+	switch v := value.(type) {
+	case *orderedmap.OrderedMap:
+		return v, true
+	case orderedmap.OrderedMap:
+		return &v, true
+	}
+	return nil, false
+}
+
+// reportLeaves 把节点下所有叶子节点的路径记录到报告中
+func reportLeaves(value interface{}, tokens []string, paths *[]string) {
+	collectLeaves(value, tokens, func(leafTokens []string, _ interface{}) {
+		*paths = append(*paths, formatPath(leafTokens))
+	})
+}
@@ -0,0 +1,192 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 占位符类型，创建翻译任务时通过 placeholders 字段选择，逗号分隔
+const (
+	PlaceholderPrintf   = "printf"   // %s %d %1$d %.2f %@
+	PlaceholderMustache = "mustache" // {{var}}
+	PlaceholderDollar   = "dollar"   // ${var}
+	PlaceholderColon    = "colon"    // :name
+	PlaceholderBrace    = "brace"    // {var}
+	PlaceholderHash     = "hash"     // #{var}
+	PlaceholderBracket  = "bracket"  // [var]
+	PlaceholderTag      = "tag"      // <b> </b> <br/>
+)
+
+// placeholderPatterns 按优先级排列，同一位置能匹配多种时取靠前的，例如 #{var} 不会被当成 {var}
+var placeholderPatterns = []struct {
+	name    string
+	pattern string
+}{
+	{PlaceholderDollar, `\$\{[^{}]+\}`},
+	{PlaceholderHash, `#\{[^{}]+\}`},
+	{PlaceholderMustache, `\{\{\{?[^{}]+\}?\}\}`},
+	{PlaceholderBrace, `\{[^{}]+\}`},
+	{PlaceholderPrintf, `%%|%(?:\d+\$)?[-+0#]*\d*(?:\.\d+)?[sdifFeEgGxXoucbq@]`},
+	{PlaceholderColon, `\B:[A-Za-z_]\w*`},
+	{PlaceholderBracket, `\[[^\[\]]+\]`},
+	{PlaceholderTag, `</?[A-Za-z][^<>]*>`},
+}
+
+// defaultPlaceholders 未指定时与之前 ExtractVariables 支持的分隔符保持一致
+var defaultPlaceholders = []string{PlaceholderHash, PlaceholderMustache, PlaceholderBrace, PlaceholderBracket, PlaceholderTag}
+
+// placeholderToken 替换占位符的不透明标记，翻译接口不会翻译或改写这类符号
+var placeholderToken = regexp.MustCompile(`⟦\s*(\d+)\s*⟧`)
+
+func formatPlaceholderToken(index int) string {
+	return "⟦" + strconv.Itoa(index) + "⟧"
+}
+
+// PlaceholderError 译文中的占位符标记丢失或被改写，这条字符串不能使用译文
+type PlaceholderError struct {
+	Text    string
+	Missing []string
+	Unknown []string
+}
+
+func (e *PlaceholderError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("missing placeholders %v", e.Missing))
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, fmt.Sprintf("unknown placeholder tokens %v", e.Unknown))
+	}
+	return fmt.Sprintf("%s in translation of %q", strings.Join(parts, ", "), e.Text)
+}
+
+// GetPlaceholders 把逗号分隔的占位符类型拆分成列表
+func GetPlaceholders(placeholdersStr string) []string {
+	return GetIgnoredFields(placeholdersStr)
+}
+
+// ValidatePlaceholders 检查占位符类型是否支持
+func ValidatePlaceholders(names []string) error {
+	for _, name := range names {
+		found := false
+		for _, p := range placeholderPatterns {
+			if p.name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unsupported placeholder type: %s", name)
+		}
+	}
+	return nil
+}
+
+// neverMatch 没有选中任何占位符类型时使用，不匹配任何文本
+var neverMatch = regexp.MustCompile(`[^\s\S]`)
+
+// compilePlaceholders 把选中的占位符类型按优先级合并成一个正则，names 为空时使用默认的类型，
+// excluded 用于去掉某些类型，例如 HTML 交给翻译接口处理时不需要保护标签
+func compilePlaceholders(names []string, excluded ...string) (*regexp.Regexp, error) {
+	if len(names) == 0 {
+		names = defaultPlaceholders
+	}
+	if err := ValidatePlaceholders(names); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+	for _, name := range excluded {
+		delete(selected, name)
+	}
+
+	var alternatives []string
+	for _, p := range placeholderPatterns {
+		if selected[p.name] {
+			alternatives = append(alternatives, p.pattern)
+		}
+	}
+	if len(alternatives) == 0 {
+		return neverMatch, nil
+	}
+	return regexp.MustCompile(strings.Join(alternatives, "|")), nil
+}
+
+// maskedText 替换占位符后的文本，placeholders[i] 对应标记 ⟦i⟧
+type maskedText struct {
+	text         string
+	placeholders []string
+}
+
+// maskPlaceholders 翻译前把占位符替换成不透明的标记
+func maskPlaceholders(text string, re *regexp.Regexp) maskedText {
+	var m maskedText
+	m.text = m.mask(text, re)
+	return m
+}
+
+// mask 把匹配的部分替换成标记，序号接着已有的占位符
+func (m *maskedText) mask(text string, re *regexp.Regexp) string {
+	return re.ReplaceAllStringFunc(text, m.add)
+}
+
+func (m *maskedText) add(placeholder string) string {
+	m.placeholders = append(m.placeholders, placeholder)
+	return formatPlaceholderToken(len(m.placeholders) - 1)
+}
+
+// onlyPlaceholders 去掉占位符后没有需要翻译的内容，例如 "{name}" 或 "%s: %d"
+func (m maskedText) onlyPlaceholders() bool {
+	rest := placeholderToken.ReplaceAllString(m.text, "")
+	return strings.IndexFunc(rest, unicode.IsLetter) < 0
+}
+
+// htmlMarkup HTML 的标签和实体由翻译接口原样保留，不计入字符数
+var htmlMarkup = regexp.MustCompile(`<[^<>]+>|&(#\d+|#x[0-9a-fA-F]+|[a-zA-Z]+);`)
+
+// billableChars 实际交给翻译接口翻译的字符数，不包括占位符、ICU 的参数和 HTML 标签
+func (m maskedText) billableChars(tagHandling string) int {
+	if m.onlyPlaceholders() {
+		return 0
+	}
+	rest := placeholderToken.ReplaceAllString(m.text, "")
+	rest = icuToken.ReplaceAllString(rest, "")
+	if tagHandling == "html" {
+		rest = htmlMarkup.ReplaceAllString(rest, "")
+	}
+	return utf8.RuneCountInString(rest)
+}
+
+// unmask 把译文中的标记原样替换回占位符，标记丢失或出现未知标记时返回 PlaceholderError
+func (m maskedText) unmask(source string, translated string) (string, error) {
+	found := make([]bool, len(m.placeholders))
+	var unknown []string
+	restored := placeholderToken.ReplaceAllStringFunc(translated, func(token string) string {
+		index, err := strconv.Atoi(placeholderToken.FindStringSubmatch(token)[1])
+		if err != nil || index >= len(m.placeholders) {
+			unknown = append(unknown, token)
+			return token
+		}
+		found[index] = true
+		return m.placeholders[index]
+	})
+
+	var missing []string
+	for i, ok := range found {
+		if !ok {
+			missing = append(missing, m.placeholders[i])
+		}
+	}
+
+	if len(missing) > 0 || len(unknown) > 0 {
+		return "", &PlaceholderError{Text: source, Missing: missing, Unknown: unknown}
+	}
+	return restored, nil
+}
@@ -0,0 +1,105 @@
+package translate
+
+import "strings"
+
+// CLDR 复数类别，按 CLDR 的顺序排列
+var pluralCategoryOrder = []string{"zero", "one", "two", "few", "many", "other"}
+
+// cardinalCategories 各语言基数词使用的复数类别，来自 CLDR 44 plurals.xml
+var cardinalCategories = map[string][]string{
+	"zh": {"other"}, "yue": {"other"}, "ja": {"other"}, "ko": {"other"}, "th": {"other"},
+	"vi": {"other"}, "id": {"other"}, "ms": {"other"}, "lo": {"other"}, "km": {"other"},
+	"my": {"other"}, "jv": {"other"}, "su": {"other"}, "to": {"other"}, "ig": {"other"},
+	"yo": {"other"}, "bo": {"other"},
+
+	"en": {"one", "other"}, "de": {"one", "other"}, "nl": {"one", "other"}, "sv": {"one", "other"},
+	"da": {"one", "other"}, "no": {"one", "other"}, "nb": {"one", "other"}, "fi": {"one", "other"},
+	"et": {"one", "other"}, "el": {"one", "other"}, "hu": {"one", "other"}, "tr": {"one", "other"},
+	"bg": {"one", "other"}, "hi": {"one", "other"}, "bn": {"one", "other"}, "ur": {"one", "other"},
+	"sw": {"one", "other"}, "af": {"one", "other"}, "az": {"one", "other"}, "ka": {"one", "other"},
+	"kk": {"one", "other"}, "ky": {"one", "other"}, "mn": {"one", "other"}, "ne": {"one", "other"},
+	"ta": {"one", "other"}, "te": {"one", "other"}, "ml": {"one", "other"}, "kn": {"one", "other"},
+	"mr": {"one", "other"}, "gu": {"one", "other"}, "pa": {"one", "other"}, "sq": {"one", "other"},
+	"eu": {"one", "other"}, "gl": {"one", "other"}, "fa": {"one", "other"}, "uz": {"one", "other"},
+	"ps": {"one", "other"}, "is": {"one", "other"}, "mk": {"one", "other"}, "fil": {"one", "other"},
+	"tl": {"one", "other"}, "hy": {"one", "other"}, "si": {"one", "other"}, "am": {"one", "other"},
+	"zu": {"one", "other"}, "xh": {"one", "other"}, "ha": {"one", "other"}, "so": {"one", "other"},
+	"lb": {"one", "other"}, "fy": {"one", "other"}, "eo": {"one", "other"}, "tk": {"one", "other"},
+	"tg": {"one", "other"}, "ku": {"one", "other"}, "sd": {"one", "other"}, "ti": {"one", "other"},
+	"fo": {"one", "other"}, "kl": {"one", "other"}, "yi": {"one", "other"}, "ast": {"one", "other"},
+	"ceb": {"one", "other"}, "haw": {"one", "other"}, "mg": {"one", "other"}, "ny": {"one", "other"},
+	"sn": {"one", "other"}, "rm": {"one", "other"}, "tt": {"other"}, "sm": {"other"},
+
+	"fr": {"one", "many", "other"}, "es": {"one", "many", "other"}, "it": {"one", "many", "other"},
+	"pt": {"one", "many", "other"}, "ca": {"one", "many", "other"},
+
+	"ru": {"one", "few", "many", "other"}, "uk": {"one", "few", "many", "other"},
+	"be": {"one", "few", "many", "other"}, "pl": {"one", "few", "many", "other"},
+	"cs": {"one", "few", "many", "other"}, "sk": {"one", "few", "many", "other"},
+	"lt": {"one", "few", "many", "other"},
+
+	"hr": {"one", "few", "other"}, "sr": {"one", "few", "other"}, "bs": {"one", "few", "other"},
+	"hbs": {"one", "few", "other"}, "cnr": {"one", "few", "other"}, "ro": {"one", "few", "other"},
+
+	"lv": {"zero", "one", "other"},
+	"he": {"one", "two", "other"},
+	"sl": {"one", "two", "few", "other"},
+	"gd": {"one", "two", "few", "other"},
+	"ga": {"one", "two", "few", "many", "other"},
+	"mt": {"one", "two", "few", "many", "other"},
+	"br": {"one", "two", "few", "many", "other"},
+	"ar": {"zero", "one", "two", "few", "many", "other"},
+	"cy": {"zero", "one", "two", "few", "many", "other"},
+}
+
+// ordinalCategories 各语言序数词使用的复数类别，未列出的语言只有 other
+var ordinalCategories = map[string][]string{
+	"en": {"one", "two", "few", "other"},
+	"fr": {"one", "other"}, "ms": {"one", "other"}, "vi": {"one", "other"}, "lo": {"one", "other"},
+	"fil": {"one", "other"}, "tl": {"one", "other"}, "sv": {"one", "other"}, "hu": {"one", "other"},
+	"ne": {"one", "other"}, "ro": {"one", "other"}, "hy": {"one", "other"}, "ga": {"one", "other"},
+	"it": {"many", "other"}, "kk": {"many", "other"}, "sc": {"many", "other"},
+	"uk": {"few", "other"}, "be": {"few", "other"}, "tk": {"few", "other"},
+	"ca": {"one", "two", "few", "other"}, "mr": {"one", "two", "few", "other"},
+	"ka": {"one", "many", "other"}, "sq": {"one", "many", "other"},
+	"mk": {"one", "two", "many", "other"},
+	"az": {"one", "few", "many", "other"},
+	"hi": {"one", "two", "few", "many", "other"}, "bn": {"one", "two", "few", "many", "other"},
+	"gu": {"one", "two", "few", "many", "other"}, "as": {"one", "two", "few", "many", "other"},
+	"or": {"one", "few", "many", "other"},
+	"cy": {"zero", "one", "two", "few", "many", "other"},
+}
+
+// PluralCategories 返回语言基数词使用的复数类别，未知的语言返回 nil，用于 Android、iOS 等格式的复数块
+func PluralCategories(lang string) []string {
+	return pluralCategories(lang, false)
+}
+
+// pluralCategories 返回目标语言需要的复数类别，未知的语言返回 nil，保持原文的分支不变
+func pluralCategories(lang string, ordinal bool) []string {
+	base := strings.ToLower(lang)
+	if i := strings.IndexAny(base, "-_"); i > 0 {
+		base = base[:i]
+	}
+
+	if ordinal {
+		if categories, ok := ordinalCategories[base]; ok {
+			return categories
+		}
+		if _, ok := cardinalCategories[base]; ok {
+			return []string{"other"}
+		}
+		return nil
+	}
+	return cardinalCategories[base]
+}
+
+// IsPluralCategory 是否是 CLDR 的复数类别
+func IsPluralCategory(key string) bool {
+	for _, category := range pluralCategoryOrder {
+		if key == category {
+			return true
+		}
+	}
+	return false
+}
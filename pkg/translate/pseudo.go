@@ -0,0 +1,100 @@
+package translate
+
+import (
+	"json_trans_api/models/models"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 伪本地化的目标语言，不调用翻译服务商，用于在真正翻译之前发现截断和硬编码的文字
+const (
+	PseudoLocale         = "qps-ploc"  // 字母加上重音符号，按比例加长，用方括号包住
+	PseudoLocaleMirrored = "qps-plocm" // 在 qps-ploc 的基础上强制从右到左显示，用于检查 RTL 布局
+)
+
+const (
+	DefaultPseudoExpansion = 30  // 默认加长 30%，接近德语等语言的译文比英文长的比例
+	MaxPseudoExpansion     = 200 // 最多加长 200%
+)
+
+// pseudoLetters 替换成外形相近、带重音符号的字母，仍然能看懂原文
+var pseudoLetters = map[rune]rune{
+	'a': 'á', 'b': 'ƀ', 'c': 'ç', 'd': 'ð', 'e': 'é', 'f': 'ƒ', 'g': 'ĝ', 'h': 'ĥ', 'i': 'í', 'j': 'ĵ', 'k': 'ķ', 'l': 'ļ', 'm': 'ɱ',
+	'n': 'ñ', 'o': 'ö', 'p': 'þ', 'q': 'ǫ', 'r': 'ŕ', 's': 'š', 't': 'ţ', 'u': 'ü', 'v': 'ṽ', 'w': 'ŵ', 'x': 'ẋ', 'y': 'ý', 'z': 'ž',
+	'A': 'Å', 'B': 'Ɓ', 'C': 'Ç', 'D': 'Ð', 'E': 'É', 'F': 'Ƒ', 'G': 'Ĝ', 'H': 'Ĥ', 'I': 'Î', 'J': 'Ĵ', 'K': 'Ķ', 'L': 'Ļ', 'M': 'Ṁ',
+	'N': 'Ñ', 'O': 'Ö', 'P': 'Þ', 'Q': 'Ǫ', 'R': 'Ŕ', 'S': 'Š', 'T': 'Ţ', 'U': 'Û', 'V': 'Ṽ', 'W': 'Ŵ', 'X': 'Ẋ', 'Y': 'Ý', 'Z': 'Ž',
+}
+
+var (
+	// pseudoProtected 占位符标记原样保留
+	pseudoProtected = regexp.MustCompile(`⟦\s*\d+\s*⟧`)
+	// pseudoProtectedHTML html 模式下标签和字符实体也原样保留
+	pseudoProtectedHTML = regexp.MustCompile(`⟦\s*\d+\s*⟧|</?[A-Za-z][^<>]*>|&#?\w+;`)
+)
+
+// IsPseudoLocale 是否是伪本地化的目标语言
+func IsPseudoLocale(lang string) bool {
+	return lang == PseudoLocale || lang == PseudoLocaleMirrored
+}
+
+// pseudoExpansion 未指定时使用默认的加长比例
+func pseudoExpansion(config models.Config) int {
+	if config.PseudoExpansion == nil {
+		return DefaultPseudoExpansion
+	}
+	return *config.PseudoExpansion
+}
+
+// pseudoTranslate 生成一批文本的伪本地化译文，代替翻译服务商的翻译
+func pseudoTranslate(texts []string, tagHandling string, config models.Config) []string {
+	protected := pseudoProtected
+	if tagHandling == "html" {
+		protected = pseudoProtectedHTML
+	}
+
+	translations := make([]string, len(texts))
+	for i, text := range texts {
+		translations[i] = pseudoText(text, protected, pseudoExpansion(config), config.TargetLang == PseudoLocaleMirrored)
+	}
+	return translations
+}
+
+// pseudoText 替换占位符之外的字母，按字母数加长，首尾的空白留在方括号外面
+func pseudoText(text string, protected *regexp.Regexp, expansion int, mirrored bool) string {
+	core := strings.TrimSpace(text)
+	leading := text[:strings.Index(text, core)]
+	trailing := text[len(leading)+len(core):]
+
+	var b strings.Builder
+	letters := 0
+	last := 0
+	convert := func(part string) {
+		for _, r := range part {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+			if replaced, ok := pseudoLetters[r]; ok {
+				r = replaced
+			}
+			b.WriteRune(r)
+		}
+	}
+	for _, loc := range protected.FindAllStringIndex(core, -1) {
+		convert(core[last:loc[0]])
+		b.WriteString(core[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	convert(core[last:])
+
+	if padding := (letters*expansion + 99) / 100; padding > 0 {
+		b.WriteString(" " + strings.Repeat("~", padding))
+	}
+
+	result := "[" + b.String() + "]"
+	if mirrored {
+		// RLO 和 PDF 控制字符强制从右到左显示
+		result = "\u202e" + result + "\u202c"
+	}
+	return leading + result + trailing
+}
@@ -0,0 +1,207 @@
+package translate
+
+import (
+	"json_trans_api/models/models"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// ReviewUnit 交给译员审校的一条字符串，Path 是 a.b[0].c 形式的 key 路径，
+// Placeholders 是译文中需要原样保留的占位符，ICU MessageFormat 的字符串为其中的参数
+type ReviewUnit struct {
+	Path         string
+	Source       string
+	Target       string
+	Placeholders []string
+}
+
+// ReviewUnits 按 TranslateJSON 相同的规则收集原文中需要翻译的字符串，和译文中相同路径的字符串一一对应。
+// 忽略的字段、自动跳过的字符串和译文中不存在的路径不导出，ICU MessageFormat 的字符串整条导出
+func ReviewUnits(source string, target string, config models.Config) ([]ReviewUnit, error) {
+	segments, masker, err := reviewSegments(source, config)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := leafStrings(target, config)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]ReviewUnit, 0, len(segments))
+	for _, seg := range segments {
+		translated, ok := targets[seg.path]
+		if !ok {
+			continue
+		}
+		units = append(units, ReviewUnit{
+			Path:         seg.path,
+			Source:       seg.text,
+			Target:       translated,
+			Placeholders: masker.protected(seg.text),
+		})
+	}
+	return units, nil
+}
+
+// ApplyReview 把审校后的译文按路径写回译文 JSON，reviewed 只使用 Path 和 Target。
+// 原文中没有对应字符串的路径记为未知的 id，占位符和原文不一致的译文不写回，都记录在报告中
+func ApplyReview(source string, target string, reviewed []ReviewUnit, config models.Config) (string, *models.ReviewReport, error) {
+	segments, masker, err := reviewSegments(source, config)
+	if err != nil {
+		return "", nil, err
+	}
+	targets, err := leafStrings(target, config)
+	if err != nil {
+		return "", nil, err
+	}
+	bySource := make(map[string]*segment, len(segments))
+	for _, seg := range segments {
+		bySource[seg.path] = seg
+	}
+
+	report := &models.ReviewReport{Updated: []string{}, UnknownIds: []string{}, PlaceholderChanges: []models.PlaceholderChange{}}
+	values := map[string]string{}
+	for _, unit := range reviewed {
+		seg, ok := bySource[unit.Path]
+		current, found := targets[unit.Path]
+		if !ok || !found {
+			report.UnknownIds = append(report.UnknownIds, unit.Path)
+			continue
+		}
+		if unit.Target == current {
+			report.Unchanged++
+			continue
+		}
+		if change := masker.change(seg, unit.Target); change != nil {
+			report.PlaceholderChanges = append(report.PlaceholderChanges, *change)
+			continue
+		}
+		values[unit.Path] = unit.Target
+		report.Updated = append(report.Updated, unit.Path)
+	}
+	if len(values) == 0 {
+		return target, report, nil
+	}
+
+	data, err := decodeKeys(target, config)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err = shapeKeys(replaceStrings(data, nil, values), config)
+	if err != nil {
+		return "", nil, err
+	}
+	// 保持译文原来的格式，只有审校修改的字符串产生差异
+	output, err := detectStyle(target, config).encode(data)
+	if err != nil {
+		return "", nil, err
+	}
+	return output, report, nil
+}
+
+// reviewSegments 收集原文中需要翻译的字符串，不拆分 ICU MessageFormat
+func reviewSegments(source string, config models.Config) ([]*segment, *segmentMasker, error) {
+	data, err := decodeKeys(source, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := newFieldRules(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	var segments []*segment
+	if _, err := collectDocument(data, rules, &segments); err != nil {
+		return nil, nil, err
+	}
+	masker, err := newSegmentMasker(config.Placeholders, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return segments, masker, nil
+}
+
+// protected 字符串中需要原样保留的占位符，HTML 的标签也包含在内，ICU MessageFormat 返回去重后的参数
+func (s *segmentMasker) protected(text string) []string {
+	if msg, ok := parseICU(text); ok {
+		return icuArguments(msg, nil)
+	}
+	return maskPlaceholders(text, s.plain).placeholders
+}
+
+// change 比较审校后的译文和原文的占位符，一致时返回 nil。ICU MessageFormat 的分支随语言增删，只比较参数是否相同
+func (s *segmentMasker) change(seg *segment, reviewed string) *models.PlaceholderChange {
+	want := s.protected(seg.text)
+	var got []string
+	if _, ok := parseICU(seg.text); ok {
+		if msg, ok := parseICU(reviewed); ok {
+			got = icuArguments(msg, nil)
+		}
+	} else {
+		got = s.protected(reviewed)
+	}
+
+	missing, added := placeholderDiff(want, got), placeholderDiff(got, want)
+	if len(missing) == 0 && len(added) == 0 {
+		return nil
+	}
+	return &models.PlaceholderChange{Path: seg.path, Missing: missing, Added: added}
+}
+
+// placeholderDiff 返回 a 中比 b 多出的占位符，重复的占位符按个数比较
+func placeholderDiff(a []string, b []string) []string {
+	counts := make(map[string]int, len(b))
+	for _, p := range b {
+		counts[p]++
+	}
+	diff := []string{}
+	for _, p := range a {
+		if counts[p] > 0 {
+			counts[p]--
+			continue
+		}
+		diff = append(diff, p)
+	}
+	return diff
+}
+
+// icuArguments 按出现的顺序收集消息和各个分支中的参数，# 不作为参数
+func icuArguments(msg *icuMessage, args []string) []string {
+	for _, node := range msg.nodes {
+		if node.kind == icuText || node.kind == icuPound {
+			continue
+		}
+		found := false
+		for _, arg := range args {
+			found = found || arg == node.describe()
+		}
+		if !found {
+			args = append(args, node.describe())
+		}
+		for _, branch := range node.branches {
+			args = icuArguments(branch.message, args)
+		}
+	}
+	return args
+}
+
+// replaceStrings 按路径替换字符串叶子节点，对象和数组原地修改，根节点是字符串时返回替换后的值
+func replaceStrings(elem interface{}, tokens []string, values map[string]string) interface{} {
+	switch v := elem.(type) {
+	case *orderedmap.OrderedMap:
+		for _, key := range v.Keys() {
+			value, _ := v.Get(key)
+			v.Set(key, replaceStrings(value, appendToken(tokens, key), values))
+		}
+	case orderedmap.OrderedMap:
+		return replaceStrings(&v, tokens, values)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = replaceStrings(item, appendToken(tokens, indexToken(i)), values)
+		}
+	case string:
+		if value, ok := values[formatPath(tokens)]; ok {
+			return value
+		}
+	}
+	return elem
+}
@@ -0,0 +1,241 @@
+package translate
+
+import (
+	"fmt"
+	"json_trans_api/models/models"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// 路径规则中的通配符
+const (
+	anyKey     = "*"   // 任意一层的 key 或数组下标
+	anyIndex   = "[*]" // 任意数组下标
+	anyDepth   = "**"  // 任意多层，包括0层
+	rootPrefix = "$"   // JSONPath 的根节点，可以省略
+)
+
+// fieldRules 忽略和只翻译的路径规则，翻译和字符统计使用同一套规则，保证计费与实际翻译一致
+//
+// 规则示例：
+//
+//	id             只匹配根节点下的 id
+//	user.profile.* user.profile 下的所有字段
+//	items[*].sku   items 数组中每个元素的 sku
+//	**.url         任意层级的 url
+//	$.meta         与 meta 相同
+//	['a.b']        key 中包含 . 或 [ 时用引号括起来
+type fieldRules struct {
+	ignored  [][]string
+	included [][]string
+	formats  []formatRule
+	skip     *skipClassifier
+	skipped  []models.SkippedValue // 收集时自动跳过的字符串
+}
+
+// GetIgnoredFields 把逗号分隔的路径规则拆分成列表，忽略空白
+func GetIgnoredFields(ignoredFieldsStr string) []string {
+	fields := []string{}
+	for _, field := range strings.Split(ignoredFieldsStr, ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// GetIncludedFields 与 GetIgnoredFields 相同，用于只翻译的路径规则
+func GetIncludedFields(includedFieldsStr string) []string {
+	return GetIgnoredFields(includedFieldsStr)
+}
+
+// ValidateFieldRules 检查路径规则的语法，创建翻译任务时提前返回错误
+func ValidateFieldRules(rules []string) error {
+	for _, rule := range rules {
+		if _, err := parseFieldRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newFieldRules(config models.Config) (*fieldRules, error) {
+	formats, err := parseFormatRules(config.Formats)
+	if err != nil {
+		return nil, err
+	}
+
+	skip, err := newSkipClassifier(config.DisabledDetectors, config.SkipPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &fieldRules{formats: formats, skip: skip}
+	for _, rule := range config.IgnoredFields {
+		pattern, err := parseFieldRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules.ignored = append(rules.ignored, pattern)
+	}
+	for _, rule := range config.IncludedFields {
+		pattern, err := parseFieldRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules.included = append(rules.included, pattern)
+	}
+	return rules, nil
+}
+
+// isIgnored 节点匹配任意一条忽略规则时，整个子树都不翻译
+func (r *fieldRules) isIgnored(tokens []string) bool {
+	for _, pattern := range r.ignored {
+		if matchPath(pattern, tokens) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIncluded 没有只翻译的规则时翻译全部字段，否则字符串本身或者它的某个父节点需要匹配规则
+func (r *fieldRules) isIncluded(tokens []string) bool {
+	if len(r.included) == 0 {
+		return true
+	}
+	for i := len(tokens); i > 0; i-- {
+		for _, pattern := range r.included {
+			if matchPath(pattern, tokens[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseFieldRule 把规则拆成 token，key 保持原样，数组下标为 "[0]" 或 "[*]"
+func parseFieldRule(rule string) ([]string, error) {
+	rule = strings.TrimSpace(rule)
+	rule = strings.TrimPrefix(rule, rootPrefix)
+	rule = strings.TrimPrefix(rule, ".")
+	if rule == "" {
+		return nil, fmt.Errorf("invalid field rule: empty path")
+	}
+
+	var tokens []string
+	for i := 0; i < len(rule); {
+		switch rule[i] {
+		case '.':
+			if i == 0 || i == len(rule)-1 || rule[i+1] == '.' || rule[i+1] == '[' {
+				return nil, fmt.Errorf("invalid field rule %q: empty key", rule)
+			}
+			i++
+		case '[':
+			end := strings.IndexByte(rule[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid field rule %q: missing ]", rule)
+			}
+			inner := rule[i+1 : i+end]
+			token, err := bracketToken(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid field rule %q: %v", rule, err)
+			}
+			tokens = append(tokens, token)
+			i += end + 1
+		default:
+			end := strings.IndexAny(rule[i:], ".[")
+			if end < 0 {
+				end = len(rule) - i
+			}
+			key := rule[i : i+end]
+			if _, err := path.Match(key, ""); err != nil {
+				return nil, fmt.Errorf("invalid field rule %q: %v", rule, err)
+			}
+			tokens = append(tokens, key)
+			i += end
+		}
+	}
+	return tokens, nil
+}
+
+// bracketToken 解析方括号中的内容：下标、* 或者带引号的 key
+func bracketToken(inner string) (string, error) {
+	if inner == "*" {
+		return anyIndex, nil
+	}
+	if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+		// 引号中的 key 按字面匹配，转义 glob 的特殊字符
+		return globEscaper.Replace(inner[1 : len(inner)-1]), nil
+	}
+	if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+		return indexToken(index), nil
+	}
+	return "", fmt.Errorf("invalid index [%s]", inner)
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+func indexToken(index int) string {
+	return "[" + strconv.Itoa(index) + "]"
+}
+
+func isIndexToken(token string) bool {
+	return strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]")
+}
+
+// matchPath 判断节点路径是否完整匹配规则，** 可以匹配任意多层
+func matchPath(pattern []string, tokens []string) bool {
+	if len(pattern) == 0 {
+		return len(tokens) == 0
+	}
+
+	if pattern[0] == anyDepth {
+		for i := 0; i <= len(tokens); i++ {
+			if matchPath(pattern[1:], tokens[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(tokens) == 0 || !matchToken(pattern[0], tokens[0]) {
+		return false
+	}
+	return matchPath(pattern[1:], tokens[1:])
+}
+
+func matchToken(pattern string, token string) bool {
+	switch {
+	case pattern == anyKey:
+		return true
+	case pattern == anyIndex:
+		return isIndexToken(token)
+	case isIndexToken(pattern) || isIndexToken(token):
+		return pattern == token
+	}
+
+	// key 支持 glob，例如 *_url
+	matched, err := path.Match(pattern, token)
+	return err == nil && matched
+}
+
+// appendToken 复制一份路径再追加，避免兄弟节点共用底层数组
+func appendToken(tokens []string, token string) []string {
+	next := make([]string, len(tokens), len(tokens)+1)
+	copy(next, tokens)
+	return append(next, token)
+}
+
+// formatPath 把 token 拼成 a.b[0].c 形式的路径，用于日志和报告
+func formatPath(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		if !isIndexToken(token) && b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(token)
+	}
+	return b.String()
+}
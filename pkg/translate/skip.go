@@ -0,0 +1,99 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 自动跳过的值的类型，创建翻译任务时可以通过 disabled_detectors 关闭
+const (
+	DetectorURL        = "url"
+	DetectorEmail      = "email"
+	DetectorUUID       = "uuid"
+	DetectorDate       = "date"
+	DetectorColor      = "color"
+	DetectorNumber     = "number"
+	DetectorIdentifier = "identifier"
+
+	// skipReasonPattern 匹配用户自定义的正则
+	skipReasonPattern = "pattern"
+)
+
+type skipDetector struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// skipDetectors 整个字符串(去掉首尾空白)匹配时不翻译，也不统计字符数
+var skipDetectors = []skipDetector{
+	{DetectorURL, regexp.MustCompile(`^((https?|ftp)://|mailto:|www\.)\S+$`)},
+	{DetectorEmail, regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`)},
+	{DetectorUUID, regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)},
+	{DetectorDate, regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?)?$`)},
+	{DetectorColor, regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$|^(rgb|rgba|hsl|hsla)\([^()]*\)$`)},
+	{DetectorNumber, regexp.MustCompile(`^[-+]?(\d+|\d{1,3}(,\d{3})+)(\.\d+)?([eE][-+]?\d+)?%?$`)},
+	// btn_primary、app.title、MAX_COUNT 这类标识符，e-mail 这类连字符单词仍然翻译
+	{DetectorIdentifier, regexp.MustCompile(`^[a-z][a-z0-9]*([_.][a-z0-9]+)+$|^[A-Z][A-Z0-9]*(_[A-Z0-9]+)+$`)},
+}
+
+// skipClassifier 判断字符串是否不需要翻译
+type skipClassifier struct {
+	detectors []skipDetector
+}
+
+// GetDisabledDetectors 把逗号分隔的类型拆分成列表
+func GetDisabledDetectors(disabledDetectorsStr string) []string {
+	return GetIgnoredFields(disabledDetectorsStr)
+}
+
+// ValidateSkipRules 检查关闭的类型是否存在、自定义的正则是否合法
+func ValidateSkipRules(disabledDetectors []string, skipPatterns []string) error {
+	_, err := newSkipClassifier(disabledDetectors, skipPatterns)
+	return err
+}
+
+func newSkipClassifier(disabledDetectors []string, skipPatterns []string) (*skipClassifier, error) {
+	disabled := make(map[string]bool, len(disabledDetectors))
+	for _, name := range disabledDetectors {
+		found := false
+		for _, detector := range skipDetectors {
+			if detector.name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported detector: %s", name)
+		}
+		disabled[name] = true
+	}
+
+	classifier := &skipClassifier{}
+	for _, detector := range skipDetectors {
+		if !disabled[detector.name] {
+			classifier.detectors = append(classifier.detectors, detector)
+		}
+	}
+
+	// 自定义的正则在字符串中匹配到即跳过，需要整串匹配时自行加上 ^ 和 $
+	for _, pattern := range skipPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid skip pattern %q: %v", pattern, err)
+		}
+		classifier.detectors = append(classifier.detectors, skipDetector{name: skipReasonPattern, re: re})
+	}
+	return classifier, nil
+}
+
+// classify 返回跳过的原因，需要翻译时返回空字符串
+func (c *skipClassifier) classify(text string) string {
+	text = strings.TrimSpace(text)
+	for _, detector := range c.detectors {
+		if detector.re.MatchString(text) {
+			return detector.name
+		}
+	}
+	return ""
+}
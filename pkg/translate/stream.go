@@ -0,0 +1,362 @@
+package translate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"json_trans_api/models/models"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 流式翻译时每累积这么多待翻译的字符串、字符数或者待写出的字节数，就翻译一次并写出，
+// 内存占用只和窗口大小有关，与文档大小无关
+const (
+	streamWindowSegments = 500
+	streamWindowChars    = 50000
+	streamWindowBytes    = 1 << 20
+)
+
+// streamStyleBytes 从文档开头读取这么多字节检测缩进、换行和转义的格式
+const streamStyleBytes = 64 << 10
+
+// streamChunk 待写出的一段输出，value 不为空时是一个字符串叶子节点，翻译后写回
+type streamChunk struct {
+	raw   []byte
+	value *string
+}
+
+// streamTranslator 基于 json.Decoder 的 token 逐个遍历文档，收集一个窗口的字符串后翻译并写出
+type streamTranslator struct {
+	ctx    context.Context
+	config models.Config
+	dec    *json.Decoder
+	w      *bufio.Writer
+	rules  *fieldRules
+
+	chunks   []streamChunk
+	segments []*segment
+	chars    int
+	bytes    int
+
+	// flush 翻译或者统计一个窗口中的字符串
+	flush func(segments []*segment) error
+
+	style jsonStyle
+	tail  *tailReader
+	sb    strings.Builder
+}
+
+// tailReader 记录已经读到的内容末尾的空白中是否有换行，读完文档后决定译文末尾是否换行
+type tailReader struct {
+	r            io.Reader
+	finalNewline bool
+}
+
+func (t *tailReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	chunk := p[:n]
+	body := bytes.TrimRight(chunk, " \t\r\n")
+	if len(body) > 0 {
+		t.finalNewline = false
+	}
+	t.finalNewline = t.finalNewline || bytes.IndexByte(chunk[len(body):], '\n') >= 0
+	return n, err
+}
+
+func newStreamTranslator(ctx context.Context, r io.Reader, w io.Writer, config models.Config) (*streamTranslator, error) {
+	if config.PreviousSource != "" || config.ExistingTarget != "" {
+		return nil, errors.New("incremental translation and merge mode are not supported for streaming translation")
+	}
+
+	rules, err := newFieldRules(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// 和 TranslateJson 一样按原文的格式输出，格式从文档开头检测，key 不排序
+	tail := &tailReader{r: r}
+	br := bufio.NewReaderSize(tail, streamStyleBytes)
+	head, err := br.Peek(streamStyleBytes)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	s := &streamTranslator{ctx: ctx, config: config, dec: json.NewDecoder(br), w: bufio.NewWriter(w), rules: rules, tail: tail}
+	s.dec.UseNumber()
+	s.style = detectStyle(string(head), config)
+	return s, nil
+}
+
+// TranslateStream 流式翻译 JSON 文档，边读边翻译边写出，用于几十 MB 的大文件。
+// 字符串的上下文只包含在它之前出现的相邻 key，不支持增量翻译和合并模式。
+// 严格模式下有字符串翻译失败时返回 PartialError，已经写出的内容需要调用方丢弃
+func TranslateStream(ctx context.Context, r io.Reader, w io.Writer, config models.Config) (*Report, error) {
+	s, err := newStreamTranslator(ctx, r, w, config)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Translated: []string{}, Failed: []models.FailedValue{}, Skipped: []models.SkippedValue{}, Terms: []models.TermIssue{}, Issues: []models.QualityIssue{}}
+	s.flush = func(segments []*segment) error {
+		windowReport, err := translateSegments(ctx, expandICUSegments(segments, config.TargetLang), config)
+		if err != nil {
+			return err
+		}
+		report.Translated = append(report.Translated, windowReport.Translated...)
+		report.Failed = append(report.Failed, windowReport.Failed...)
+		report.Terms = append(report.Terms, windowReport.Terms...)
+		report.Issues = append(report.Issues, windowReport.Issues...)
+		return nil
+	}
+
+	if err := s.run(); err != nil {
+		return nil, err
+	}
+	report.Skipped = append(report.Skipped, s.rules.skipped...)
+	if err := strictCheck(report, config); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// AnalyzeStream 流式统计需要翻译的字符数和自动跳过的字符串，与 TranslateStream 实际翻译的文字一致
+func AnalyzeStream(r io.Reader, config models.Config) (*JsonStats, error) {
+	s, err := newStreamTranslator(context.Background(), r, io.Discard, config)
+	if err != nil {
+		return nil, err
+	}
+
+	masker, err := newSegmentMasker(config.Placeholders, config.Glossary)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &JsonStats{Skipped: []models.SkippedValue{}}
+	s.flush = func(segments []*segment) error {
+		for _, seg := range expandICUSegments(segments, config.TargetLang) {
+			m, tagHandling := masker.mask(seg.text, seg.format)
+			stats.Chars += m.billableChars(tagHandling)
+		}
+		return nil
+	}
+
+	if err := s.run(); err != nil {
+		return nil, err
+	}
+	stats.Skipped = append(stats.Skipped, s.rules.skipped...)
+	return stats, nil
+}
+
+func (s *streamTranslator) run() error {
+	if err := s.walkValue(nil, "", nil, false, 0); err != nil {
+		return err
+	}
+	if _, err := s.dec.Token(); err != io.EOF {
+		return errors.New("invalid JSON: unexpected data after the top-level value")
+	}
+	if err := s.flushWindow(); err != nil {
+		return err
+	}
+	if s.tail.finalNewline {
+		if err := s.writeRaw([]byte(s.style.newline)); err != nil {
+			return err
+		}
+	}
+	return s.w.Flush()
+}
+
+// walkValue 写出一个值，ignored 为 true 时值在忽略规则匹配的子树中，原样写出，depth 是值所在的层级
+func (s *streamTranslator) walkValue(tokens []string, key string, siblings []string, ignored bool, depth int) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	token, err := s.dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	switch v := token.(type) {
+	case json.Delim:
+		if v == '{' {
+			return s.walkObject(tokens, ignored, depth)
+		}
+		return s.walkArray(tokens, ignored, depth)
+	case string:
+		if ignored {
+			return s.writeRaw(s.encodeString(v))
+		}
+		return s.addString(tokens, key, siblings, v)
+	case json.Number:
+		return s.writeRaw([]byte(v.String()))
+	case bool:
+		return s.writeRaw([]byte(strconv.FormatBool(v)))
+	case nil:
+		return s.writeRaw([]byte("null"))
+	}
+	return fmt.Errorf("unsupported token: %v", token)
+}
+
+func (s *streamTranslator) walkObject(tokens []string, ignored bool, depth int) error {
+	if err := s.writeRaw([]byte("{")); err != nil {
+		return err
+	}
+
+	// 只记录上下文需要的前几个 key，很大的对象也不会占用太多内存
+	var keys []string
+	n := 0
+	for ; s.dec.More(); n++ {
+		token, err := s.dec.Token()
+		if err != nil {
+			return fmt.Errorf("invalid JSON: %v", err)
+		}
+		key := token.(string)
+
+		if err := s.writeStyled(func(b *strings.Builder) {
+			if n > 0 {
+				s.style.writeComma(b)
+			}
+			s.style.breakLine(b, depth+1)
+			s.style.writeString(b, key)
+			b.WriteString(s.style.colon)
+		}); err != nil {
+			return err
+		}
+
+		if len(keys) <= maxContextSiblings {
+			keys = append(keys, key)
+		}
+		keyTokens := appendToken(tokens, key)
+		if err := s.walkValue(keyTokens, key, keys, ignored || s.rules.isIgnored(keyTokens), depth+1); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.dec.Token(); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	return s.writeClose('}', n, depth)
+}
+
+func (s *streamTranslator) walkArray(tokens []string, ignored bool, depth int) error {
+	if err := s.writeRaw([]byte("[")); err != nil {
+		return err
+	}
+
+	n := 0
+	for ; s.dec.More(); n++ {
+		if err := s.writeStyled(func(b *strings.Builder) {
+			if n > 0 {
+				s.style.writeComma(b)
+			}
+			s.style.breakLine(b, depth+1)
+		}); err != nil {
+			return err
+		}
+		itemTokens := appendToken(tokens, indexToken(n))
+		if err := s.walkValue(itemTokens, "", nil, ignored || s.rules.isIgnored(itemTokens), depth+1); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.dec.Token(); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	return s.writeClose(']', n, depth)
+}
+
+// writeClose 写出对象或数组的结尾，空的对象和数组不换行
+func (s *streamTranslator) writeClose(closer byte, n int, depth int) error {
+	return s.writeStyled(func(b *strings.Builder) {
+		if n > 0 {
+			s.style.breakLine(b, depth)
+		}
+		b.WriteByte(closer)
+	})
+}
+
+// writeStyled 按原文的格式生成一段输出后写出
+func (s *streamTranslator) writeStyled(write func(b *strings.Builder)) error {
+	s.sb.Reset()
+	write(&s.sb)
+	return s.writeRaw([]byte(s.sb.String()))
+}
+
+// addString 字符串先按原值放入输出，需要翻译时加入当前窗口，翻译后替换
+func (s *streamTranslator) addString(tokens []string, key string, siblings []string, text string) error {
+	value := text
+	count := len(s.segments)
+	addSegment(&s.segments, tokens, key, siblings, s.rules, text, func(translated string) error {
+		value = translated
+		return nil
+	})
+	if len(s.segments) == count {
+		return s.writeRaw(s.encodeString(text))
+	}
+
+	s.chunks = append(s.chunks, streamChunk{value: &value})
+	s.chars += utf8.RuneCountInString(text)
+	s.bytes += len(text)
+	if len(s.segments) >= streamWindowSegments || s.chars >= streamWindowChars {
+		return s.flushWindow()
+	}
+	return nil
+}
+
+// writeRaw 窗口中没有待翻译的字符串时直接写出，否则排在待翻译的字符串之后
+func (s *streamTranslator) writeRaw(raw []byte) error {
+	if len(s.segments) == 0 {
+		_, err := s.w.Write(raw)
+		return err
+	}
+
+	if last := len(s.chunks) - 1; s.chunks[last].value == nil {
+		s.chunks[last].raw = append(s.chunks[last].raw, raw...)
+	} else {
+		s.chunks = append(s.chunks, streamChunk{raw: append([]byte(nil), raw...)})
+	}
+	s.bytes += len(raw)
+	if s.bytes >= streamWindowBytes {
+		return s.flushWindow()
+	}
+	return nil
+}
+
+// flushWindow 翻译当前窗口中的字符串，按顺序写出窗口中的输出
+func (s *streamTranslator) flushWindow() error {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	if err := s.flush(s.segments); err != nil {
+		return err
+	}
+
+	for _, chunk := range s.chunks {
+		raw := chunk.raw
+		if chunk.value != nil {
+			raw = s.encodeString(*chunk.value)
+		}
+		if _, err := s.w.Write(raw); err != nil {
+			return err
+		}
+	}
+
+	s.chunks = s.chunks[:0]
+	s.segments = nil
+	s.chars = 0
+	s.bytes = 0
+	return nil
+}
+
+// encodeString 与 TranslateJson 一样按原文的习惯转义字符串
+func (s *streamTranslator) encodeString(text string) []byte {
+	s.sb.Reset()
+	s.style.writeString(&s.sb, text)
+	return []byte(s.sb.String())
+}
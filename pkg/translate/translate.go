@@ -1,154 +1,136 @@
 package translate
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"json_trans_api/models/models"
-	"json_trans_api/pkg/logger"
-	"json_trans_api/utils/translateapi"
 	"log"
-	"reflect"
 	"regexp"
-	"strings"
-	"time"
-	"unicode/utf8"
-
-	"github.com/iancoleman/orderedmap"
 )
 
-func GetIgnoredFields(ignoredFieldsStr string) []string {
-	if ignoredFieldsStr == "" {
-		return []string{}
+// JsonResult 翻译后的JSON和翻译的报告，合并模式下还有合并的报告
+type JsonResult struct {
+	JSON string
+	Report
+	Merge *models.MergeReport
+}
+
+// Report 按路径记录翻译成功、失败和自动跳过的字符串，以及没有使用术语表规定译法的字符串和译文质量检查发现的问题
+type Report struct {
+	Translated []string              // 翻译成功的字符串
+	Failed     []models.FailedValue  // 翻译请求失败、占位符丢失等原因保留原文的字符串
+	Skipped    []models.SkippedValue // 自动跳过、没有翻译的字符串
+	Terms      []models.TermIssue    // 没有使用术语表规定译法的字符串
+	Issues     []models.QualityIssue // 译文质量检查发现的问题
+}
+
+// Status 有字符串翻译失败时为 partial，否则为 complete
+func (r *Report) Status() string {
+	if len(r.Failed) > 0 {
+		return models.TranslationStatusPartial
 	}
+	return models.TranslationStatusComplete
+}
+
+// PartialError 严格模式下有字符串翻译失败，不返回部分翻译的结果
+type PartialError struct {
+	Failed []models.FailedValue
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d strings failed to translate in strict mode, first: %s: %s", len(e.Failed), e.Failed[0].Path, e.Failed[0].Error)
+}
 
-	fields := strings.Split(ignoredFieldsStr, ",")
-	return fields
+// strictCheck 严格模式下有字符串翻译失败时返回 PartialError
+func strictCheck(report *Report, config models.Config) error {
+	if config.Strict && len(report.Failed) > 0 {
+		return &PartialError{Failed: report.Failed}
+	}
+	return nil
 }
 
-func TranslateJson(json_data string, from_lang string, to_lang string, ignored_fields string) (string, error) {
+func TranslateJson(ctx context.Context, json_data string, config models.Config) (*JsonResult, error) {
 
 	var err error
 
-	result := orderedmap.New()
-	if err := json.Unmarshal([]byte(json_data), &result); err != nil {
-		return "", err
+	// 指定了 key 的形状时按嵌套的对象翻译，写回时再转换成指定的形状
+	result, err := decodeKeys(json_data, config)
+	if err != nil {
+		return nil, err
 	}
 
-	config := models.Config{
-		SourceData:    result,
-		SourceLang:    from_lang,
-		TargetLang:    to_lang,
-		IgnoredFields: GetIgnoredFields(ignored_fields),
+	existing, err := newExistingTarget(config)
+	if err != nil {
+		return nil, err
 	}
 
-	config.TranslatedFile, err = TranslateJSON(config)
+	config.SourceData = result
+	// 任务超时或者进程退出时返回错误，由调用方标记失败并交给 asynq 重试
+	var report *Report
+	config.TranslatedFile, report, err = translateJSON(ctx, config, existing)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	// Encoding the map back to JSON
-	buf := new(bytes.Buffer)
-	enc := json.NewEncoder(buf)
-	enc.SetEscapeHTML(false)
-
-	if err := enc.Encode(config.TranslatedFile); err != nil {
-		log.Fatal(err)
+	if err := strictCheck(report, config); err != nil {
+		return nil, err
+	}
+	var merge *models.MergeReport
+	config.TranslatedFile, merge = existing.merge(config.TranslatedFile)
+	if config.TranslatedFile, err = shapeKeys(config.TranslatedFile, config); err != nil {
+		return nil, err
 	}
 
-	return buf.String(), nil
-}
-
-func TranslateJSON(config models.Config) (*orderedmap.OrderedMap, error) {
-	translatedFile := orderedmap.New()
-	keys := config.SourceData.Keys()
-
-	for _, key := range keys {
-		elem, _ := config.SourceData.Get(key)
-
-		if isIgnored(key, config.IgnoredFields) {
-			translatedFile.Set(key, elem)
-			continue
-		}
-
-		translatedElem, err := translateElement(elem, config)
-		if err != nil {
-			log.Printf("Error translating key %s: %v", key, err)
-			translatedFile.Set(key, elem)
-		} else {
-			translatedFile.Set(key, translatedElem)
-		}
+	// 按原文的缩进、换行和转义的习惯写回
+	output, err := detectStyle(json_data, config).encode(config.TranslatedFile)
+	if err != nil {
+		return nil, err
 	}
 
-	return translatedFile, nil
+	return &JsonResult{JSON: output, Report: *report, Merge: merge}, nil
 }
 
-func isIgnored(key string, ignoredFields []string) bool {
-	for _, ignoredKey := range ignoredFields {
-		if key == ignoredKey {
-			return true
-		}
+// TranslateJSON 分两步翻译：先收集所有需要翻译的字符串叶子节点，再去重后批量调用翻译接口并写回，
+// 占位符丢失的字符串保留原文，和失败的原因、没有使用术语表规定译法的字符串、译文质量检查的问题一起返回
+func TranslateJSON(ctx context.Context, config models.Config) (interface{}, *Report, error) {
+	existing, err := newExistingTarget(config)
+	if err != nil {
+		return nil, nil, err
 	}
-	return false
+	return translateJSON(ctx, config, existing)
 }
 
-func translateElement(elem interface{}, config models.Config) (interface{}, error) {
-	switch v := elem.(type) {
-	case *orderedmap.OrderedMap:
-		return translateNestedJSON(v, config)
-	case orderedmap.OrderedMap:
-		return translateNestedJSON(&v, config)
-	case []interface{}:
-		return translateArray(v, config)
-	case string:
-		return translateString(v, config)
-	case float64, bool:
-		return v, nil
-	case nil:
-		return v, nil
-	default:
-		return nil, fmt.Errorf("unsupported type: %v", reflect.TypeOf(elem))
+// translateJSON 按已经解析的合并模式的目标语言JSON翻译，TranslateJson 合并结果时使用同一份
+func translateJSON(ctx context.Context, config models.Config, existing *existingTarget) (interface{}, *Report, error) {
+	rules, err := newFieldRules(config)
+	if err != nil {
+		return nil, nil, err
 	}
-}
-
-func translateNestedJSON(data *orderedmap.OrderedMap, config models.Config) (*orderedmap.OrderedMap, error) {
-	translatedMap := orderedmap.New()
-	for _, key := range data.Keys() {
-		value, _ := data.Get(key)
-
-		if isIgnored(key, config.IgnoredFields) {
-			translatedMap.Set(key, value)
-			continue
-		}
 
-		translatedValue, err := translateElement(value, config)
-		if err != nil {
-			return nil, fmt.Errorf("error translating key %s: %v", key, err)
-		}
-		translatedMap.Set(key, translatedValue)
+	previous, err := newPreviousTranslation(config)
+	if err != nil {
+		return nil, nil, err
 	}
-	return translatedMap, nil
-}
 
-func translateArray(arr []interface{}, config models.Config) ([]interface{}, error) {
-	var translatedArr []interface{}
-	for _, item := range arr {
-		translatedItem, err := translateElement(item, config)
-		if err != nil {
-			return nil, err
-		}
-		translatedArr = append(translatedArr, translatedItem)
+	var segments []*segment
+	translatedFile, err := collectDocument(config.SourceData, rules, &segments)
+	if err != nil {
+		return nil, nil, err
 	}
-	return translatedArr, nil
-}
 
-func translateString(text string, config models.Config) (string, error) {
-	res, err := Translate(text, config)
+	// 增量翻译只翻译新增或修改的字符串
+	segments, _ = previous.reuse(segments)
+	// 合并模式只翻译目标语言中缺少的字符串
+	segments = existing.filter(segments)
+
+	// ICU MessageFormat 的字符串只翻译各个分支中的文字
+	segments = expandICUSegments(segments, config.TargetLang)
+
+	report, err := translateSegments(ctx, segments, config)
 	if err != nil {
-		logger.Logger.Error("Error with Translate", "error", err.Error())
-		return text, err
+		return nil, nil, err
 	}
-	return res, nil
+	report.Skipped = append(report.Skipped, rules.skipped...)
+	return translatedFile.root, report, nil
 }
 
 var delimiters = [][]string{
@@ -159,32 +141,6 @@ var delimiters = [][]string{
 	{"<", "/>"},
 }
 
-func Translate(sourceText string, config models.Config) (string, error) {
-	variablesPre := ExtractVariables(sourceText)
-	var translatedText string
-	translatedText, err := translateapi.Translate(config.SourceLang, config.TargetLang, sourceText)
-
-	// 翻译遇到错误，可能是超过了QPS限制，暂时等待3秒再发起重试
-	if err != nil {
-		time.Sleep(3 * time.Second)
-		translatedText, err := translateapi.Translate(config.SourceLang, config.TargetLang, sourceText)
-		if err != nil {
-			return translatedText, err
-		}
-	}
-
-	if len(variablesPre) > 0 {
-		variablesPost := ExtractVariables(translatedText)
-		if len(variablesPost) == len(variablesPre) {
-			for i, v := range variablesPost {
-				translatedText = strings.Replace(translatedText, v, variablesPre[i], 1)
-			}
-		}
-	}
-
-	return translatedText, nil
-}
-
 // 检查是否与已处理的位置有重叠
 func isOverlapping(start, end int, processed map[int]bool) bool {
 	for i := start; i < end; i++ {
@@ -223,71 +179,67 @@ func ExtractVariables(text string) []string {
 	return variables
 }
 
-// CountJsonChars 统计JSON字符串中value的字符个数
+// JsonStats 需要翻译的字符数和自动跳过的字符串
+type JsonStats struct {
+	Chars   int
+	Skipped []models.SkippedValue
+	Reused  int                 // 增量翻译时沿用上一次译文的字符串个数
+	Merge   *models.MergeReport // 合并模式下新翻译、保留和删除的 key 路径
+}
+
+// CountJsonChars 统计JSON字符串中需要翻译的value的字符个数，与 TranslateJSON 实际交给翻译接口的文字一致
 func CountJsonChars(json_data string, config models.Config) (int, error) {
-	result := orderedmap.New()
-	if err := json.Unmarshal([]byte(json_data), &result); err != nil {
+	stats, err := AnalyzeJson(json_data, config)
+	if err != nil {
 		return 0, err
 	}
+	return stats.Chars, nil
+}
 
-	count, err := countElement(result, config)
+// AnalyzeJson 统计需要翻译的字符数，同时记录 URL、UUID 这类自动跳过的字符串的路径。
+// 与 TranslateJSON 使用相同的收集、ICU 拆分和占位符替换，ICU 的参数和关键字、只有占位符的字符串、
+// 增量翻译时沿用上一次译文的字符串、合并模式下目标语言中已有的字符串都不计费
+func AnalyzeJson(json_data string, config models.Config) (*JsonStats, error) {
+	result, err := decodeKeys(json_data, config)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return count, nil
-}
+	rules, err := newFieldRules(config)
+	if err != nil {
+		return nil, err
+	}
 
-// countElement 递归统计元素字符数
-func countElement(elem interface{}, config models.Config) (int, error) {
-	switch v := elem.(type) {
-	case *orderedmap.OrderedMap:
-		return countOrderedMap(v, config)
-	case orderedmap.OrderedMap:
-		return countOrderedMap(&v, config)
-	case []interface{}:
-		return countArray(v, config)
-	case string:
-		// 使用 utf8.RuneCountInString 正确计算字符数
-		return utf8.RuneCountInString(v), nil
-	case float64, bool:
-		return 0, nil
-	case nil:
-		return 0, nil
-	default:
-		return 0, fmt.Errorf("unsupported type: %v", reflect.TypeOf(elem))
+	previous, err := newPreviousTranslation(config)
+	if err != nil {
+		return nil, err
 	}
-}
 
-// countOrderedMap 统计OrderedMap中的字符数
-func countOrderedMap(data *orderedmap.OrderedMap, config models.Config) (int, error) {
-	totalCount := 0
-	for _, key := range data.Keys() {
-		value, _ := data.Get(key)
+	existing, err := newExistingTarget(config)
+	if err != nil {
+		return nil, err
+	}
 
-		// 忽略的字符不统计字符数量
-		if isIgnored(key, config.IgnoredFields) {
-			continue
-		}
+	var segments []*segment
+	copied, err := collectDocument(result, rules, &segments)
+	if err != nil {
+		return nil, err
+	}
+	segments, reused := previous.reuse(segments)
+	segments = existing.filter(segments)
+	segments = expandICUSegments(segments, config.TargetLang)
 
-		count, err := countElement(value, config)
-		if err != nil {
-			return 0, fmt.Errorf("error counting characters for key %s: %v", key, err)
-		}
-		totalCount += count
+	masker, err := newSegmentMasker(config.Placeholders, config.Glossary)
+	if err != nil {
+		return nil, err
 	}
-	return totalCount, nil
-}
 
-// countArray 统计数组中的字符数
-func countArray(arr []interface{}, config models.Config) (int, error) {
-	totalCount := 0
-	for _, item := range arr {
-		count, err := countElement(item, config)
-		if err != nil {
-			return 0, err
-		}
-		totalCount += count
+	_, merge := existing.merge(copied.root)
+	stats := &JsonStats{Skipped: []models.SkippedValue{}, Reused: reused, Merge: merge}
+	stats.Skipped = append(stats.Skipped, rules.skipped...)
+	for _, seg := range segments {
+		m, tagHandling := masker.mask(seg.text, seg.format)
+		stats.Chars += m.billableChars(tagHandling)
 	}
-	return totalCount, nil
+	return stats, nil
 }
@@ -1,11 +1,1474 @@
 package translate
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"json_trans_api/config"
 	"json_trans_api/models/models"
+	"json_trans_api/utils/translateapi"
+	"net/http"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+// fakeTranslator 本地的假翻译实现，把文本转为大写，测试时不依赖阿里云
+type fakeTranslator struct {
+	mu           sync.Mutex
+	batches      [][]string // 记录每次批量请求的文本
+	tagHandlings []string   // 记录每次批量请求的 TagHandling
+}
+
+func (f *fakeTranslator) Name() string {
+	return "fake"
+}
+
+func (f *fakeTranslator) Translate(ctx context.Context, from string, to string, text string) (string, error) {
+	return strings.ToUpper(text), nil
+}
+
+func (f *fakeTranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	f.mu.Lock()
+	f.batches = append(f.batches, req.Text)
+	f.tagHandlings = append(f.tagHandlings, req.TagHandling)
+	f.mu.Unlock()
+
+	translations := make([]models.TranslationResponse, len(req.Text))
+	for i, text := range req.Text {
+		translations[i].Text, _ = f.Translate(ctx, req.SourceLang, req.TargetLang, text)
+	}
+	return translations, nil
+}
+
+func (f *fakeTranslator) Detect(ctx context.Context, text string) (string, error) {
+	return "en", nil
+}
+
+func (f *fakeTranslator) SupportedLanguages() []config.Language {
+	return config.SupportedLanguagesAli
+}
+
+func (f *fakeTranslator) BatchLimit() (int, int) {
+	return 2, 100
+}
+
+func TestTranslateJson(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	tests := []struct {
+		name          string
+		json          string
+		ignoredFields string
+		want          string
+	}{
+		{
+			name: "nested object and array",
+			json: `{"title":"hello","menu":{"items":["save","cancel"]},"count":3}`,
+			want: `{"title":"HELLO","menu":{"items":["SAVE","CANCEL"]},"count":3}`,
+		},
+		{
+			name:          "ignored fields",
+			json:          `{"id":"abc","name":"john"}`,
+			ignoredFields: "id",
+			want:          `{"id":"abc","name":"JOHN"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TranslateJson(context.Background(), tt.json, models.Config{
+				SourceLang:    "en",
+				TargetLang:    "zh",
+				IgnoredFields: GetIgnoredFields(tt.ignoredFields),
+			})
+			if err != nil {
+				t.Fatalf("TranslateJson() error = %v", err)
+			}
+			if strings.TrimRight(got.JSON, "\n") != tt.want {
+				t.Errorf("TranslateJson() = %v, want %v", got.JSON, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateJSONBatchesUniqueTexts(t *testing.T) {
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	input := `{"a":"save","b":"cancel","c":["save","ok"],"d":{"e":"cancel","f":""}}`
+	got, err := TranslateJson(context.Background(), input, models.Config{SourceLang: "en", TargetLang: "zh"})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+
+	want := `{"a":"SAVE","b":"CANCEL","c":["SAVE","OK"],"d":{"e":"CANCEL","f":""}}`
+	if strings.TrimRight(got.JSON, "\n") != want {
+		t.Errorf("TranslateJson() = %v, want %v", got.JSON, want)
+	}
+
+	// 批次是并发发送的，按第一条文本排序后再比较
+	sort.Slice(fake.batches, func(i, j int) bool {
+		return fake.batches[i][0] > fake.batches[j][0]
+	})
+	wantBatches := [][]string{{"save", "cancel"}, {"ok"}}
+	if !reflect.DeepEqual(fake.batches, wantBatches) {
+		t.Errorf("batches = %v, want %v", fake.batches, wantBatches)
+	}
+}
+
+func TestTranslateJsonCanceled(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	// 任务超时或进程退出时返回错误，不能结束整个 worker
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := TranslateJson(ctx, `{"a":"save"}`, models.Config{SourceLang: "en", TargetLang: "zh"}); err == nil {
+		t.Error("TranslateJson() error = nil, want context canceled")
+	}
+}
+
+// mapMemoryStore 进程内的翻译记忆，测试时不依赖Redis
+type mapMemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+func (m *mapMemoryStore) Get(ctx context.Context, userID string, keys []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = m.entries[key]
+	}
+	return values, nil
+}
+
+func (m *mapMemoryStore) Set(ctx context.Context, userID string, entries map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, value := range entries {
+		m.entries[key] = value
+	}
+	return nil
+}
+
+func (m *mapMemoryStore) Purge(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prefix := MemoryKeyPrefix(userID)
+	for key := range m.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.entries, key)
+		}
+	}
+	return nil
+}
+
+func TestTranslationMemory(t *testing.T) {
+	SetMemoryStore(&mapMemoryStore{entries: make(map[string]string)})
+	defer SetMemoryStore(nil)
+
+	cfg := models.Config{SourceLang: "en", TargetLang: "zh", UserID: "user-1"}
+	input := `{"a":"save","b":"cancel"}`
+	want := `{"a":"SAVE","b":"CANCEL"}`
+
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+	if _, err := TranslateJson(context.Background(), input, cfg); err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+
+	// 第二次翻译时全部命中翻译记忆，首尾空白不同也视为同一条原文
+	fake = &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+	got, err := TranslateJson(context.Background(), `{"a":"save","b":" cancel "}`, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if strings.TrimRight(got.JSON, "\n") != `{"a":"SAVE","b":" CANCEL "}` {
+		t.Errorf("TranslateJson() = %v", got.JSON)
+	}
+	if len(fake.batches) != 0 {
+		t.Errorf("batches = %v, want none", fake.batches)
+	}
+	if hits, _ := MemoryStats(); hits < 2 {
+		t.Errorf("MemoryStats() hits = %d, want at least 2", hits)
+	}
+
+	// 中间的空白不同是不同的原文，换行不会被缓存的译文吃掉
+	if _, err := TranslateJson(context.Background(), `{"a":"line one line two"}`, cfg); err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	got, err = TranslateJson(context.Background(), `{"a":"line one\nline two"}`, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if strings.TrimRight(got.JSON, "\n") != `{"a":"LINE ONE\nLINE TWO"}` {
+		t.Errorf("TranslateJson() = %v", got.JSON)
+	}
+	fake = &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	// 关闭翻译记忆时仍然调用翻译接口
+	cfg.DisableMemory = true
+	if _, err := TranslateJson(context.Background(), input, cfg); err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if len(fake.batches) != 1 {
+		t.Errorf("batches = %v, want 1 batch", fake.batches)
+	}
+
+	// 清除后重新调用翻译接口
+	cfg.DisableMemory = false
+	if err := PurgeMemory(context.Background(), cfg.UserID); err != nil {
+		t.Fatalf("PurgeMemory() error = %v", err)
+	}
+	got, err = TranslateJson(context.Background(), input, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if strings.TrimRight(got.JSON, "\n") != want {
+		t.Errorf("TranslateJson() = %v, want %v", got.JSON, want)
+	}
+	if len(fake.batches) != 2 {
+		t.Errorf("batches = %v, want 2 batches", fake.batches)
+	}
+}
+
+func TestFieldRules(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	input := `{"id":"a1","title":"hello","user":{"id":"u1","name":"john","avatar_url":"photo"},"meta":{"author":"tom","tags":["x"]},"items":[{"sku":"s1","name":"pen"},{"sku":"s2","name":"ink"}],"links":{"home":{"url":"/home","label":"home"}}}`
+
+	tests := []struct {
+		name           string
+		ignoredFields  string
+		includedFields string
+		want           string
+		wantCount      int // 计费的字符数与实际翻译的字符串一致
+	}{
+		{
+			name:          "root key does not match nested key",
+			ignoredFields: "id",
+			want:          `{"id":"a1","title":"HELLO","user":{"id":"U1","name":"JOHN","avatar_url":"PHOTO"},"meta":{"author":"TOM","tags":["X"]},"items":[{"sku":"S1","name":"PEN"},{"sku":"S2","name":"INK"}],"links":{"home":{"url":"/HOME","label":"HOME"}}}`,
+			wantCount:     39,
+		},
+		{
+			name:          "wildcards",
+			ignoredFields: "meta.*, items[*].sku, **.url, **.id, user.*_url",
+			want:          `{"id":"a1","title":"HELLO","user":{"id":"u1","name":"JOHN","avatar_url":"photo"},"meta":{"author":"tom","tags":["x"]},"items":[{"sku":"s1","name":"PEN"},{"sku":"s2","name":"INK"}],"links":{"home":{"url":"/home","label":"HOME"}}}`,
+			wantCount:     19,
+		},
+		{
+			name:           "include only",
+			includedFields: "$.title,items[1],links",
+			ignoredFields:  "links.home.url",
+			want:           `{"id":"a1","title":"HELLO","user":{"id":"u1","name":"john","avatar_url":"photo"},"meta":{"author":"tom","tags":["x"]},"items":[{"sku":"s1","name":"pen"},{"sku":"S2","name":"INK"}],"links":{"home":{"url":"/home","label":"HOME"}}}`,
+			wantCount:      14,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := models.Config{
+				SourceLang:     "en",
+				TargetLang:     "zh",
+				IgnoredFields:  GetIgnoredFields(tt.ignoredFields),
+				IncludedFields: GetIncludedFields(tt.includedFields),
+			}
+			got, err := TranslateJson(context.Background(), input, cfg)
+			if err != nil {
+				t.Fatalf("TranslateJson() error = %v", err)
+			}
+			if strings.TrimRight(got.JSON, "\n") != tt.want {
+				t.Errorf("TranslateJson() = %v, want %v", got.JSON, tt.want)
+			}
+
+			count, err := CountJsonChars(input, cfg)
+			if err != nil {
+				t.Fatalf("CountJsonChars() error = %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("CountJsonChars() = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestValidateFieldRules(t *testing.T) {
+	valid := []string{"id", "$.meta.*", "items[*].sku", "**.url", "items[0]", "['a.b'].c", "*_url"}
+	if err := ValidateFieldRules(valid); err != nil {
+		t.Errorf("ValidateFieldRules(%v) error = %v", valid, err)
+	}
+
+	for _, rule := range []string{"a..b", "items[", "items[x]", "a.", "$"} {
+		if err := ValidateFieldRules([]string{rule}); err == nil {
+			t.Errorf("ValidateFieldRules(%q) expected error", rule)
+		}
+	}
+
+	// 迁移后的旧规则 **['key'] 与原来一样匹配任意层级的 key
+	translateapi.SetTranslator(&fakeTranslator{})
+	got, err := TranslateJson(context.Background(), `{"id":"a","user":{"id":"b","items[":"c","name":"d"}}`, models.Config{
+		SourceLang:    "en",
+		TargetLang:    "zh",
+		IgnoredFields: []string{"**['id']", "**['items[']"},
+	})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if want := `{"id":"a","user":{"id":"b","items[":"c","name":"D"}}`; strings.TrimRight(got.JSON, "\n") != want {
+		t.Errorf("TranslateJson() = %v, want %v", got.JSON, want)
+	}
+
+	// 未迁移的非法规则返回错误，由 worker 标记任务失败
+	if _, err := TranslateJson(context.Background(), `{"a":"b"}`, models.Config{IgnoredFields: []string{"items["}}); err == nil {
+		t.Error("TranslateJson() error = nil, want invalid field rule")
+	}
+}
+
+// droppingTranslator 翻译时丢掉第一个占位符标记，模拟翻译接口改写占位符
+type droppingTranslator struct {
+	fakeTranslator
+}
+
+func (d *droppingTranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	translations, err := d.fakeTranslator.BatchTranslate(ctx, req)
+	for i := range translations {
+		translations[i].Text = strings.Replace(translations[i].Text, "⟦0⟧", "", 1)
+	}
+	return translations, err
+}
+
+// failingTranslator 每批只翻译一个文本，包含 fail 的文本翻译请求失败
+type failingTranslator struct {
+	fakeTranslator
+}
+
+func (f *failingTranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	if strings.Contains(req.Text[0], "fail") {
+		return nil, errors.New("provider unavailable")
+	}
+	return f.fakeTranslator.BatchTranslate(ctx, req)
+}
+
+func (f *failingTranslator) BatchLimit() (int, int) {
+	return 1, 100
+}
+
+func TestTranslateJSONPartialFailure(t *testing.T) {
+	translateapi.SetTranslator(&failingTranslator{})
+
+	input := `{"a":"hello","b":"please fail","c":"https://example.com","d":"hello"}`
+	got, err := TranslateJson(context.Background(), input, models.Config{SourceLang: "en", TargetLang: "de"})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if want := `{"a":"HELLO","b":"please fail","c":"https://example.com","d":"HELLO"}`; got.JSON != want {
+		t.Errorf("JSON = %s, want %s", got.JSON, want)
+	}
+	if want := []string{"a", "d"}; !reflect.DeepEqual(got.Translated, want) {
+		t.Errorf("Translated = %v, want %v", got.Translated, want)
+	}
+	if want := []models.FailedValue{{Path: "b", Error: "translation request failed: provider unavailable"}}; !reflect.DeepEqual(got.Failed, want) {
+		t.Errorf("Failed = %v, want %v", got.Failed, want)
+	}
+	if want := []models.SkippedValue{{Path: "c", Reason: "url"}}; !reflect.DeepEqual(got.Skipped, want) {
+		t.Errorf("Skipped = %v, want %v", got.Skipped, want)
+	}
+	if got.Status() != models.TranslationStatusPartial {
+		t.Errorf("Status() = %s, want %s", got.Status(), models.TranslationStatusPartial)
+	}
+
+	// 严格模式下部分失败时整个翻译失败
+	_, err = TranslateJson(context.Background(), input, models.Config{SourceLang: "en", TargetLang: "de", Strict: true})
+	var partial *PartialError
+	if !errors.As(err, &partial) || len(partial.Failed) != 1 || partial.Failed[0].Path != "b" {
+		t.Errorf("TranslateJson() error = %v, want PartialError for b", err)
+	}
+	if _, err := TranslateStream(context.Background(), strings.NewReader(input), io.Discard, models.Config{SourceLang: "en", TargetLang: "de", Strict: true}); !errors.As(err, &partial) {
+		t.Errorf("TranslateStream() error = %v, want PartialError", err)
+	}
+
+	got, err = TranslateJson(context.Background(), `{"a":"hello"}`, models.Config{SourceLang: "en", TargetLang: "de", Strict: true})
+	if err != nil || got.Status() != models.TranslationStatusComplete {
+		t.Errorf("TranslateJson() = %v, %v, want complete", got, err)
+	}
+}
+
+func TestMaskPlaceholders(t *testing.T) {
+	tests := []struct {
+		name         string
+		placeholders []string
+		text         string
+		wantMasked   string
+	}{
+		{
+			name:       "default delimiters",
+			text:       "Hello {name}, you have #{count} <b>new</b> [items]",
+			wantMasked: "Hello ⟦0⟧, you have ⟦1⟧ ⟦2⟧new⟦3⟧ ⟦4⟧",
+		},
+		{
+			name:         "printf",
+			placeholders: []string{PlaceholderPrintf},
+			text:         "%1$s paid %.2f (100%%) for %d items",
+			wantMasked:   "⟦0⟧ paid ⟦1⟧ (100⟦2⟧) for ⟦3⟧ items",
+		},
+		{
+			name:         "mustache dollar colon",
+			placeholders: []string{PlaceholderMustache, PlaceholderDollar, PlaceholderColon},
+			text:         "Hi {{user}}, ${total} due at :time. Note: ok",
+			wantMasked:   "Hi ⟦0⟧, ⟦1⟧ due at ⟦2⟧. Note: ok",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compilePlaceholders(tt.placeholders)
+			if err != nil {
+				t.Fatalf("compilePlaceholders() error = %v", err)
+			}
+			m := maskPlaceholders(tt.text, re)
+			if m.text != tt.wantMasked {
+				t.Errorf("maskPlaceholders() = %v, want %v", m.text, tt.wantMasked)
+			}
+
+			// 翻译接口在标记中加了空格，仍然能够还原
+			spaced := placeholderToken.ReplaceAllStringFunc(m.text, func(token string) string {
+				return strings.Replace(token, "⟦", "⟦ ", 1)
+			})
+			restored, err := m.unmask(tt.text, spaced)
+			if err != nil {
+				t.Fatalf("unmask() error = %v", err)
+			}
+			if restored != tt.text {
+				t.Errorf("unmask() = %v, want %v", restored, tt.text)
+			}
+
+			if _, err := m.unmask(tt.text, strings.Replace(m.text, "⟦0⟧", "", 1)); err == nil {
+				t.Errorf("unmask() expected error for missing placeholder")
+			}
+		})
+	}
+
+	if _, err := compilePlaceholders([]string{"unknown"}); err == nil {
+		t.Errorf("compilePlaceholders() expected error for unknown type")
+	}
+}
+
+func TestTranslateJSONPlaceholders(t *testing.T) {
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	cfg := models.Config{SourceLang: "en", TargetLang: "zh", Placeholders: []string{PlaceholderPrintf, PlaceholderBrace}}
+	got, err := TranslateJson(context.Background(), `{"a":"hi {name}","b":"hi {user}","c":"{name}","d":"%s: %d"}`, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+
+	want := `{"a":"HI {name}","b":"HI {user}","c":"{name}","d":"%s: %d"}`
+	if strings.TrimRight(got.JSON, "\n") != want {
+		t.Errorf("TranslateJson() = %v, want %v", got.JSON, want)
+	}
+
+	// 替换后相同的文本只翻译一次，只有占位符的文本不调用翻译接口
+	wantBatches := [][]string{{"hi ⟦0⟧"}}
+	if !reflect.DeepEqual(fake.batches, wantBatches) {
+		t.Errorf("batches = %v, want %v", fake.batches, wantBatches)
+	}
+
+	// 占位符丢失的字符串保留原文
+	translateapi.SetTranslator(&droppingTranslator{})
+	got, err = TranslateJson(context.Background(), `{"a":"hi {name}","b":"bye"}`, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	want = `{"a":"hi {name}","b":"BYE"}`
+	if strings.TrimRight(got.JSON, "\n") != want {
+		t.Errorf("TranslateJson() = %v, want %v", got.JSON, want)
+	}
+	if len(got.Failed) != 1 || got.Failed[0].Path != "a" || !strings.Contains(got.Failed[0].Error, "{name}") {
+		t.Errorf("Failed = %v, want a with missing {name}", got.Failed)
+	}
+}
+
+func TestTranslateJSONICU(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	tests := []struct {
+		name       string
+		targetLang string
+		text       string
+		want       string
+	}{
+		{
+			name:       "plural categories added for russian",
+			targetLang: "ru",
+			text:       "{count, plural, one {# item} other {# items}}",
+			want:       "{count, plural, one {# ITEM} few {# ITEMS} many {# ITEMS} other {# ITEMS}}",
+		},
+		{
+			name:       "plural categories dropped for chinese",
+			targetLang: "zh",
+			text:       "You have {count, plural, offset:1 =0 {no messages} one {# message} other {# messages}} in {folder}",
+			want:       "YOU HAVE {count, plural, offset:1 =0 {NO MESSAGES} other {# MESSAGES}} IN {folder}",
+		},
+		{
+			name:       "nested select and selectordinal",
+			targetLang: "en",
+			text:       "{gender, select, male {He is {n, selectordinal, one {#st} other {#th}}} other {They are {n, number}}}",
+			want:       "{gender, select, male {HE IS {n, selectordinal, one {#ST} two {#TH} few {#TH} other {#TH}}} other {THEY ARE {n, number}}}",
+		},
+		{
+			name:       "quoted text", // 引号中的大括号仍然按占位符保护
+			targetLang: "en",
+			text:       "{n, plural, one {it''s '{'one'}'} other {'#' many}}",
+			want:       "{n, plural, one {IT'S '{'one'}'} other {'#' MANY}}",
+		},
+		{
+			name:       "not a message",
+			targetLang: "en",
+			text:       "hello {name}",
+			want:       "HELLO {name}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := json.Marshal(map[string]string{"a": tt.text})
+			got, err := TranslateJson(context.Background(), string(input), models.Config{SourceLang: "en", TargetLang: tt.targetLang})
+			if err != nil {
+				t.Fatalf("TranslateJson() error = %v", err)
+			}
+
+			var result map[string]string
+			if err := json.Unmarshal([]byte(got.JSON), &result); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if result["a"] != tt.want {
+				t.Errorf("TranslateJson() = %v, want %v", result["a"], tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain text", FormatPlain},
+		{"Hello <username>", FormatPlain},
+		{"Click <a href=\"/x\">here</a>", FormatHTML},
+		{"Tom &amp; Jerry", FormatHTML},
+		{"See [docs](https://example.com)", FormatMarkdown},
+		{"Run `make` first", FormatMarkdown},
+		{"## Title", FormatMarkdown},
+	}
+
+	for _, tt := range tests {
+		if got := detectFormat(tt.text); got != tt.want {
+			t.Errorf("detectFormat(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateJSONFormats(t *testing.T) {
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	input := `{"html":"<b>bold</b> text","md":"## Intro\nsee [the docs](https://example.com/a) or run ` + "`npm install`" + `","raw":"<b>keep</b>"}`
+	cfg := models.Config{SourceLang: "en", TargetLang: "zh", Formats: []string{"raw=plain"}}
+	got, err := TranslateJson(context.Background(), input, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+
+	var result map[string]string
+	if err := json.Unmarshal([]byte(got.JSON), &result); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	want := map[string]string{
+		"html": "<B>BOLD</B> TEXT",
+		"md":   "## INTRO\nSEE [THE DOCS](https://example.com/a) OR RUN `npm install`",
+		"raw":  "<b>KEEP</b>",
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("TranslateJson() = %v, want %v", result, want)
+	}
+
+	// HTML 单独成批，使用翻译接口的 html 模式
+	for i, batch := range fake.batches {
+		isHTML := len(batch) == 1 && batch[0] == "<b>bold</b> text"
+		if isHTML != (fake.tagHandlings[i] == "html") {
+			t.Errorf("batch %v tag handling = %q", batch, fake.tagHandlings[i])
+		}
+	}
+
+	// 相同的原文按路径指定了不同的格式时分开翻译
+	fake = &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+	got, err = TranslateJson(context.Background(), `{"a":"<b>x</b> y","b":"<b>x</b> y"}`, models.Config{SourceLang: "en", TargetLang: "zh", Formats: []string{"b=plain"}})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	result = nil
+	if err := json.Unmarshal([]byte(got.JSON), &result); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if want := map[string]string{"a": "<B>X</B> Y", "b": "<b>X</b> Y"}; !reflect.DeepEqual(result, want) {
+		t.Errorf("TranslateJson() = %v, want %v", result, want)
+	}
+	sort.Strings(fake.tagHandlings)
+	if want := []string{"", "html"}; !reflect.DeepEqual(fake.tagHandlings, want) {
+		t.Errorf("tag handlings = %q, want %q", fake.tagHandlings, want)
+	}
+
+	if err := ValidateFormats([]string{"body=rtf"}); err == nil {
+		t.Errorf("ValidateFormats() expected error for unsupported format")
+	}
+}
+
+func TestTranslateJSONDelta(t *testing.T) {
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	cfg := models.Config{
+		SourceLang:     "en",
+		TargetLang:     "zh",
+		DisableMemory:  true,
+		PreviousSource: `{"a":"hello","b":"world","list":["one","two"],"gone":"bye","d":"retry"}`,
+		PreviousTarget: `{"a":"HOLA","b":"MUNDO","list":["UNO"],"d":"retry"}`,
+	}
+	input := `{"a":"hello","b":"world!","list":["one","two"],"c":"new","d":"retry"}`
+
+	got, err := TranslateJson(context.Background(), input, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	want := `{"a":"HOLA","b":"WORLD!","list":["UNO","TWO"],"c":"NEW","d":"RETRY"}`
+	if strings.TrimSpace(got.JSON) != want {
+		t.Errorf("TranslateJson() = %s, want %s", got.JSON, want)
+	}
+
+	// 只有修改的 b、新增的 c、上一次译文中缺少的 list[1] 和上一次翻译失败保留原文的 d 交给翻译接口
+	var sent []string
+	for _, batch := range fake.batches {
+		sent = append(sent, batch...)
+	}
+	sort.Strings(sent)
+	if wantSent := []string{"new", "retry", "two", "world!"}; !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("translated texts = %q, want %q", sent, wantSent)
+	}
+
+	stats, err := AnalyzeJson(input, cfg)
+	if err != nil {
+		t.Fatalf("AnalyzeJson() error = %v", err)
+	}
+	if stats.Chars != 17 || stats.Reused != 2 {
+		t.Errorf("AnalyzeJson() chars = %d, reused = %d, want 17, 2", stats.Chars, stats.Reused)
+	}
+
+	if err := ValidatePrevious(`{"a":"hello"}`, ""); err == nil {
+		t.Errorf("ValidatePrevious() expected error when previous target is missing")
+	}
+	if err := ValidatePrevious(`{"a":`, `{}`); err == nil {
+		t.Errorf("ValidatePrevious() expected error for invalid previous source")
+	}
+}
+
+func TestTranslateJSONMerge(t *testing.T) {
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	input := `{"title":"hello","menu":{"open":"open","close":"close"},"list":["one","two"],"count":3}`
+	existing := `{"menu":{"close":"Schließen","old":"Alt"},"title":"Hallo","list":["eins"],"extra":"x"}`
+	cfg := models.Config{SourceLang: "en", TargetLang: "de", DisableMemory: true, ExistingTarget: existing}
+
+	got, err := TranslateJson(context.Background(), input, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	// 按原文的顺序输出，原文中不存在的 key 追加在所在对象的末尾
+	want := `{"title":"Hallo","menu":{"open":"OPEN","close":"Schließen","old":"Alt"},"list":["eins","TWO"],"count":3,"extra":"x"}`
+	if strings.TrimSpace(got.JSON) != want {
+		t.Errorf("TranslateJson() = %s, want %s", got.JSON, want)
+	}
+	wantReport := &models.MergeReport{
+		Added:   []string{"menu.open", "list[1]", "count"},
+		Kept:    []string{"title", "menu.close", "menu.old", "list[0]", "extra"},
+		Removed: []string{},
+	}
+	if !reflect.DeepEqual(got.Merge, wantReport) {
+		t.Errorf("merge report = %+v, want %+v", got.Merge, wantReport)
+	}
+
+	var sent []string
+	for _, batch := range fake.batches {
+		sent = append(sent, batch...)
+	}
+	sort.Strings(sent)
+	if wantSent := []string{"open", "two"}; !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("translated texts = %q, want %q", sent, wantSent)
+	}
+
+	// 删除原文中已经不存在的 key
+	cfg.PruneRemoved = true
+	got, err = TranslateJson(context.Background(), input, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	want = `{"title":"Hallo","menu":{"open":"OPEN","close":"Schließen"},"list":["eins","TWO"],"count":3}`
+	if strings.TrimSpace(got.JSON) != want {
+		t.Errorf("TranslateJson() = %s, want %s", got.JSON, want)
+	}
+	if wantRemoved := []string{"menu.old", "extra"}; !reflect.DeepEqual(got.Merge.Removed, wantRemoved) {
+		t.Errorf("removed = %q, want %q", got.Merge.Removed, wantRemoved)
+	}
+
+	stats, err := AnalyzeJson(input, cfg)
+	if err != nil {
+		t.Fatalf("AnalyzeJson() error = %v", err)
+	}
+	if stats.Chars != 7 || !reflect.DeepEqual(stats.Merge, got.Merge) {
+		t.Errorf("AnalyzeJson() chars = %d, merge = %+v, want 7, %+v", stats.Chars, stats.Merge, got.Merge)
+	}
+
+	if err := ValidateExistingTarget(models.Config{ExistingTarget: `{"a":`}); err == nil {
+		t.Errorf("ValidateExistingTarget() expected error for invalid JSON")
+	}
+	// 已有的译文按请求的 key 的形状展开，扁平的 key 冲突时报错
+	flat := models.Config{ExistingTarget: `{"a":"x","a.b":"y"}`, KeyStyle: KeyStyleNested}
+	var collision *KeyCollisionError
+	if err := ValidateExistingTarget(flat); !errors.As(err, &collision) {
+		t.Errorf("ValidateExistingTarget() error = %v, want KeyCollisionError", err)
+	}
+	flat.KeyStyle = ""
+	if err := ValidateExistingTarget(flat); err != nil {
+		t.Errorf("ValidateExistingTarget() error = %v, want nil without a key style", err)
+	}
+}
+
+// contextTranslator 按请求接收上下文的假翻译，译文后面带上上下文的第一行
+type contextTranslator struct {
+	fakeTranslator
+	contexts []string
+}
+
+func (c *contextTranslator) ContextMode() string {
+	return translateapi.ContextPerRequest
+}
+
+func (c *contextTranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	translations, err := c.fakeTranslator.BatchTranslate(ctx, req)
+	c.mu.Lock()
+	c.contexts = append(c.contexts, req.Context)
+	c.mu.Unlock()
+	for i := range translations {
+		translations[i].Text += " (" + strings.SplitN(req.Context, "\n", 2)[0] + ")"
+	}
+	return translations, err
+}
+
+func TestTranslateJSONContext(t *testing.T) {
+	SetMemoryStore(&mapMemoryStore{entries: make(map[string]string)})
+	defer SetMemoryStore(nil)
+
+	fake := &contextTranslator{}
+	translateapi.SetTranslator(fake)
+
+	input := `{"nav":{"home":"Home","back":"Back"},"page":{"home":"Home"}}`
+	cfg := models.Config{SourceLang: "en", TargetLang: "de", UserID: "user-1", Description: "Mobile app menu",
+		Comments: map[string]string{"page.home": "Landing page heading"}}
+	got, err := TranslateJson(context.Background(), input, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	want := `{"nav":{"home":"HOME (Description: Mobile app menu)","back":"BACK (Description: Mobile app menu)"},"page":{"home":"HOME (Comment: Landing page heading)"}}`
+	if strings.TrimSpace(got.JSON) != want {
+		t.Errorf("TranslateJson() = %s, want %s", got.JSON, want)
+	}
+
+	// 按请求发送的上下文只有原文件中的注释和描述，注释和描述相同的放在同一批
+	sort.Strings(fake.contexts)
+	wantContexts := []string{
+		"Comment: Landing page heading\nDescription: Mobile app menu",
+		"Description: Mobile app menu",
+	}
+	if !reflect.DeepEqual(fake.contexts, wantContexts) {
+		t.Errorf("contexts = %q, want %q", fake.contexts, wantContexts)
+	}
+
+	// 不支持上下文的服务商优先使用按上下文区分的翻译记忆，不同 key 下的相同原文也能命中
+	plain := &fakeTranslator{}
+	translateapi.SetTranslator(plain)
+	cfg.Comments = map[string]string{"page.home": "Landing page heading", "footer": "Footer link"}
+	got, err = TranslateJson(context.Background(), `{"nav":{"home":"Home","back":"Back"},"page":{"home":"Home"},"footer":"Home","menu":{"home":"Home"}}`, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	want = `{"nav":{"home":"HOME (Description: Mobile app menu)","back":"BACK (Description: Mobile app menu)"},"page":{"home":"HOME (Comment: Landing page heading)"},"footer":"HOME","menu":{"home":"HOME (Description: Mobile app menu)"}}`
+	if strings.TrimSpace(got.JSON) != want {
+		t.Errorf("TranslateJson() = %s, want %s", got.JSON, want)
+	}
+	if wantBatches := [][]string{{"Home"}}; !reflect.DeepEqual(plain.batches, wantBatches) {
+		t.Errorf("batches = %v, want %v", plain.batches, wantBatches)
+	}
+}
+
+// textContextTranslator 逐条接收上下文的假翻译，记录每次请求的上下文
+type textContextTranslator struct {
+	fakeTranslator
+	contexts [][]string
+}
+
+func (c *textContextTranslator) ContextMode() string {
+	return translateapi.ContextPerText
+}
+
+func (c *textContextTranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	c.mu.Lock()
+	c.contexts = append(c.contexts, req.Contexts)
+	c.mu.Unlock()
+	return c.fakeTranslator.BatchTranslate(ctx, req)
+}
+
+func TestTranslateJSONContextDedupe(t *testing.T) {
+	var data strings.Builder
+	data.WriteString("{")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&data, `"button%d":"Save",`, i)
+	}
+	data.WriteString(`"title":"Settings"}`)
+	cfg := models.Config{SourceLang: "en", TargetLang: "de", Description: "Settings page"}
+
+	// 不同 key 下的相同原文只翻译一次，按请求接收上下文时只发送一次请求
+	perRequest := &contextTranslator{}
+	translateapi.SetTranslator(perRequest)
+	got, err := TranslateJson(context.Background(), data.String(), cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if wantBatches := [][]string{{"Save", "Settings"}}; !reflect.DeepEqual(perRequest.batches, wantBatches) {
+		t.Errorf("per-request batches = %v, want %v", perRequest.batches, wantBatches)
+	}
+	if !strings.Contains(got.JSON, `"button99":"SAVE (Description: Settings page)"`) {
+		t.Errorf("TranslateJson() = %s, want every button translated", got.JSON)
+	}
+
+	// 逐条接收上下文时同一批中每条带上第一次出现的 key 路径
+	perText := &textContextTranslator{}
+	translateapi.SetTranslator(perText)
+	if _, err := TranslateJson(context.Background(), data.String(), cfg); err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if wantBatches := [][]string{{"Save", "Settings"}}; !reflect.DeepEqual(perText.batches, wantBatches) {
+		t.Errorf("per-text batches = %v, want %v", perText.batches, wantBatches)
+	}
+	wantContexts := [][]string{{
+		"Key: button0\nSibling keys: button1, button2, button3, button4, button5, button6, button7, button8, button9, button10\nDescription: Settings page",
+		"Key: title\nSibling keys: button0, button1, button2, button3, button4, button5, button6, button7, button8, button9\nDescription: Settings page",
+	}}
+	if !reflect.DeepEqual(perText.contexts, wantContexts) {
+		t.Errorf("per-text contexts = %q, want %q", perText.contexts, wantContexts)
+	}
+}
+
+func TestTranslateJSONGlossary(t *testing.T) {
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	cfg := models.Config{SourceLang: "en", TargetLang: "de", Glossary: []models.GlossaryTerm{
+		{Source: "Acme", DoNotTranslate: true},
+		{Source: "Pro", Target: "Profi"},
+		{Source: "Pro Plan", Target: "Pro-Tarif"},
+		{Source: "cloud", Target: "Wolke", IgnoreCase: true},
+	}}
+	input := `{"a":"Acme Pro Plan","b":"Upgrade to Pro Plan","c":"Cloud storage","d":"Product"}`
+	got, err := TranslateJson(context.Background(), input, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+
+	// 长的术语优先匹配，只按整个单词匹配，只有术语的字符串不调用翻译接口
+	want := `{"a":"Acme Pro-Tarif","b":"UPGRADE TO Pro-Tarif","c":"Wolke STORAGE","d":"PRODUCT"}`
+	if strings.TrimRight(got.JSON, "\n") != want {
+		t.Errorf("TranslateJson() = %v, want %v", got.JSON, want)
+	}
+	if len(got.Terms) != 0 {
+		t.Errorf("Terms = %v, want none", got.Terms)
+	}
+
+	// 术语不计费
+	chars, err := CountJsonChars(input, cfg)
+	if err != nil {
+		t.Fatalf("CountJsonChars() error = %v", err)
+	}
+	if wantChars := len("Upgrade to ") + len(" storage") + len("Product"); chars != wantChars {
+		t.Errorf("CountJsonChars() = %d, want %d", chars, wantChars)
+	}
+
+	// 术语标记丢失时保留原文，并记录没有使用术语表规定译法的字符串
+	translateapi.SetTranslator(&droppingTranslator{})
+	got, err = TranslateJson(context.Background(), `{"b":"Upgrade to Pro Plan","e":"Acme cloud"}`, cfg)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	wantTerms := []models.TermIssue{{Path: "b", Term: "Pro Plan", Expected: "Pro-Tarif"}}
+	if !reflect.DeepEqual(got.Terms, wantTerms) {
+		t.Errorf("Terms = %v, want %v", got.Terms, wantTerms)
+	}
+}
+
+func TestValidateTranslation(t *testing.T) {
+	masker, err := newSegmentMasker(nil, nil)
+	if err != nil {
+		t.Fatalf("newSegmentMasker() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		targetLang string
+		source     string
+		translated string
+		wantChecks []string
+	}{
+		{name: "ok", source: "Save changes", translated: "Änderungen speichern"},
+		{name: "placeholders", source: "Hello {name}", translated: "Hallo", wantChecks: []string{CheckPlaceholders}},
+		{name: "tags", source: "<b>Save</b> now", translated: "<b>Jetzt speichern", wantChecks: []string{CheckTags}},
+		{name: "untranslated", source: "Settings", translated: "Settings", wantChecks: []string{CheckUntranslated}},
+		{name: "length ratio", source: "Please confirm your email address", translated: "Bitte", wantChecks: []string{CheckLengthRatio}},
+		{name: "dense target", targetLang: "zh", source: "Please confirm your email address", translated: "请确认您的电子邮件地址"},
+		{name: "whitespace", source: " Save", translated: "Speichern", wantChecks: []string{CheckWhitespace}},
+		{name: "punctuation", source: "Are you sure?", translated: "Sind Sie sicher", wantChecks: []string{CheckPunctuation}},
+		{name: "fullwidth punctuation", targetLang: "zh", source: "Are you sure?", translated: "你确定吗？"},
+		{name: "newlines", source: "Line one\nLine two", translated: "Zeile eins Zeile zwei", wantChecks: []string{CheckNewlines}},
+		{name: "only placeholders", source: "{name}", translated: "{name}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetLang := tt.targetLang
+			if targetLang == "" {
+				targetLang = "de"
+			}
+			v := newValidator(masker, models.Config{SourceLang: "en", TargetLang: targetLang})
+
+			var checks []string
+			for _, issue := range v.check(&segment{path: "a", text: tt.source}, tt.translated) {
+				checks = append(checks, issue.Check)
+			}
+			if !reflect.DeepEqual(checks, tt.wantChecks) {
+				t.Errorf("check() = %v, want %v", checks, tt.wantChecks)
+			}
+		})
+	}
+}
+
+func TestTranslateJSONScriptCheck(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	// fakeTranslator 的译文是拉丁字母，目标语言是俄语时译文的文字不对，短文本、占位符和标签不检查
+	input := `{"a":"Welcome to our application","b":"Hi","c":"Welcome to our application","d":"{username} <b>Добро пожаловать</b>"}`
+	got, err := TranslateJson(context.Background(), input, models.Config{SourceLang: "en", TargetLang: "ru"})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	want := []models.QualityIssue{
+		{Path: "a", Check: CheckScript, Detail: "translation is written in Latin script, expected Cyrillic for ru"},
+		{Path: "c", Check: CheckScript, Detail: "translation is written in Latin script, expected Cyrillic for ru"},
+	}
+	if !reflect.DeepEqual(got.Issues, want) {
+		t.Errorf("Issues = %v, want %v", got.Issues, want)
+	}
+
+	got, err = TranslateJson(context.Background(), `{"a":"Welcome to our application"}`, models.Config{SourceLang: "en", TargetLang: "de"})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if len(got.Issues) != 0 {
+		t.Errorf("Issues = %v, want none", got.Issues)
+	}
+}
+
+func TestTextScript(t *testing.T) {
+	tests := map[string]string{
+		"Welcome to our application":  "Latin",
+		"Добро пожаловать в Acme App": "Cyrillic",
+		"アプリケーションへようこそ、田中さん":          "Japanese",
+		"欢迎使用我们的应用程序，祝您使用愉快":          "Han",
+		"우리 애플리케이션에 오신 것을 환영합니다":      "Hangul",
+		"Hi": "",
+	}
+	for text, want := range tests {
+		if got := textScript(text); got != want {
+			t.Errorf("textScript(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestTranslateJSONRoots(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	tests := []struct {
+		name       string
+		input      string
+		want       string
+		skipStream bool // 流式翻译不合并重复的 key
+	}{
+		{name: "字符串数组", input: `["hello","world"]`, want: `["HELLO","WORLD"]`},
+		{name: "对象数组", input: `[{"title":"hello","id":1},{"title":"bye"}]`, want: `[{"title":"HELLO","id":1},{"title":"BYE"}]`},
+		{name: "字符串", input: `"hello"`, want: `"HELLO"`},
+		{name: "数字", input: `12345678901234567890`, want: `12345678901234567890`},
+		{name: "null", input: `null`, want: `null`},
+		{name: "空数组", input: `[]`, want: `[]`},
+		{name: "数字原样保留", input: `{"id":12345678901234567890,"price":1.10,"exp":1e5,"neg":-0.0,"list":[1.50,2e-7],"title":"hi"}`, want: `{"id":12345678901234567890,"price":1.10,"exp":1e5,"neg":-0.0,"list":[1.50,2e-7],"title":"HI"}`},
+		{name: "重复的key", input: `{"a":"x","b":"y","a":"z"}`, want: `{"b":"Y","a":"Z"}`, skipStream: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TranslateJson(context.Background(), tt.input, models.Config{SourceLang: "en", TargetLang: "de"})
+			if err != nil {
+				t.Fatalf("TranslateJson() error = %v", err)
+			}
+			if strings.TrimSuffix(got.JSON, "\n") != tt.want {
+				t.Errorf("TranslateJson() = %s, want %s", got.JSON, tt.want)
+			}
+
+			var out bytes.Buffer
+			if _, err := TranslateStream(context.Background(), strings.NewReader(tt.input), &out, models.Config{SourceLang: "en", TargetLang: "de"}); err != nil {
+				t.Fatalf("TranslateStream() error = %v", err)
+			}
+			if !tt.skipStream && strings.TrimSuffix(out.String(), "\n") != tt.want {
+				t.Errorf("TranslateStream() = %s, want %s", out.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := TranslateJson(context.Background(), `{"a":"b"} {}`, models.Config{SourceLang: "en", TargetLang: "de"}); err == nil {
+		t.Error("TranslateJson() error = nil, want trailing data error")
+	}
+
+	// 增量翻译和合并模式同样支持数组根节点
+	got, err := TranslateJson(context.Background(), `["hello","new",3.0]`, models.Config{
+		SourceLang:     "en",
+		TargetLang:     "de",
+		ExistingTarget: `["Hallo"]`,
+	})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if want := "[\"Hallo\",\"NEW\",3.0]"; got.JSON != want {
+		t.Errorf("TranslateJson() = %s, want %s", got.JSON, want)
+	}
+}
+
+func TestTranslateJSONKeyStyle(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	nested := `{"home":{"title":"Home","id":"x1"},"list":[{"a.b":"item"}],"empty":{}}`
+	flat := `{"home.title":"Home","home.id":"x1","list":[{"a.b":"item"}],"empty":{}}`
+	tests := []struct {
+		name      string
+		input     string
+		style     string
+		separator string
+		want      string
+		wantPaths []string
+	}{
+		{name: "嵌套转扁平", input: nested, style: KeyStyleFlat, want: `{"home.title":"HOME","home.id":"x1","list":[{"a.b":"ITEM"}],"empty":{}}`, wantPaths: []string{"home.title", "list[0].a.b"}},
+		{name: "扁平转嵌套", input: flat, style: KeyStyleNested, want: `{"home":{"title":"HOME","id":"x1"},"list":[{"a.b":"ITEM"}],"empty":{}}`, wantPaths: []string{"home.title", "list[0].a.b"}},
+		{name: "混合的形状", input: `{"home":{"title":"Home"},"home.id":"x1"}`, style: KeyStyleNested, want: `{"home":{"title":"HOME","id":"x1"}}`, wantPaths: []string{"home.title"}},
+		{name: "自定义分隔符", input: `{"home/title":"Home","home/id":"x1"}`, style: KeyStyleFlat, separator: "/", want: `{"home/title":"HOME","home/id":"x1"}`, wantPaths: []string{"home.title"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 路径规则按嵌套的路径匹配，两种形状的原文结果一致
+			cfg := models.Config{SourceLang: "en", TargetLang: "de", IgnoredFields: []string{"home.id"}, KeyStyle: tt.style, KeySeparator: tt.separator}
+			got, err := TranslateJson(context.Background(), tt.input, cfg)
+			if err != nil {
+				t.Fatalf("TranslateJson() error = %v", err)
+			}
+			if strings.TrimSuffix(got.JSON, "\n") != tt.want {
+				t.Errorf("TranslateJson() = %s, want %s", got.JSON, tt.want)
+			}
+			if !reflect.DeepEqual(got.Translated, tt.wantPaths) {
+				t.Errorf("Translated = %v, want %v", got.Translated, tt.wantPaths)
+			}
+		})
+	}
+
+	// 增量翻译和合并模式的 JSON 同样按嵌套的路径对比
+	got, err := TranslateJson(context.Background(), nested, models.Config{
+		SourceLang:     "en",
+		TargetLang:     "de",
+		KeyStyle:       KeyStyleFlat,
+		ExistingTarget: `{"home.title":"Startseite"}`,
+	})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if want := `{"home.title":"Startseite","home.id":"X1","list":[{"a.b":"ITEM"}],"empty":{}}`; strings.TrimSuffix(got.JSON, "\n") != want {
+		t.Errorf("TranslateJson() = %s, want %s", got.JSON, want)
+	}
+
+	for _, input := range []string{`{"a":"x","a.b":"y"}`, `{"a":{"b":"x"},"a.b":"y"}`, `{"a.b":"y","a":"x"}`} {
+		_, err := AnalyzeJson(input, models.Config{KeyStyle: KeyStyleNested})
+		var collision *KeyCollisionError
+		if !errors.As(err, &collision) {
+			t.Errorf("AnalyzeJson(%s) error = %v, want KeyCollisionError", input, err)
+		}
+	}
+
+	if err := ValidateKeyStyle("camel", ""); err == nil {
+		t.Error("ValidateKeyStyle(camel) error = nil, want error")
+	}
+	if err := ValidateKeyStyle("", "/"); err == nil {
+		t.Error("ValidateKeyStyle with separator only error = nil, want error")
+	}
+}
+
+func TestTranslateJSONOutputStyle(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	tests := []struct {
+		name   string
+		input  string
+		indent string
+		sort   bool
+		want   string
+	}{
+		{name: "两个空格和末尾换行", input: "{\n  \"b\": \"hi\",\n  \"a\": {\n    \"list\": [\"x\", 1]\n  },\n  \"e\": {}\n}\n", want: "{\n  \"b\": \"HI\",\n  \"a\": {\n    \"list\": [\n      \"X\",\n      1\n    ]\n  },\n  \"e\": {}\n}\n"},
+		{name: "制表符和CRLF", input: "{\r\n\t\"a\": \"hi\"\r\n}\r\n", want: "{\r\n\t\"a\": \"HI\"\r\n}\r\n"},
+		{name: "单行带空格", input: `{"a": "hi", "b": "<b>"}`, want: `{"a": "HI", "b": "<B>"}`},
+		{name: "沿用原文的转义", input: `{"a":"caf\u00E9","b":"\u003cb\u003e"}`, want: `{"a":"CAF\u00C9","b":"\u003CB\u003E"}`},
+		{name: "原文中有非ASCII字符时不转义", input: `{"a":"café","b":"é"}`, want: `{"a":"CAFÉ","b":"É"}`},
+		{name: "覆盖缩进并排序", input: `{"b":"hi","a":{"d":"x","c":"y"}}`, indent: "4", sort: true, want: "{\n    \"a\": {\n        \"c\": \"Y\",\n        \"d\": \"X\"\n    },\n    \"b\": \"HI\"\n}"},
+		{name: "覆盖为制表符", input: `{"a":"hi"}`, indent: IndentTab, want: "{\n\t\"a\": \"HI\"\n}"},
+		{name: "覆盖为紧凑", input: "{\n  \"a\": \"hi\",\n  \"b\": \"x\"\n}\n", indent: "0", want: "{\"a\":\"HI\",\"b\":\"X\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TranslateJson(context.Background(), tt.input, models.Config{SourceLang: "en", TargetLang: "de", Indent: tt.indent, SortKeys: tt.sort})
+			if err != nil {
+				t.Fatalf("TranslateJson() error = %v", err)
+			}
+			if got.JSON != tt.want {
+				t.Errorf("TranslateJson() = %q, want %q", got.JSON, tt.want)
+			}
+		})
+	}
+
+	for indent, valid := range map[string]bool{"": true, "tab": true, "0": true, "8": true, "9": false, "-1": false, "two": false} {
+		if err := ValidateIndent(indent); (err == nil) != valid {
+			t.Errorf("ValidateIndent(%q) error = %v, want valid %v", indent, err, valid)
+		}
+	}
+}
+
+func TestPseudoLocale(t *testing.T) {
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	input := `{"greeting":"Hello {name}","body":"<b>Bold</b> text","padded":" Hi ","count":2}`
+	got, err := TranslateJson(context.Background(), input, models.Config{SourceLang: "en", TargetLang: PseudoLocale})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	want := `{"greeting":"[Ĥéļļö {name} ~~]","body":"[<b>Ɓöļð</b> ţéẋţ ~~~]","padded":" [Ĥí ~] ","count":2}`
+	if got.JSON != want {
+		t.Errorf("TranslateJson() = %s, want %s", got.JSON, want)
+	}
+	if len(fake.batches) != 0 {
+		t.Errorf("pseudo-localization called the provider: %v", fake.batches)
+	}
+	if len(got.Issues) != 0 {
+		t.Errorf("Issues = %v, want none", got.Issues)
+	}
+
+	expansion := 0
+	got, err = TranslateJson(context.Background(), `["Hi"]`, models.Config{SourceLang: "en", TargetLang: PseudoLocaleMirrored, PseudoExpansion: &expansion})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if want := "[\"\u202e[Ĥí]\u202c\"]"; got.JSON != want {
+		t.Errorf("TranslateJson() = %q, want %q", got.JSON, want)
+	}
+}
+
+func TestTranslateStream(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	// 超过一个窗口的字符串，验证跨窗口写出的顺序，重复的文本只翻译一次，测试不会太慢
+	var items []string
+	for i := 0; i < streamWindowSegments+20; i++ {
+		items = append(items, fmt.Sprintf(`{"id":%d,"title":"item %d","tags":["tag %d",true,null]}`, i, i%5, i%3))
+	}
+	// 流式翻译和 TranslateJson 一样按原文决定末尾是否换行
+	input := `{"name":"Shop","count":3,"nested":{"url":"https://example.com","label":"Hello {name}"},"items":[` + strings.Join(items, ",") + "]}\n"
+	config := models.Config{SourceLang: "en", TargetLang: "de", IgnoredFields: []string{"nested.label"}}
+
+	want, err := TranslateJson(context.Background(), input, config)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	report, err := TranslateStream(context.Background(), strings.NewReader(input), &out, config)
+	if err != nil {
+		t.Fatalf("TranslateStream() error = %v", err)
+	}
+	if out.String() != want.JSON {
+		t.Errorf("TranslateStream() output differs from TranslateJson()\ngot:  %.200s\nwant: %.200s", out.String(), want.JSON)
+	}
+	if len(report.Failed) != len(want.Failed) {
+		t.Errorf("Failed = %v, want %v", report.Failed, want.Failed)
+	}
+
+	stats, err := AnalyzeStream(strings.NewReader(input), config)
+	if err != nil {
+		t.Fatalf("AnalyzeStream() error = %v", err)
+	}
+	wantStats, err := AnalyzeJson(input, config)
+	if err != nil {
+		t.Fatalf("AnalyzeJson() error = %v", err)
+	}
+	if stats.Chars != wantStats.Chars || !reflect.DeepEqual(stats.Skipped, wantStats.Skipped) {
+		t.Errorf("AnalyzeStream() = %d %v, want %d %v", stats.Chars, stats.Skipped, wantStats.Chars, wantStats.Skipped)
+	}
+
+	if _, err := TranslateStream(context.Background(), strings.NewReader(`{"a":"b"} x`), io.Discard, config); err == nil {
+		t.Error("TranslateStream() error = nil, want trailing data error")
+	}
+	if _, err := TranslateStream(context.Background(), strings.NewReader(input), io.Discard, models.Config{SourceLang: "en", TargetLang: "de", ExistingTarget: "{}"}); err == nil {
+		t.Error("TranslateStream() error = nil, want merge mode not supported")
+	}
+}
+
+func TestTranslateStreamOutputStyle(t *testing.T) {
+	translateapi.SetTranslator(&fakeTranslator{})
+
+	// 4 个空格缩进、CRLF 换行、\u 转义非 ASCII 字符的原文，流式输出和 TranslateJson 的格式一致
+	input := "{\r\n" +
+		"    \"title\": \"Caf\\u00e9 <b>menu</b>\",\r\n" +
+		"    \"empty\": {},\r\n" +
+		"    \"items\": [\r\n" +
+		"        \"one\",\r\n" +
+		"        2\r\n" +
+		"    ]\r\n" +
+		"}\r\n"
+	want := "{\r\n" +
+		"    \"title\": \"CAF\\u00c9 <B>MENU</B>\",\r\n" +
+		"    \"empty\": {},\r\n" +
+		"    \"items\": [\r\n" +
+		"        \"ONE\",\r\n" +
+		"        2\r\n" +
+		"    ]\r\n" +
+		"}\r\n"
+	config := models.Config{SourceLang: "en", TargetLang: "de"}
+
+	var out bytes.Buffer
+	if _, err := TranslateStream(context.Background(), strings.NewReader(input), &out, config); err != nil {
+		t.Fatalf("TranslateStream() error = %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("TranslateStream() = %q, want %q", out.String(), want)
+	}
+	got, err := TranslateJson(context.Background(), input, config)
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if got.JSON != out.String() {
+		t.Errorf("TranslateJson() = %q, want the same as TranslateStream() %q", got.JSON, out.String())
+	}
+
+	// 原文末尾没有换行时也不加换行
+	out.Reset()
+	if _, err := TranslateStream(context.Background(), strings.NewReader(`{"a": "b"}`), &out, config); err != nil {
+		t.Fatalf("TranslateStream() error = %v", err)
+	}
+	if want := `{"a": "B"}`; out.String() != want {
+		t.Errorf("TranslateStream() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestSkipNonTranslatable(t *testing.T) {
+	fake := &fakeTranslator{}
+	translateapi.SetTranslator(fake)
+
+	input := `{"site":"https://example.com","mail":"a@b.co","id":"123e4567-e89b-12d3-a456-426614174000","date":"2024-05-01T10:00:00Z","color":"#ff00aa","price":"1,299.00","class":"btn_primary","sku":"SKU-001","label":"Buy now"}`
+
+	tests := []struct {
+		name              string
+		disabledDetectors []string
+		skipPatterns      []string
+		wantChars         int
+		wantSkipped       []models.SkippedValue
+		wantSent          []string
+	}{
+		{
+			name:      "default detectors",
+			wantChars: len("SKU-001") + len("Buy now"),
+			wantSkipped: []models.SkippedValue{
+				{Path: "site", Reason: DetectorURL},
+				{Path: "mail", Reason: DetectorEmail},
+				{Path: "id", Reason: DetectorUUID},
+				{Path: "date", Reason: DetectorDate},
+				{Path: "color", Reason: DetectorColor},
+				{Path: "price", Reason: DetectorNumber},
+				{Path: "class", Reason: DetectorIdentifier},
+			},
+			wantSent: []string{"Buy now", "SKU-001"},
+		},
+		{
+			name:              "disabled detectors and custom pattern",
+			disabledDetectors: []string{DetectorURL, DetectorEmail, DetectorUUID, DetectorDate, DetectorColor, DetectorNumber},
+			skipPatterns:      []string{`^SKU-\d+$`},
+			wantChars:         95, // 与实际发送的字符串一致，不包括没有字母的 1,299.00
+			wantSkipped: []models.SkippedValue{
+				{Path: "class", Reason: DetectorIdentifier},
+				{Path: "sku", Reason: skipReasonPattern},
+			},
+			// 没有字母的 1,299.00 本身就不会发送给翻译接口
+			wantSent: []string{"#ff00aa", "123e4567-e89b-12d3-a456-426614174000", "2024-05-01T10:00:00Z", "Buy now", "a@b.co", "https://example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := models.Config{SourceLang: "en", TargetLang: "zh", DisabledDetectors: tt.disabledDetectors, SkipPatterns: tt.skipPatterns}
+			stats, err := AnalyzeJson(input, cfg)
+			if err != nil {
+				t.Fatalf("AnalyzeJson() error = %v", err)
+			}
+			if stats.Chars != tt.wantChars {
+				t.Errorf("AnalyzeJson() chars = %d, want %d", stats.Chars, tt.wantChars)
+			}
+			if !reflect.DeepEqual(stats.Skipped, tt.wantSkipped) {
+				t.Errorf("AnalyzeJson() skipped = %v, want %v", stats.Skipped, tt.wantSkipped)
+			}
+
+			// 跳过的值不会发送给翻译接口
+			fake.batches = nil
+			if _, err := TranslateJson(context.Background(), input, cfg); err != nil {
+				t.Fatalf("TranslateJson() error = %v", err)
+			}
+			var sent []string
+			for _, batch := range fake.batches {
+				sent = append(sent, batch...)
+			}
+			sort.Strings(sent)
+			if !reflect.DeepEqual(sent, tt.wantSent) {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+		})
+	}
+
+	if err := ValidateSkipRules([]string{"unknown"}, nil); err == nil {
+		t.Errorf("ValidateSkipRules() expected error for unknown detector")
+	}
+	if err := ValidateSkipRules(nil, []string{"("}); err == nil {
+		t.Errorf("ValidateSkipRules() expected error for invalid pattern")
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "throttled then success",
+			errs:      []error{&translateapi.APIError{StatusCode: http.StatusTooManyRequests}, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "non retryable error",
+			errs:      []error{&translateapi.APIError{StatusCode: http.StatusBadRequest}},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name: "retries exhausted",
+			errs: []error{
+				&translateapi.APIError{StatusCode: http.StatusServiceUnavailable},
+				&translateapi.APIError{StatusCode: http.StatusServiceUnavailable},
+				&translateapi.APIError{StatusCode: http.StatusServiceUnavailable},
+			},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := withRetry(context.Background(), 2, func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("withRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("withRetry() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// errorLimiter 模拟Redis不可用的分布式限流器
+type errorLimiter struct{}
+
+func (errorLimiter) Wait(ctx context.Context, from string, to string) error {
+	return errors.New("redis: connection refused")
+}
+
+func TestLimiterFallback(t *testing.T) {
+	defer func() { limiter = localLimiter }()
+	SetLimiter(errorLimiter{})
+
+	// 分布式限流器出错时退回到进程内的令牌桶，翻译照常完成
+	translateapi.SetTranslator(&fakeTranslator{})
+	got, err := TranslateJson(context.Background(), `{"a":"save"}`, models.Config{SourceLang: "en", TargetLang: "zh"})
+	if err != nil {
+		t.Fatalf("TranslateJson() error = %v", err)
+	}
+	if want := `{"a":"SAVE"}`; strings.TrimRight(got.JSON, "\n") != want {
+		t.Errorf("TranslateJson() = %v, want %v", got.JSON, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, "en", "zh"); err == nil {
+		t.Error("Wait() error = nil, want context canceled")
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	texts := []string{"aaaa", "bb", "cccccccc", "d", "e"}
+	got := splitBatches(texts, 3, 6)
+	want := [][]string{{"aaaa", "bb"}, {"cccccccc"}, {"d", "e"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitBatches() = %v, want %v", got, want)
+	}
+}
+
 func TestCountJsonChars(t *testing.T) {
 	// 定义测试用例表
 	tests := []struct {
@@ -77,7 +1540,7 @@ func TestCountJsonChars(t *testing.T) {
                 "special": "!@#$%^&*",  
                 "unicode": "你好世界"  
             }`,
-			want:    12,
+			want:    4, // 没有文字的字符串不调用翻译接口，不计费
 			wantErr: false,
 		},
 		{
@@ -112,6 +1575,25 @@ func TestCountJsonChars(t *testing.T) {
 			want:    0,
 			wantErr: false,
 		},
+		{
+			// 只统计 ICU 分支中的文字，不包括参数、关键字和大括号
+			name:    "icu plural",
+			json:    `{"a":"{count, plural, one {# item} other {# items}}"}`,
+			want:    11,
+			wantErr: false,
+		},
+		{
+			name:    "only placeholders",
+			json:    `{"b":"{name}","c":"hi {name}"}`,
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "html tags",
+			json:    `{"a":"<b>Save</b> now&nbsp;"}`,
+			want:    8,
+			wantErr: false,
+		},
 	}
 	translate_config := models.Config{
 		IgnoredFields: []string{},
@@ -236,3 +1718,65 @@ func TestExtractVariablesEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestReviewUnits(t *testing.T) {
+	source := `{"title":"Hello {name}","id":"7f3c2a9e-1b4d-4c8e-9f6a-2d5b8c1e0a47","count":"{n, plural, one{# file} other{# files}}","meta":{"url":"Home"},"tags":["New"]}`
+	target := `{"title":"Hallo {name}","id":"7f3c2a9e-1b4d-4c8e-9f6a-2d5b8c1e0a47","count":"{n, plural, one{# Datei} other{# Dateien}}","meta":{"url":"Home"},"tags":["Neu"]}`
+	config := models.Config{SourceLang: "en", TargetLang: "de", IgnoredFields: []string{"meta"}}
+
+	units, err := ReviewUnits(source, target, config)
+	if err != nil {
+		t.Fatalf("ReviewUnits() error = %v", err)
+	}
+	want := []ReviewUnit{
+		{Path: "title", Source: "Hello {name}", Target: "Hallo {name}", Placeholders: []string{"{name}"}},
+		{Path: "count", Source: "{n, plural, one{# file} other{# files}}", Target: "{n, plural, one{# Datei} other{# Dateien}}", Placeholders: []string{"{n, plural}"}},
+		{Path: "tags[0]", Source: "New", Target: "Neu"},
+	}
+	if !reflect.DeepEqual(units, want) {
+		t.Errorf("ReviewUnits() = %+v, want %+v", units, want)
+	}
+}
+
+func TestApplyReview(t *testing.T) {
+	source := `{"title":"Hello {name}","body":"<b>Save</b> now","count":"{n, plural, one{# file} other{# files}}","list":["One","Two"],"n":1}`
+	target := `{"title":"Hallo {name}","body":"<b>Speichern</b> jetzt","count":"{n, plural, one{# Datei} other{# Dateien}}","list":["Eins","Zwei"],"n":1}`
+	config := models.Config{SourceLang: "en", TargetLang: "de"}
+
+	reviewed := []ReviewUnit{
+		{Path: "title", Target: "Servus {name}"},
+		{Path: "body", Target: "Jetzt speichern"},
+		{Path: "count", Target: "{n, plural, one{# Akte} few{# Akten} other{# Akten}}"},
+		{Path: "list[1]", Target: "Zwei"},
+		{Path: "list[5]", Target: "Fünf"},
+		{Path: "n", Target: "2"},
+	}
+	got, report, err := ApplyReview(source, target, reviewed, config)
+	if err != nil {
+		t.Fatalf("ApplyReview() error = %v", err)
+	}
+
+	want := `{"title":"Servus {name}","body":"<b>Speichern</b> jetzt","count":"{n, plural, one{# Akte} few{# Akten} other{# Akten}}","list":["Eins","Zwei"],"n":1}`
+	if got != want {
+		t.Errorf("ApplyReview() = %s, want %s", got, want)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"title", "count"}) {
+		t.Errorf("Updated = %v, want [title count]", report.Updated)
+	}
+	if report.Unchanged != 1 {
+		t.Errorf("Unchanged = %d, want 1", report.Unchanged)
+	}
+	if !reflect.DeepEqual(report.UnknownIds, []string{"list[5]", "n"}) {
+		t.Errorf("UnknownIds = %v, want [list[5] n]", report.UnknownIds)
+	}
+	wantChanges := []models.PlaceholderChange{{Path: "body", Missing: []string{"<b>", "</b>"}, Added: []string{}}}
+	if !reflect.DeepEqual(report.PlaceholderChanges, wantChanges) {
+		t.Errorf("PlaceholderChanges = %+v, want %+v", report.PlaceholderChanges, wantChanges)
+	}
+
+	// 没有修改时原样返回译文
+	got, _, err = ApplyReview(source, target, []ReviewUnit{{Path: "title", Target: "Hallo {name}"}}, config)
+	if err != nil || got != target {
+		t.Errorf("ApplyReview() = %s, %v, want the target unchanged", got, err)
+	}
+}
@@ -0,0 +1,221 @@
+package translate
+
+import (
+	"fmt"
+	"json_trans_api/models/models"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 译文质量检查项
+const (
+	CheckPlaceholders = "placeholders" // 占位符个数不一致
+	CheckTags         = "tags"         // HTML 标签个数不一致
+	CheckUntranslated = "untranslated" // 译文和原文相同
+	CheckLengthRatio  = "length_ratio" // 译文长度和原文相差过大
+	CheckWhitespace   = "whitespace"   // 首尾的空白不一致
+	CheckPunctuation  = "punctuation"  // 结尾的标点不一致
+	CheckNewlines     = "newlines"     // 换行的个数不一致
+	CheckScript       = "script"       // 译文的文字不是目标语言使用的文字
+)
+
+const (
+	minLengthRatioLetters = 10  // 原文字母数少于这个值时不检查长度比例
+	maxLengthRatio        = 3.0 // 按文字密度换算后，译文和原文长度之比的上限，下限为倒数
+	minScriptLetters      = 12  // 译文字母数少于这个值时不检查文字，短文本常常保留拉丁字母的缩写和品牌名
+)
+
+// denseLanguages 一个字符通常对应多个拉丁字母的语言，比较长度时按这个系数换算
+var denseLanguages = map[string]float64{
+	"zh": 2.5,
+	"ja": 2.5,
+	"ko": 2,
+}
+
+// languageScripts 使用非拉丁文字的语言，没有列出的语言按拉丁文字处理
+var languageScripts = map[string]string{
+	"zh": "Han", "ja": "Japanese", "ko": "Hangul",
+	"ru": "Cyrillic", "uk": "Cyrillic", "bg": "Cyrillic", "be": "Cyrillic", "kk": "Cyrillic", "ky": "Cyrillic", "mk": "Cyrillic", "mn": "Cyrillic", "sr": "Cyrillic", "tg": "Cyrillic", "tt": "Cyrillic",
+	"ar": "Arabic", "fa": "Arabic", "ur": "Arabic", "ps": "Arabic", "ug": "Arabic",
+	"he": "Hebrew", "yi": "Hebrew",
+	"el": "Greek",
+	"hi": "Devanagari", "mr": "Devanagari", "ne": "Devanagari", "sa": "Devanagari",
+	"bn": "Bengali", "as": "Bengali",
+	"th": "Thai", "lo": "Lao", "km": "Khmer", "my": "Myanmar",
+	"ka": "Georgian", "hy": "Armenian", "am": "Ethiopic",
+	"ta": "Tamil", "te": "Telugu", "kn": "Kannada", "ml": "Malayalam", "gu": "Gujarati", "pa": "Gurmukhi", "si": "Sinhala",
+}
+
+// scriptTables 检查译文的文字时区分的 Unicode 文字，名称和 languageScripts 一致，日文的假名记为 Japanese
+var scriptTables = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin}, {"Han", unicode.Han}, {"Japanese", unicode.Hiragana}, {"Japanese", unicode.Katakana}, {"Hangul", unicode.Hangul},
+	{"Cyrillic", unicode.Cyrillic}, {"Arabic", unicode.Arabic}, {"Hebrew", unicode.Hebrew}, {"Greek", unicode.Greek},
+	{"Devanagari", unicode.Devanagari}, {"Bengali", unicode.Bengali}, {"Thai", unicode.Thai}, {"Lao", unicode.Lao},
+	{"Khmer", unicode.Khmer}, {"Myanmar", unicode.Myanmar}, {"Georgian", unicode.Georgian}, {"Armenian", unicode.Armenian},
+	{"Ethiopic", unicode.Ethiopic}, {"Tamil", unicode.Tamil}, {"Telugu", unicode.Telugu}, {"Kannada", unicode.Kannada},
+	{"Malayalam", unicode.Malayalam}, {"Gujarati", unicode.Gujarati}, {"Gurmukhi", unicode.Gurmukhi}, {"Sinhala", unicode.Sinhala},
+}
+
+// validator 检查每个字符串的译文，发现占位符、标签、空白、标点和长度等问题
+type validator struct {
+	masker     *segmentMasker
+	sourceLang string
+	targetLang string
+}
+
+func newValidator(masker *segmentMasker, config models.Config) *validator {
+	return &validator{masker: masker, sourceLang: config.SourceLang, targetLang: config.TargetLang}
+}
+
+// check 检查一个字符串的译文，只有术语和占位符的字符串不需要翻译，不检查
+func (v *validator) check(seg *segment, translated string) []models.QualityIssue {
+	if m, _ := v.masker.mask(seg.text, seg.format); m.onlyPlaceholders() {
+		return nil
+	}
+
+	var issues []models.QualityIssue
+	add := func(check string, format string, args ...interface{}) {
+		issues = append(issues, models.QualityIssue{Path: seg.path, Check: check, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if want, got := len(v.masker.html.FindAllString(seg.text, -1)), len(v.masker.html.FindAllString(translated, -1)); want != got {
+		add(CheckPlaceholders, "expected %d placeholders, found %d", want, got)
+	}
+	if want, got := len(htmlTag.FindAllString(seg.text, -1)), len(htmlTag.FindAllString(translated, -1)); want != got {
+		add(CheckTags, "expected %d tags, found %d", want, got)
+	}
+	if translated == seg.text {
+		add(CheckUntranslated, "translation is identical to the source")
+	}
+
+	if source := countLetters(seg.text); source >= minLengthRatioLetters {
+		ratio := float64(countLetters(translated)) * languageDensity(v.targetLang) / (float64(source) * languageDensity(v.sourceLang))
+		if ratio > maxLengthRatio || ratio < 1/maxLengthRatio {
+			add(CheckLengthRatio, "translation is %.1fx the length of the source", ratio)
+		}
+	}
+
+	if leadingSpace(seg.text) != leadingSpace(translated) || trailingSpace(seg.text) != trailingSpace(translated) {
+		add(CheckWhitespace, "leading or trailing whitespace differs from the source")
+	}
+	if want, got := endPunctuation(seg.text), endPunctuation(translated); want != got {
+		add(CheckPunctuation, "source ends with %s, translation ends with %s", punctuationName(want), punctuationName(got))
+	}
+	if want, got := strings.Count(seg.text, "\n"), strings.Count(translated, "\n"); want != got {
+		add(CheckNewlines, "expected %d newlines, found %d", want, got)
+	}
+
+	// 在本地按 Unicode 的范围判断译文的文字，不调用翻译服务商的语言检测接口，占位符和标签不计入。
+	// 只有汉字的日文译文也算正确
+	text := htmlTag.ReplaceAllString(v.masker.html.ReplaceAllString(translated, ""), "")
+	if got, want := textScript(text), languageScript(v.targetLang); got != "" && got != want && !(want == "Japanese" && got == "Han") {
+		add(CheckScript, "translation is written in %s script, expected %s for %s", got, want, v.targetLang)
+	}
+	return issues
+}
+
+// textScript 按 Unicode 的范围统计字母，返回最多的文字。有假名时汉字也算作日文，
+// 字母数少于 minScriptLetters 时不可靠，返回空字符串
+func textScript(text string) string {
+	counts := map[string]int{}
+	total := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		total++
+		for _, script := range scriptTables {
+			if unicode.Is(script.table, r) {
+				counts[script.name]++
+				break
+			}
+		}
+	}
+	if total < minScriptLetters {
+		return ""
+	}
+	if counts["Japanese"] > 0 {
+		counts["Japanese"] += counts["Han"]
+		counts["Han"] = 0
+	}
+
+	best := ""
+	for _, script := range scriptTables {
+		if counts[script.name] > counts[best] {
+			best = script.name
+		}
+	}
+	return best
+}
+
+// baseLanguage 去掉地区，例如 zh-tw 为 zh、EN-US 为 en
+func baseLanguage(lang string) string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+func languageScript(lang string) string {
+	if script, ok := languageScripts[baseLanguage(lang)]; ok {
+		return script
+	}
+	return "Latin"
+}
+
+func languageDensity(lang string) float64 {
+	if density, ok := denseLanguages[baseLanguage(lang)]; ok {
+		return density
+	}
+	return 1
+}
+
+func countLetters(text string) int {
+	count := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			count++
+		}
+	}
+	return count
+}
+
+func leadingSpace(text string) string {
+	return text[:len(text)-len(strings.TrimLeftFunc(text, unicode.IsSpace))]
+}
+
+func trailingSpace(text string) string {
+	return text[len(strings.TrimRightFunc(text, unicode.IsSpace)):]
+}
+
+// endPunctuation 结尾的标点类型，全角和各语言的写法归为同一类，例如 "?" 和 "？"
+func endPunctuation(text string) string {
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	if strings.HasSuffix(text, "...") || strings.HasSuffix(text, "…") {
+		return "ellipsis"
+	}
+	r, _ := utf8.DecodeLastRuneInString(text)
+	switch r {
+	case '.', '。', '।', '۔':
+		return "period"
+	case '?', '？', '؟':
+		return "question"
+	case '!', '！':
+		return "exclamation"
+	case ':', '：':
+		return "colon"
+	}
+	return ""
+}
+
+func punctuationName(kind string) string {
+	if kind == "" {
+		return "no punctuation"
+	}
+	return kind
+}
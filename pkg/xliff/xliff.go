@@ -0,0 +1,295 @@
+package xliff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 导出支持的 XLIFF 版本
+const (
+	Version12 = "1.2"
+	Version20 = "2.0"
+)
+
+// ContentType 导出的 XLIFF 文件的 Content-Type
+const ContentType = "application/xliff+xml"
+
+// Document XLIFF 文件中的语言和需要翻译的 unit，Original 是原文件的名称
+type Document struct {
+	Version    string
+	SourceLang string
+	TargetLang string
+	Original   string
+	Units      []Unit
+}
+
+// Unit 一条字符串，Id 是 key 路径，Placeholders 写入 note 提示译员原样保留。
+// Decode 不返回没有 target 的 unit
+type Unit struct {
+	Id           string
+	Source       string
+	Target       string
+	Placeholders []string
+}
+
+// IsSupportedVersion 是否支持导出这个版本
+func IsSupportedVersion(version string) bool {
+	return version == Version12 || version == Version20
+}
+
+// Encode 按 doc.Version 输出 XLIFF 1.2 或 2.0，字符串中的标签等内容作为文字转义，不使用行内元素
+func Encode(doc Document) ([]byte, error) {
+	var root interface{}
+	switch doc.Version {
+	case Version12:
+		root = newXliff12(doc)
+	case Version20:
+		root = newXliff20(doc)
+	default:
+		return nil, fmt.Errorf("unsupported XLIFF version %q", doc.Version)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// placeholderNote 提示译员原样保留的占位符
+func placeholderNote(placeholders []string) string {
+	if len(placeholders) == 0 {
+		return ""
+	}
+	return "Keep these placeholders unchanged: " + strings.Join(placeholders, " ")
+}
+
+// preserve xml:space 的值，保留字符串首尾的空白和换行
+const preserve = "preserve"
+
+type xliff12 struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string   `xml:"version,attr"`
+	File    struct {
+		Original   string        `xml:"original,attr"`
+		SourceLang string        `xml:"source-language,attr"`
+		TargetLang string        `xml:"target-language,attr"`
+		Datatype   string        `xml:"datatype,attr"`
+		Units      []transUnit12 `xml:"body>trans-unit"`
+	} `xml:"file"`
+}
+
+type transUnit12 struct {
+	Id     string `xml:"id,attr"`
+	Space  string `xml:"http://www.w3.org/XML/1998/namespace space,attr"`
+	Source string `xml:"source"`
+	Target string `xml:"target"`
+	Note   string `xml:"note,omitempty"`
+}
+
+func newXliff12(doc Document) *xliff12 {
+	x := &xliff12{Version: Version12}
+	x.File.Original = doc.Original
+	x.File.SourceLang = doc.SourceLang
+	x.File.TargetLang = doc.TargetLang
+	x.File.Datatype = "plaintext"
+	for _, unit := range doc.Units {
+		x.File.Units = append(x.File.Units, transUnit12{
+			Id:     unit.Id,
+			Space:  preserve,
+			Source: unit.Source,
+			Target: unit.Target,
+			Note:   placeholderNote(unit.Placeholders),
+		})
+	}
+	return x
+}
+
+type xliff20 struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string   `xml:"version,attr"`
+	SrcLang string   `xml:"srcLang,attr"`
+	TrgLang string   `xml:"trgLang,attr"`
+	File    struct {
+		Id       string   `xml:"id,attr"`
+		Original string   `xml:"original,attr,omitempty"`
+		Units    []unit20 `xml:"unit"`
+	} `xml:"file"`
+}
+
+type unit20 struct {
+	Id      string `xml:"id,attr"`
+	Notes   *notes20
+	Segment struct {
+		Source text20 `xml:"source"`
+		Target text20 `xml:"target"`
+	} `xml:"segment"`
+}
+
+type notes20 struct {
+	XMLName xml.Name `xml:"notes"`
+	Note    struct {
+		Category string `xml:"category,attr"`
+		Text     string `xml:",chardata"`
+	} `xml:"note"`
+}
+
+type text20 struct {
+	Space string `xml:"http://www.w3.org/XML/1998/namespace space,attr"`
+	Text  string `xml:",chardata"`
+}
+
+func newXliff20(doc Document) *xliff20 {
+	x := &xliff20{Version: Version20, SrcLang: doc.SourceLang, TrgLang: doc.TargetLang}
+	x.File.Id = "f1"
+	x.File.Original = doc.Original
+	for _, unit := range doc.Units {
+		u := unit20{Id: unit.Id}
+		if note := placeholderNote(unit.Placeholders); note != "" {
+			u.Notes = &notes20{}
+			u.Notes.Note.Category = "placeholders"
+			u.Notes.Note.Text = note
+		}
+		u.Segment.Source = text20{Space: preserve, Text: unit.Source}
+		u.Segment.Target = text20{Space: preserve, Text: unit.Target}
+		x.File.Units = append(x.File.Units, u)
+	}
+	return x
+}
+
+// document 解析时同时兼容 1.2 和 2.0，元素和属性不区分命名空间
+type document struct {
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr"`
+	Files   []fileGroup `xml:"file"`
+}
+
+type fileGroup struct {
+	Original   string `xml:"original,attr"`
+	SourceLang string `xml:"source-language,attr"`
+	TargetLang string `xml:"target-language,attr"`
+	Body       group  `xml:"body"`
+	group
+}
+
+// group 1.2 的 body 和 group、2.0 的 file 和 group 中的 unit，group 可以嵌套
+type group struct {
+	TransUnits []struct {
+		Id     string `xml:"id,attr"`
+		Source text   `xml:"source"`
+		Target *text  `xml:"target"`
+	} `xml:"trans-unit"`
+	Units []struct {
+		Id       string `xml:"id,attr"`
+		Segments []struct {
+			Source text  `xml:"source"`
+			Target *text `xml:"target"`
+		} `xml:"segment"`
+	} `xml:"unit"`
+	Groups []group `xml:"group"`
+}
+
+// text source 或 target 的内容，行内元素无法对应回 JSON 字符串，解析时报错
+type text struct {
+	Text   string `xml:",chardata"`
+	Inline []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// Decode 解析 XLIFF 1.2 或 2.0，返回有 target 的 unit。2.0 的多个 segment 按顺序拼接
+func Decode(r io.Reader) (*Document, error) {
+	return decode(r, false)
+}
+
+// DecodeSource 解析 XLIFF 1.2 或 2.0，返回全部 unit，没有 target 的 unit 的 Target 为空，
+// 用于把 XLIFF 文件作为原文翻译
+func DecodeSource(r io.Reader) (*Document, error) {
+	return decode(r, true)
+}
+
+func decode(r io.Reader, all bool) (*Document, error) {
+	var x document
+	if err := xml.NewDecoder(r).Decode(&x); err != nil {
+		return nil, fmt.Errorf("invalid XLIFF: %v", err)
+	}
+
+	doc := &Document{Version: x.Version, SourceLang: x.SrcLang, TargetLang: x.TrgLang}
+	switch {
+	case strings.HasPrefix(x.Version, "1."):
+		for _, file := range x.Files {
+			if doc.SourceLang == "" {
+				doc.SourceLang, doc.TargetLang = file.SourceLang, file.TargetLang
+			}
+			if err := file.Body.units(doc, all); err != nil {
+				return nil, err
+			}
+		}
+	case strings.HasPrefix(x.Version, "2."):
+		for _, file := range x.Files {
+			if err := file.group.units(doc, all); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported XLIFF version %q", x.Version)
+	}
+
+	if len(x.Files) == 0 {
+		return nil, errors.New("invalid XLIFF: no file element")
+	}
+	doc.Original = x.Files[0].Original
+	return doc, nil
+}
+
+// units 收集 unit，all 为 false 时跳过没有 target 的 unit
+func (g *group) units(doc *Document, all bool) error {
+	for _, u := range g.TransUnits {
+		if u.Target == nil && !all {
+			continue
+		}
+		if len(u.Source.Inline) > 0 || (u.Target != nil && len(u.Target.Inline) > 0) {
+			return fmt.Errorf("unit %s: inline elements are not supported", u.Id)
+		}
+		unit := Unit{Id: u.Id, Source: u.Source.Text}
+		if u.Target != nil {
+			unit.Target = u.Target.Text
+		}
+		doc.Units = append(doc.Units, unit)
+	}
+
+	for _, u := range g.Units {
+		unit := Unit{Id: u.Id}
+		translated := len(u.Segments) > 0
+		for _, segment := range u.Segments {
+			if len(segment.Source.Inline) > 0 || (segment.Target != nil && len(segment.Target.Inline) > 0) {
+				return fmt.Errorf("unit %s: inline elements are not supported", u.Id)
+			}
+			unit.Source += segment.Source.Text
+			if segment.Target == nil {
+				translated = false
+				continue
+			}
+			unit.Target += segment.Target.Text
+		}
+		if translated || all {
+			doc.Units = append(doc.Units, unit)
+		}
+	}
+
+	for i := range g.Groups {
+		if err := g.Groups[i].units(doc, all); err != nil {
+			return err
+		}
+	}
+	return nil
+}
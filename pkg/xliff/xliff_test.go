@@ -0,0 +1,87 @@
+package xliff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	doc := Document{
+		SourceLang: "en",
+		TargetLang: "de",
+		Original:   "messages.json",
+		Units: []Unit{
+			{Id: "title", Source: "Hello {name}", Target: "Hallo {name}", Placeholders: []string{"{name}"}},
+			{Id: "items[0].body", Source: " <b>Save</b> & close\n", Target: " <b>Speichern</b> & schließen\n"},
+		},
+	}
+
+	for _, version := range []string{Version12, Version20} {
+		t.Run(version, func(t *testing.T) {
+			doc.Version = version
+			data, err := Encode(doc)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if !strings.Contains(string(data), "Keep these placeholders unchanged: {name}") {
+				t.Errorf("Encode() = %s, want a placeholder note", data)
+			}
+			if !strings.Contains(string(data), `version="`+version+`"`) {
+				t.Errorf("Encode() = %s, want version %s", data, version)
+			}
+
+			got, err := Decode(strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got.Version != version || got.SourceLang != "en" || got.TargetLang != "de" {
+				t.Errorf("Decode() = %s %s %s, want %s en de", got.Version, got.SourceLang, got.TargetLang, version)
+			}
+			want := []Unit{
+				{Id: "title", Source: "Hello {name}", Target: "Hallo {name}"},
+				{Id: "items[0].body", Source: " <b>Save</b> & close\n", Target: " <b>Speichern</b> & schließen\n"},
+			}
+			if !reflect.DeepEqual(got.Units, want) {
+				t.Errorf("Decode() units = %+v, want %+v", got.Units, want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	input := `<?xml version="1.0"?>
+<xliff version="2.0" xmlns="urn:oasis:names:tc:xliff:document:2.0" srcLang="en" trgLang="fr">
+  <file id="f1">
+    <group id="g1">
+      <unit id="a.b">
+        <segment><source>Hello </source><target>Bonjour </target></segment>
+        <segment><source>world</source><target>le monde</target></segment>
+      </unit>
+    </group>
+    <unit id="untranslated">
+      <segment><source>Skip</source></segment>
+    </unit>
+  </file>
+</xliff>`
+	got, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []Unit{{Id: "a.b", Source: "Hello world", Target: "Bonjour le monde"}}
+	if !reflect.DeepEqual(got.Units, want) {
+		t.Errorf("Decode() units = %+v, want %+v", got.Units, want)
+	}
+
+	errorInputs := []string{
+		`<xliff version="3.0"><file/></xliff>`,
+		`<xliff version="1.2"></xliff>`,
+		`<xliff version="1.2"><file><body><trans-unit id="a"><source>Hi</source><target><g id="1">Salut</g></target></trans-unit></body></file></xliff>`,
+		`not xml`,
+	}
+	for _, input := range errorInputs {
+		if _, err := Decode(strings.NewReader(input)); err == nil {
+			t.Errorf("Decode(%q) error = nil, want error", input)
+		}
+	}
+}
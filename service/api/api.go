@@ -3,16 +3,23 @@ package api
 import (
 	// "encoding/json"
 
+	"json_trans_api/config"
+	"json_trans_api/pkg/rds"
 	"json_trans_api/pkg/tasks"
+	"json_trans_api/pkg/translate"
 	"json_trans_api/service/api/blog"
 	"json_trans_api/service/api/json"
 	"json_trans_api/service/api/middleware/auth"
 	"json_trans_api/service/api/user/apikey"
+	"json_trans_api/service/api/user/glossary"
+	"json_trans_api/service/api/user/memory"
 	"json_trans_api/service/api/user/plan"
+	"json_trans_api/service/api/user/ratelimit"
 	"json_trans_api/service/api/user/usage"
 	"json_trans_api/service/api/user/webhook"
 	"json_trans_api/service/api/user/stripe"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -23,6 +30,10 @@ func Run() {
 	// queue close
 	defer tasks.AsynqClient.Close()
 
+	// 翻译记忆，用于清除用户自己的记录
+	memoryTTL := time.Duration(config.Cfg.Translate.Memory.TTLDays) * 24 * time.Hour
+	translate.SetupMemory(rds.NewMemoryStore(memoryTTL, translate.MemoryKeyPrefix))
+
 	// Router
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
@@ -65,7 +76,19 @@ func Run() {
 			r.Get("/detail/{id}", webhook.WebhookDetails)
 		})
 
+		// 术语表相关api
+		r.Route("/glossaries", func(r chi.Router) {
+			r.Get("/", glossary.ListGlossaries)
+			r.Post("/", glossary.CreateGlossary)
+			r.Post("/import", glossary.ImportGlossary)
+			r.Get("/{id}", glossary.GetGlossary)
+			r.Put("/{id}", glossary.UpdateGlossary)
+			r.Delete("/{id}", glossary.DeleteGlossary)
+		})
+
 		r.Get("/api_key", apikey.GetApiKeys)
+		r.Delete("/translation_memory", memory.PurgeMemory)
+		r.Get("/rate_limit", ratelimit.GetUsage)
 		r.Get("/usage", usage.GetCurrentUsage)
 		r.Get("/usage_history", usage.GetUsageHistory)
 
@@ -98,8 +121,13 @@ func V1JsonRoute() *chi.Mux {
 	// 批量翻译请求[mvp 不开放批量功能]
 	// router.Post("/batch", json.CreateBatch)
 	router.Post("/", json.CreateOne)
+	router.Post("/upload", json.CreateFromFile)
 	router.Delete("/{id}", json.DeleteById)
 	router.Get("/", json.GetListData)
+	router.Get("/jobs/{id}", json.GetJobById)
+	router.Get("/{id}/download", json.DownloadById)
+	router.Get("/{id}/xliff", json.ExportXliff)
+	router.Post("/{id}/xliff", json.ImportXliff)
 	router.Get("/{id}", json.GetOneById)
 	// router.Put("/{id}", json.UpdateById)
 	return router
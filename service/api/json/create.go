@@ -3,32 +3,81 @@ package json
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"json_trans_api/config"
 	"json_trans_api/models/models"
+	"json_trans_api/models/tables"
+	"json_trans_api/pkg/fileformat"
+	"json_trans_api/pkg/glossary"
 	"json_trans_api/pkg/httpclient"
 	responsex "json_trans_api/pkg/response"
 	"json_trans_api/pkg/tasks"
 	"json_trans_api/pkg/translate"
 	"json_trans_api/pkg/users"
 	"json_trans_api/service/api/middleware/auth"
+	"json_trans_api/utils/translateapi"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 )
 
 type UserJsonDataRequest struct {
-	OriginJson    string `json:"origin_json"`
-	FromLang      string `json:"from_lang"`
-	ToLang        string `json:"to_lang"`
-	IgnoredFields string `json:"ignored_fields"`
+	OriginJson        string   `json:"origin_json"`
+	FileFormat        string   `json:"file_format"` // origin_json 的格式，json(默认)、jsonc、json5、yaml、properties、po、android、strings、stringsdict、arb 或 xliff
+	FromLang          string   `json:"from_lang"`
+	ToLang            string   `json:"to_lang"`
+	IgnoredFields     string   `json:"ignored_fields"`     // 忽略翻译的路径规则，逗号分隔，如 id,meta.*,**.url
+	IncludedFields    string   `json:"included_fields"`    // 只翻译的路径规则，逗号分隔，如 items[*].title
+	Placeholders      string   `json:"placeholders"`       // 需要保护的占位符类型，逗号分隔，如 printf,mustache,dollar,colon
+	Formats           string   `json:"formats"`            // 按路径指定字符串的格式，逗号分隔，如 body=html,**.notes=markdown
+	DisabledDetectors string   `json:"disabled_detectors"` // 关闭的自动跳过类型，逗号分隔，如 number,identifier
+	SkipPatterns      []string `json:"skip_patterns"`      // 自定义的跳过正则，匹配的字符串不翻译
+	DisableMemory     bool     `json:"disable_memory"`     // 不使用翻译记忆
+	ToLangs           []string `json:"to_langs"`           // 多个目标语言，会创建一个多语言翻译任务
+	WebhookMode       string   `json:"webhook_mode"`       // 多语言翻译任务的 webhook 发送方式，all(默认) 或 each
+	BaseId            string   `json:"base_id"`            // 增量翻译：以这次翻译的原文和译文为基础，只翻译新增或修改的字符串
+	PreviousSource    string   `json:"previous_source"`    // 增量翻译：上一次的原文JSON，与 previous_target 一起使用
+	PreviousTarget    string   `json:"previous_target"`    // 增量翻译：上一次的译文JSON
+	ExistingTarget    string   `json:"existing_target"`    // 合并模式：已有的目标语言JSON，保留已有的值，只翻译缺少的 key
+	PruneRemoved      bool     `json:"prune_removed"`      // 合并模式：删除原文中已经不存在的 key
+	Description       string   `json:"description"`        // 内容的描述，如 "Mobile app settings screen"，和 key 路径一起作为翻译的上下文
+	GlossaryId        string   `json:"glossary_id"`        // 使用的术语表，术语按术语表规定的译法翻译或者保留原文
+	Mode              string   `json:"mode"`               // lenient(默认) 部分字符串翻译失败时保留原文，strict 任意字符串失败时整个翻译失败
+	PseudoExpansion   *int     `json:"pseudo_expansion"`   // 伪本地化(qps-ploc、qps-plocm)时译文加长的百分比，默认 30
+	CommentContext    bool     `json:"comment_context"`    // jsonc 和 json5 中字符串前面和同一行后面的注释作为翻译的上下文
+	KeyStyle          string   `json:"key_style"`          // 译文中 key 的形状，flat 输出扁平的 key，nested 输出嵌套的对象，为空时保持原文的形状
+	KeySeparator      string   `json:"key_separator"`      // 扁平的 key 中各层之间的分隔符，默认为 "."
+	Indent            string   `json:"indent"`             // 译文的缩进，tab 或者 0-8 个空格，0 输出紧凑的 JSON，为空时沿用原文的缩进
+	SortKeys          bool     `json:"sort_keys"`          // 译文中对象的 key 按字母顺序排列
 }
 
+// targetLangs 合并 to_lang 和 to_langs 并去重
+func (req UserJsonDataRequest) targetLangs() []string {
+	var langs []string
+	seen := map[string]bool{}
+	for _, lang := range append([]string{req.ToLang}, req.ToLangs...) {
+		lang = strings.TrimSpace(lang)
+		if lang == "" || seen[lang] {
+			continue
+		}
+		seen[lang] = true
+		langs = append(langs, lang)
+	}
+	return langs
+}
+
+// 描述会和每个字符串的上下文一起发给翻译服务商，限制长度
+const maxDescriptionLength = 500
+
 type BatchTranslationRequest struct {
 	Requests []UserJsonDataRequest `json:"requests"`
 }
@@ -61,7 +110,8 @@ func CreateOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if requestData.ToLang == "" {
+	targetLangs := requestData.targetLangs()
+	if len(targetLangs) == 0 {
 		responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
 			Code: http.StatusBadRequest,
 			Msg:  "Please specify the target language.",
@@ -79,17 +129,37 @@ func CreateOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if requestData.FromLang == requestData.ToLang {
+	for _, toLang := range targetLangs {
+		if requestData.FromLang == toLang {
+			responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+				Code: http.StatusBadRequest,
+				Msg:  "Source and target languages must be different. Please choose a different target language.",
+				Data: map[string]interface{}{},
+			})
+			return
+		}
+	}
+
+	if requestData.WebhookMode != "" && requestData.WebhookMode != tasks.WebhookModeAll && requestData.WebhookMode != tasks.WebhookModeEach {
+		responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+			Code: http.StatusBadRequest,
+			Msg:  "Invalid webhook_mode. Supported values are all and each.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	if utf8.RuneCountInString(requestData.Description) > maxDescriptionLength {
 		responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
 			Code: http.StatusBadRequest,
-			Msg:  "Source and target languages must be different. Please choose a different target language.",
+			Msg:  fmt.Sprintf("The description must not exceed %d characters.", maxDescriptionLength),
 			Data: map[string]interface{}{},
 		})
 		return
 	}
 
 	// 语言支持校验
-	if !config.IsLanguageSupported(requestData.FromLang) {
+	if !translateapi.IsLanguageSupported(requestData.FromLang) {
 		responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
 			Code: http.StatusBadRequest,
 			Msg:  "The specified source language is not supported. Please check our documentation for supported languages.",
@@ -98,26 +168,122 @@ func CreateOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !config.IsLanguageSupported(requestData.ToLang) {
+	for _, toLang := range targetLangs {
+		if !translateapi.IsLanguageSupported(toLang) && !translate.IsPseudoLocale(toLang) {
+			responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+				Code: http.StatusBadRequest,
+				Msg:  fmt.Sprintf("The specified target language %s is not supported. Please check our documentation for supported languages.", toLang),
+				Data: map[string]interface{}{},
+			})
+			return
+		}
+	}
+
+	// JSON格式验证，其他文件格式在统计字符数时解析
+	if fileformat.IsJSON(requestData.FileFormat) && !json.Valid([]byte(requestData.OriginJson)) {
 		responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
 			Code: http.StatusBadRequest,
-			Msg:  "The specified target language is not supported. Please check our documentation for supported languages.",
+			Msg:  "The provided content is not a valid JSON format. For JSON with comments or trailing commas, set file_format to jsonc or json5.",
 			Data: map[string]interface{}{},
 		})
 		return
 	}
 
-	// JSON格式验证
-	if !json.Valid([]byte(requestData.OriginJson)) {
+	// 翻译模式、路径规则、占位符类型、格式规则和自动跳过规则校验
+	if msg := validateOptions(requestData); msg != "" {
 		responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
 			Code: http.StatusBadRequest,
-			Msg:  "The provided content is not a valid JSON format. Please check and try again.",
+			Msg:  msg,
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	// 增量翻译校验，base_id 使用之前一次翻译的原文和译文
+	if requestData.BaseId != "" || requestData.PreviousSource != "" || requestData.PreviousTarget != "" {
+		if len(targetLangs) > 1 {
+			responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+				Code: http.StatusBadRequest,
+				Msg:  "Incremental translation supports a single target language only.",
+				Data: map[string]interface{}{},
+			})
+			return
+		}
+
+		if requestData.BaseId != "" {
+			if requestData.PreviousSource != "" || requestData.PreviousTarget != "" {
+				responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+					Code: http.StatusBadRequest,
+					Msg:  "Please provide either base_id or previous_source and previous_target, not both.",
+					Data: map[string]interface{}{},
+				})
+				return
+			}
+
+			base, status, msg := fetchBaseTranslation(requestData.BaseId, auth.GetUserIDFromContext(r), requestData.FromLang, targetLangs[0])
+			if base == nil {
+				responsex.RespondWithJSON(w, status, models.Response{
+					Code: status,
+					Msg:  msg,
+					Data: map[string]interface{}{},
+				})
+				return
+			}
+			requestData.PreviousSource = base.OriginJSON
+			requestData.PreviousTarget = base.TranslatedJSON
+		}
+
+		if err := translate.ValidatePrevious(requestData.PreviousSource, requestData.PreviousTarget); err != nil {
+			responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+				Code: http.StatusBadRequest,
+				Msg:  fmt.Sprintf("Invalid previous_source or previous_target: %v", err),
+				Data: map[string]interface{}{},
+			})
+			return
+		}
+	}
+
+	// 合并模式校验，已有的目标语言JSON只对应一个目标语言
+	if requestData.ExistingTarget != "" {
+		if len(targetLangs) > 1 {
+			responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+				Code: http.StatusBadRequest,
+				Msg:  "Merge mode supports a single target language only.",
+				Data: map[string]interface{}{},
+			})
+			return
+		}
+
+		// 按请求的 key 的形状解析，和翻译时的合并一致
+		mergeConfig := newTranslateConfig(requestData, targetLangs[0], nil)
+		mergeConfig.ExistingTarget = requestData.ExistingTarget
+		if err := translate.ValidateExistingTarget(mergeConfig); err != nil {
+			msg := "The provided existing_target is not a valid JSON format. Please check and try again."
+			var collision *translate.KeyCollisionError
+			if errors.As(err, &collision) {
+				msg = fmt.Sprintf("Unable to convert the keys of existing_target to the %s style: %v", requestData.KeyStyle, err)
+			}
+			responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+				Code: http.StatusBadRequest,
+				Msg:  msg,
+				Data: map[string]interface{}{},
+			})
+			return
+		}
+	}
+
+	// 术语表校验，术语表的语言必须和请求的语言一致
+	glossaryTerms, status, msg := resolveGlossary(requestData, auth.GetUserIDFromContext(r), targetLangs)
+	if msg != "" {
+		responsex.RespondWithJSON(w, status, models.Response{
+			Code: status,
+			Msg:  msg,
 			Data: map[string]interface{}{},
 		})
 		return
 	}
 
-	user_info, err := users.GetUserInfo(auth.GetUserIDFromContext(r))
+	characters_used, characters_max, err := characterLimit(auth.GetUserIDFromContext(r))
 	if err != nil {
 		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
 			Code: http.StatusInternalServerError,
@@ -127,39 +293,43 @@ func CreateOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 免费用户默认是10000个字符的创建额度
-	characters_max := 10000
-
-	// 查询订阅信息
-	subscription, err := users.GetSubscription(auth.GetUserIDFromContext(r))
-	if err == nil {
-		prices, err := users.GetPrices(subscription.PriceID)
-		if err == nil {
-			metadata := prices.Metadata
-			character_limit_string, ok := metadata["character_limit"].(string)
-			if ok {
-				character_limit, _ := strconv.Atoi(character_limit_string)
-				characters_max = character_limit
-			}
-		}
-	}
-
 	// 字符统计
-	translate_config := models.Config{
-		IgnoredFields: translate.GetIgnoredFields(requestData.IgnoredFields),
+	translate_config := newTranslateConfig(requestData, targetLangs[0], glossaryTerms)
+	translate_config.PreviousSource = requestData.PreviousSource
+	translate_config.PreviousTarget = requestData.PreviousTarget
+	translate_config.ExistingTarget = requestData.ExistingTarget
+	translate_config.PruneRemoved = requestData.PruneRemoved
+	var stats *translate.JsonStats
+	if fileformat.IsJSON(requestData.FileFormat) {
+		stats, err = translate.AnalyzeJson(requestData.OriginJson, translate_config)
+	} else {
+		stats, err = fileformat.Analyze(requestData.FileFormat, requestData.OriginJson, translate_config)
 	}
-	char_total, err := translate.CountJsonChars(requestData.OriginJson, translate_config)
 	if err != nil {
+		msg := "Unable to process the JSON content. Please verify the format and try again."
+		var collision *translate.KeyCollisionError
+		switch {
+		case !fileformat.IsJSON(requestData.FileFormat):
+			msg = fmt.Sprintf("The provided content is not a valid %s file: %v", requestData.FileFormat, err)
+		case errors.As(err, &collision):
+			msg = fmt.Sprintf("Unable to convert the keys to the %s style: %v", requestData.KeyStyle, err)
+		}
 		responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
 			Code: http.StatusBadRequest,
-			Msg:  "Unable to process the JSON content. Please verify the format and try again.",
+			Msg:  msg,
 			Data: map[string]interface{}{},
 		})
 		return
 	}
 
-	// 配额检查
-	if char_total+int(user_info.CharactersUsedThisMonth) > int(characters_max) {
+	char_total := stats.Chars
+
+	// 配额检查，多个目标语言合并计算字符数，伪本地化不计费
+	billed := 0
+	for _, toLang := range targetLangs {
+		billed += billableChars(char_total, toLang)
+	}
+	if billed+characters_used > characters_max {
 		responsex.RespondWithJSON(w, http.StatusTooManyRequests, models.Response{
 			Code: http.StatusTooManyRequests,
 			Msg:  "Monthly translation quota exceeded. Please upgrade your plan or wait until the next billing cycle. Contact support for immediate assistance.",
@@ -168,20 +338,15 @@ func CreateOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	doc_id := uuid.New().String()
-	userData := map[string]interface{}{
-		"id":              doc_id,
-		"userid":          auth.GetUserIDFromContext(r),
-		"origin_json":     requestData.OriginJson,
-		"translated_json": "",
-		"from_lang":       requestData.FromLang,
-		"to_lang":         requestData.ToLang,
-		"char_total":      char_total,
-		"create_time":     time.Now().UTC().Format(time.RFC3339),
-		"update_time":     time.Now().UTC().Format(time.RFC3339),
-		"ignored_fields":  requestData.IgnoredFields,
+	if len(requestData.ToLangs) > 0 {
+		createJob(w, r, requestData, targetLangs, stats, char_total)
+		return
 	}
 
+	char_total = billableChars(char_total, requestData.ToLang)
+	doc_id := uuid.New().String()
+	userData := newUserJsonRow(auth.GetUserIDFromContext(r), doc_id, requestData, requestData.ToLang, char_total, stats.Skipped)
+
 	jsonData, err := json.Marshal(userData)
 	if err != nil {
 		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
@@ -241,11 +406,19 @@ func CreateOne(w http.ResponseWriter, r *http.Request) {
 	log.Printf("enqueued task: id=%s queue=%s", info.ID, info.Queue)
 
 	type CreateSingleData struct {
-		Id string `json:"id"`
+		Id           string                `json:"id"`
+		SkippedPaths []models.SkippedValue `json:"skipped_paths"`
+		CharTotal    int                   `json:"char_total"`
+		ReusedCount  int                   `json:"reused_count"`           // 增量翻译时沿用上一次译文的字符串个数
+		MergeReport  *models.MergeReport   `json:"merge_report,omitempty"` // 合并模式下新翻译、保留和删除的 key 路径
 	}
 
 	responseData := CreateSingleData{
-		Id: doc_id,
+		Id:           doc_id,
+		SkippedPaths: stats.Skipped,
+		CharTotal:    char_total,
+		ReusedCount:  stats.Reused,
+		MergeReport:  stats.Merge,
 	}
 
 	responsex.RespondWithJSON(w, http.StatusCreated, models.Response{
@@ -255,6 +428,385 @@ func CreateOne(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// validateOptions 校验文件格式、翻译模式、路径规则、占位符类型、格式规则和自动跳过规则，返回给用户的提示，校验通过时为空
+func validateOptions(requestData UserJsonDataRequest) string {
+	if !fileformat.IsSupported(requestData.FileFormat) {
+		return "Invalid file_format. Supported values are json, jsonc, json5, yaml, properties, po, android, strings, stringsdict, arb and xliff."
+	}
+
+	// 增量翻译和合并模式按 JSON 的 key 路径对比，只支持 JSON
+	if !fileformat.IsJSON(requestData.FileFormat) && (requestData.BaseId != "" || requestData.PreviousSource != "" || requestData.PreviousTarget != "" || requestData.ExistingTarget != "") {
+		return "Incremental translation and merge mode support JSON content only."
+	}
+
+	if err := translate.ValidateKeyStyle(requestData.KeyStyle, requestData.KeySeparator); err != nil {
+		return fmt.Sprintf("Invalid key_style or key_separator: %v. Supported key styles are flat and nested.", err)
+	}
+	if !fileformat.IsJSON(requestData.FileFormat) && requestData.KeyStyle != "" {
+		return "The key_style option supports JSON content only."
+	}
+
+	if err := translate.ValidateIndent(requestData.Indent); err != nil {
+		return fmt.Sprintf("Invalid indent: %v.", err)
+	}
+	// 其他格式按原文写回，不重新排版
+	if !fileformat.IsJSON(requestData.FileFormat) && (requestData.Indent != "" || requestData.SortKeys) {
+		return "The indent and sort_keys options support JSON content only."
+	}
+
+	if requestData.Mode != "" && requestData.Mode != models.TranslationModeLenient && requestData.Mode != models.TranslationModeStrict {
+		return "Invalid mode. Supported values are lenient and strict."
+	}
+
+	if requestData.PseudoExpansion != nil && (*requestData.PseudoExpansion < 0 || *requestData.PseudoExpansion > translate.MaxPseudoExpansion) {
+		return fmt.Sprintf("Invalid pseudo_expansion. Please use a percentage between 0 and %d.", translate.MaxPseudoExpansion)
+	}
+
+	ignoredFields := translate.GetIgnoredFields(requestData.IgnoredFields)
+	includedFields := translate.GetIncludedFields(requestData.IncludedFields)
+	if err := translate.ValidateFieldRules(append(ignoredFields, includedFields...)); err != nil {
+		return fmt.Sprintf("Invalid ignored_fields or included_fields: %v", err)
+	}
+
+	if err := translate.ValidatePlaceholders(translate.GetPlaceholders(requestData.Placeholders)); err != nil {
+		return fmt.Sprintf("Invalid placeholders: %v", err)
+	}
+
+	if err := translate.ValidateFormats(translate.GetFormats(requestData.Formats)); err != nil {
+		return fmt.Sprintf("Invalid formats: %v", err)
+	}
+
+	disabledDetectors := translate.GetDisabledDetectors(requestData.DisabledDetectors)
+	if err := translate.ValidateSkipRules(disabledDetectors, requestData.SkipPatterns); err != nil {
+		return fmt.Sprintf("Invalid disabled_detectors or skip_patterns: %v", err)
+	}
+	return ""
+}
+
+// resolveGlossary 获取请求使用的术语表中的术语，术语表需要属于当前用户并且适用于每个目标语言，
+// 获取失败时返回响应的状态码和提示
+func resolveGlossary(requestData UserJsonDataRequest, userid string, targetLangs []string) ([]models.GlossaryTerm, int, string) {
+	if requestData.GlossaryId == "" {
+		return nil, 0, ""
+	}
+
+	userGlossary, err := glossary.Get(requestData.GlossaryId, userid)
+	if err != nil {
+		log.Printf("failed to fetch glossary: id=%s, error=%v", requestData.GlossaryId, err)
+		return nil, http.StatusInternalServerError, "Internal server error. Please try again later."
+	}
+	if userGlossary == nil {
+		return nil, http.StatusNotFound, "The specified glossary was not found."
+	}
+	for _, toLang := range targetLangs {
+		if !glossary.Applies(userGlossary, requestData.FromLang, toLang) {
+			return nil, http.StatusBadRequest, fmt.Sprintf("The glossary does not apply to %s -> %s.", requestData.FromLang, toLang)
+		}
+	}
+	return userGlossary.Terms, 0, ""
+}
+
+// characterLimit 返回用户本月已经使用的字符数和订阅计划的字符上限
+func characterLimit(userid string) (int, int, error) {
+	user_info, err := users.GetUserInfo(userid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// 免费用户默认是10000个字符的创建额度
+	characters_max := 10000
+
+	// 查询订阅信息
+	subscription, err := users.GetSubscription(userid)
+	if err == nil {
+		prices, err := users.GetPrices(subscription.PriceID)
+		if err == nil {
+			metadata := prices.Metadata
+			character_limit_string, ok := metadata["character_limit"].(string)
+			if ok {
+				character_limit, _ := strconv.Atoi(character_limit_string)
+				characters_max = character_limit
+			}
+		}
+	}
+	return int(user_info.CharactersUsedThisMonth), characters_max, nil
+}
+
+// newTranslateConfig 按请求的选项生成统计字符数使用的翻译配置
+func newTranslateConfig(requestData UserJsonDataRequest, toLang string, glossaryTerms []models.GlossaryTerm) models.Config {
+	return models.Config{
+		SourceLang:        requestData.FromLang,
+		TargetLang:        toLang,
+		IgnoredFields:     translate.GetIgnoredFields(requestData.IgnoredFields),
+		IncludedFields:    translate.GetIncludedFields(requestData.IncludedFields),
+		Placeholders:      translate.GetPlaceholders(requestData.Placeholders),
+		Formats:           translate.GetFormats(requestData.Formats),
+		DisabledDetectors: translate.GetDisabledDetectors(requestData.DisabledDetectors),
+		SkipPatterns:      requestData.SkipPatterns,
+		Glossary:          glossaryTerms,
+		KeyStyle:          requestData.KeyStyle,
+		KeySeparator:      requestData.KeySeparator,
+		Indent:            requestData.Indent,
+		SortKeys:          requestData.SortKeys,
+	}
+}
+
+// billableChars 伪本地化不调用翻译服务商，不计入字符用量
+func billableChars(chars int, toLang string) int {
+	if translate.IsPseudoLocale(toLang) {
+		return 0
+	}
+	return chars
+}
+
+// newUserJsonRow 生成 user_json_translations 的一行数据
+func newUserJsonRow(userid string, id string, req UserJsonDataRequest, toLang string, charTotal int, skipped []models.SkippedValue) map[string]interface{} {
+	row := map[string]interface{}{
+		"id":                 id,
+		"userid":             userid,
+		"origin_json":        req.OriginJson,
+		"translated_json":    "",
+		"from_lang":          req.FromLang,
+		"to_lang":            toLang,
+		"char_total":         charTotal,
+		"create_time":        time.Now().UTC().Format(time.RFC3339),
+		"update_time":        time.Now().UTC().Format(time.RFC3339),
+		"ignored_fields":     req.IgnoredFields,
+		"included_fields":    req.IncludedFields,
+		"placeholders":       req.Placeholders,
+		"formats":            req.Formats,
+		"disabled_detectors": req.DisabledDetectors,
+		"skip_patterns":      req.SkipPatterns,
+		"skipped_paths":      skipped,
+		"disable_memory":     req.DisableMemory,
+		"previous_source":    req.PreviousSource,
+		"previous_target":    req.PreviousTarget,
+		"existing_target":    req.ExistingTarget,
+		"prune_removed":      req.PruneRemoved,
+		"description":        req.Description,
+		"translation_mode":   models.TranslationModeLenient,
+		"file_format":        fileformat.JSON,
+		"comment_context":    req.CommentContext,
+		"key_style":          req.KeyStyle,
+		"key_separator":      req.KeySeparator,
+		"indent":             req.Indent,
+		"sort_keys":          req.SortKeys,
+	}
+	if req.FileFormat != "" {
+		row["file_format"] = req.FileFormat
+	}
+	if req.Mode != "" {
+		row["translation_mode"] = req.Mode
+	}
+	if req.PseudoExpansion != nil {
+		row["pseudo_expansion"] = *req.PseudoExpansion
+	}
+	if req.BaseId != "" {
+		row["base_id"] = req.BaseId
+	}
+	if req.GlossaryId != "" {
+		row["glossary_id"] = req.GlossaryId
+	}
+	return row
+}
+
+// createJob 创建多语言翻译任务，每个目标语言一行翻译数据和一个翻译任务
+func createJob(w http.ResponseWriter, r *http.Request, requestData UserJsonDataRequest, targetLangs []string, stats *translate.JsonStats, charTotal int) {
+	userid := auth.GetUserIDFromContext(r)
+	webhookMode := requestData.WebhookMode
+	if webhookMode == "" {
+		webhookMode = tasks.WebhookModeAll
+	}
+
+	// 伪本地化的语言不计费
+	jobChars := 0
+	for _, toLang := range targetLangs {
+		jobChars += billableChars(charTotal, toLang)
+	}
+
+	job_id := uuid.New().String()
+	jobData := map[string]interface{}{
+		"id":           job_id,
+		"userid":       userid,
+		"from_lang":    requestData.FromLang,
+		"to_langs":     targetLangs,
+		"char_total":   jobChars,
+		"webhook_mode": webhookMode,
+		"webhook_sent": false,
+		"create_time":  time.Now().UTC().Format(time.RFC3339),
+		"update_time":  time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := insertRows("user_json_translation_jobs", jobData); err != nil {
+		log.Printf("failed to create translation job: %v", err)
+		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "Unable to create translation job. Please try again later.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	rows := make([]map[string]interface{}, 0, len(targetLangs))
+	translations := make([]models.JobTranslation, 0, len(targetLangs))
+	for _, toLang := range targetLangs {
+		doc_id := uuid.New().String()
+		row := newUserJsonRow(userid, doc_id, requestData, toLang, billableChars(charTotal, toLang), stats.Skipped)
+		row["job_id"] = job_id
+		rows = append(rows, row)
+		translations = append(translations, models.JobTranslation{
+			Id:     doc_id,
+			ToLang: toLang,
+			Status: models.JobStatusPending,
+		})
+	}
+	if err := insertRows("user_json_translations", rows); err != nil {
+		log.Printf("failed to create job translations: %v", err)
+		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "Unable to create translation record. Please try again later.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	for _, translation := range translations {
+		task, err := tasks.NewTranslateCreateTask(userid, translation.Id, billableChars(charTotal, translation.ToLang))
+		if err == nil {
+			var info *asynq.TaskInfo
+			info, err = tasks.AsynqClient.Enqueue(task)
+			if err == nil {
+				log.Printf("enqueued task: id=%s queue=%s job=%s", info.ID, info.Queue, job_id)
+				continue
+			}
+		}
+		log.Printf("could not enqueue task: job=%s, id=%s, error=%v", job_id, translation.Id, err)
+		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "Unable to queue translation tasks. Please try again later.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	type CreateJobData struct {
+		JobID        string                  `json:"job_id"`
+		Status       string                  `json:"status"`
+		Translations []models.JobTranslation `json:"translations"`
+		SkippedPaths []models.SkippedValue   `json:"skipped_paths"`
+	}
+
+	responsex.RespondWithJSON(w, http.StatusCreated, models.Response{
+		Code: http.StatusCreated,
+		Msg:  "Translation job created successfully",
+		Data: CreateJobData{
+			JobID:        job_id,
+			Status:       models.JobStatusPending,
+			Translations: translations,
+			SkippedPaths: stats.Skipped,
+		},
+	})
+}
+
+// fetchBaseTranslation 获取增量翻译的基础翻译，需要属于当前用户、已翻译完成且语言一致，
+// 获取失败时返回 nil 以及响应的状态码和提示
+func fetchBaseTranslation(id string, userid string, fromLang string, toLang string) (*tables.UserJsonData, int, string) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, http.StatusBadRequest, "Invalid base_id format"
+	}
+
+	rows, err := fetchTranslations(id, userid, "*")
+	if err != nil {
+		log.Printf("failed to fetch base translation: id=%s, error=%v", id, err)
+		return nil, http.StatusInternalServerError, "Failed to fetch data from the database."
+	}
+
+	if len(rows) == 0 {
+		return nil, http.StatusNotFound, "Base translation not found."
+	}
+
+	base := &rows[0]
+	if base.OriginFile != "" {
+		return nil, http.StatusBadRequest, "Uploaded file translations cannot be used as a base translation."
+	}
+	if !fileformat.IsJSON(base.FileFormat) {
+		return nil, http.StatusBadRequest, "Only JSON translations can be used as a base translation."
+	}
+	if base.TranslatedJSON == "" {
+		return nil, http.StatusBadRequest, "Base translation has not been translated yet."
+	}
+	if base.FromLang != fromLang || base.ToLang != toLang {
+		return nil, http.StatusBadRequest, "Base translation must use the same source and target languages."
+	}
+	return base, 0, ""
+}
+
+// fetchTranslations 按 id 查询当前用户的翻译数据，select 为需要的字段，不存在时返回空
+func fetchTranslations(id string, userid string, selectFields string) ([]tables.UserJsonData, error) {
+	queryParams := url.Values{}
+	queryParams.Add("select", selectFields)
+	queryParams.Add("id", "eq."+id)
+	queryParams.Add("userid", "eq."+userid)
+	queryParams.Add("limit", "1")
+	fullURL := fmt.Sprintf("%s/rest/v1/user_json_translations?%s", config.Cfg.Supabase.SupabaseUrl, queryParams.Encode())
+
+	req, err := http.NewRequest("GET", fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("apikey", config.Cfg.Supabase.SupabaseSecretKey)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.Cfg.Supabase.SupabaseSecretKey))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("supabase request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("supabase response error: %s, %s", resp.Status, string(bodyBytes))
+	}
+
+	var rows []tables.UserJsonData
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to decode rows: %v", err)
+	}
+	return rows, nil
+}
+
+// insertRows 向 Supabase 表插入一行或多行数据
+func insertRows(table string, data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rows: %v", err)
+	}
+
+	supabaseURL := fmt.Sprintf("%s/rest/v1/%s", config.Cfg.Supabase.SupabaseUrl, table)
+	req, err := http.NewRequest("POST", supabaseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("apikey", config.Cfg.Supabase.SupabaseSecretKey)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.Cfg.Supabase.SupabaseSecretKey))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=minimal")
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("supabase request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("supabase response error: %s, %s", resp.Status, string(bodyBytes))
+	}
+	return nil
+}
+
 func CreateBatch(w http.ResponseWriter, r *http.Request) {
 	var batchRequest BatchTranslationRequest
 	err := json.NewDecoder(r.Body).Decode(&batchRequest)
@@ -285,12 +837,12 @@ func CreateBatch(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if !config.IsLanguageSupported(req.FromLang) {
+		if !translateapi.IsLanguageSupported(req.FromLang) {
 			writeBatchResponse(w, http.StatusBadRequest, "Unsupported source language", nil)
 			return
 		}
 
-		if !config.IsLanguageSupported(req.ToLang) {
+		if !translateapi.IsLanguageSupported(req.ToLang) {
 			writeBatchResponse(w, http.StatusBadRequest, "Unsupported target language", nil)
 			return
 		}
@@ -299,6 +851,26 @@ func CreateBatch(w http.ResponseWriter, r *http.Request) {
 			writeBatchResponse(w, http.StatusBadRequest, "Invalid JSON String.", nil)
 			return
 		}
+
+		if err := translate.ValidateFieldRules(append(translate.GetIgnoredFields(req.IgnoredFields), translate.GetIncludedFields(req.IncludedFields)...)); err != nil {
+			writeBatchResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid ignored_fields or included_fields: %v", err), nil)
+			return
+		}
+
+		if err := translate.ValidatePlaceholders(translate.GetPlaceholders(req.Placeholders)); err != nil {
+			writeBatchResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid placeholders: %v", err), nil)
+			return
+		}
+
+		if err := translate.ValidateFormats(translate.GetFormats(req.Formats)); err != nil {
+			writeBatchResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid formats: %v", err), nil)
+			return
+		}
+
+		if err := translate.ValidateSkipRules(translate.GetDisabledDetectors(req.DisabledDetectors), req.SkipPatterns); err != nil {
+			writeBatchResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid disabled_detectors or skip_patterns: %v", err), nil)
+			return
+		}
 	}
 
 	// 创建 Asynq 客户端
@@ -312,22 +884,39 @@ func CreateBatch(w http.ResponseWriter, r *http.Request) {
 
 	for _, req := range batchRequest.Requests {
 		translate_config := models.Config{
-			IgnoredFields: translate.GetIgnoredFields(req.IgnoredFields),
+			SourceLang:        req.FromLang,
+			TargetLang:        req.ToLang,
+			IgnoredFields:     translate.GetIgnoredFields(req.IgnoredFields),
+			IncludedFields:    translate.GetIncludedFields(req.IncludedFields),
+			Placeholders:      translate.GetPlaceholders(req.Placeholders),
+			Formats:           translate.GetFormats(req.Formats),
+			DisabledDetectors: translate.GetDisabledDetectors(req.DisabledDetectors),
+			SkipPatterns:      req.SkipPatterns,
 		}
-		char_total, err := translate.CountJsonChars(req.OriginJson, translate_config)
+		stats, err := translate.AnalyzeJson(req.OriginJson, translate_config)
 		if err != nil {
 			writeBatchResponse(w, http.StatusInternalServerError, "Invalid JSON String.", nil)
 			return
 		}
+		char_total := stats.Chars
 
 		doc_id := uuid.New().String()
 		userData := map[string]interface{}{
-			"id":              doc_id,
-			"userid":          auth.GetUserIDFromContext(r),
-			"origin_json":     req.OriginJson,
-			"translated_json": "",
-			"from_lang":       req.FromLang,
-			"to_lang":         req.ToLang,
+			"id":                 doc_id,
+			"userid":             auth.GetUserIDFromContext(r),
+			"origin_json":        req.OriginJson,
+			"translated_json":    "",
+			"from_lang":          req.FromLang,
+			"to_lang":            req.ToLang,
+			"char_total":         char_total,
+			"ignored_fields":     req.IgnoredFields,
+			"included_fields":    req.IncludedFields,
+			"placeholders":       req.Placeholders,
+			"formats":            req.Formats,
+			"disabled_detectors": req.DisabledDetectors,
+			"skip_patterns":      req.SkipPatterns,
+			"skipped_paths":      stats.Skipped,
+			"disable_memory":     req.DisableMemory,
 		}
 
 		jsonData, err := json.Marshal(userData)
@@ -9,6 +9,7 @@ import (
 	"json_trans_api/models/tables"
 	"json_trans_api/pkg/httpclient"
 	responsex "json_trans_api/pkg/response"
+	"json_trans_api/pkg/tasks"
 	"json_trans_api/service/api/middleware/auth"
 	"log"
 	"net/http"
@@ -281,3 +282,53 @@ func GetListData(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// GetJobById 获取多语言翻译任务的整体状态和每个目标语言的状态
+func GetJobById(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if _, err := uuid.Parse(id); err != nil {
+		responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+			Code: http.StatusBadRequest,
+			Msg:  "Invalid ID format",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	job, err := tasks.FetchJobById(id, auth.GetUserIDFromContext(r))
+	if err != nil {
+		log.Printf("failed to fetch translation job: %v", err)
+		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "Failed to fetch data from the database.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	if job == nil {
+		responsex.RespondWithJSON(w, http.StatusNotFound, models.Response{
+			Code: http.StatusNotFound,
+			Msg:  "Translation job not found.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	status, err := tasks.GetJobStatus(job, r.URL.Query().Get("with_content") == "true")
+	if err != nil {
+		log.Printf("failed to get translation job status: %v", err)
+		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "Failed to fetch data from the database.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	responsex.RespondWithJSON(w, http.StatusOK, models.Response{
+		Code: http.StatusOK,
+		Msg:  "Success",
+		Data: status,
+	})
+}
@@ -2,7 +2,6 @@ package json
 
 import (
 	"encoding/json"
-	"json_trans_api/config"
 	"json_trans_api/models/models"
 	responsex "json_trans_api/pkg/response"
 	"json_trans_api/utils/translateapi"
@@ -24,8 +23,9 @@ type DetectLanguageData struct {
 }
 
 func GetSupportedLanguages(w http.ResponseWriter, r *http.Request) {
-	languages := make([]Language, len(config.SupportedLanguagesAli))
-	for i, lang := range config.SupportedLanguagesAli {
+	supportedLanguages := translateapi.SupportedLanguages()
+	languages := make([]Language, len(supportedLanguages))
+	for i, lang := range supportedLanguages {
 		languages[i] = Language{
 			Code: lang.Code,
 			Name: lang.Name,
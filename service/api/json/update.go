@@ -12,6 +12,7 @@ import (
 	responsex "json_trans_api/pkg/response"
 	"json_trans_api/pkg/tasks"
 	"json_trans_api/service/api/middleware/auth"
+	"json_trans_api/utils/translateapi"
 	"log"
 	"net/http"
 	"net/url"
@@ -91,7 +92,7 @@ func UpdateById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 语言支持校验
-	if !config.IsLanguageSupported(updateRequest.Translation.FromLang) {
+	if !translateapi.IsLanguageSupported(updateRequest.Translation.FromLang) {
 		responsex.RespondWithJSON(w, http.StatusNotFound, models.Response{
 			Code: http.StatusOK,
 			Msg:  "Unsupported source language",
@@ -100,7 +101,7 @@ func UpdateById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !config.IsLanguageSupported(updateRequest.Translation.FromLang) {
+	if !translateapi.IsLanguageSupported(updateRequest.Translation.FromLang) {
 		responsex.RespondWithJSON(w, http.StatusNotFound, models.Response{
 			Code: http.StatusOK,
 			Msg:  "Unsupported target language",
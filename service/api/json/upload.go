@@ -0,0 +1,353 @@
+package json
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"json_trans_api/models/models"
+	"json_trans_api/pkg/fileformat"
+	responsex "json_trans_api/pkg/response"
+	"json_trans_api/pkg/storage"
+	"json_trans_api/pkg/tasks"
+	"json_trans_api/pkg/translate"
+	"json_trans_api/service/api/middleware/auth"
+	"json_trans_api/utils/translateapi"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const (
+	maxUploadSize    = 64 << 20 // 上传的文件最大 64MB
+	maxFormFieldSize = 64 << 10 // 文件之外每个表单字段的最大长度
+)
+
+// CreateFromFile 上传文件创建翻译，用于几十 MB 的大文件。
+// 表单字段和 JSON 请求的字段名一致，需要放在 file 字段之前；没有 file_format 字段时按文件名推断格式。
+// JSON 文件边上传到 Storage 边统计字符数，翻译时流式读写，不把整个文件读入内存；
+// 其他格式需要整个文件解析后再写回。只支持单个目标语言，不支持增量翻译和合并模式
+func CreateFromFile(w http.ResponseWriter, r *http.Request) {
+	userid := auth.GetUserIDFromContext(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		respondUploadError(w, http.StatusBadRequest, "Please upload the JSON file as multipart/form-data.")
+		return
+	}
+
+	requestData, file, msg := readUploadForm(reader)
+	if msg != "" {
+		respondUploadError(w, http.StatusBadRequest, msg)
+		return
+	}
+	defer file.Close()
+
+	if msg := validateUpload(requestData); msg != "" {
+		respondUploadError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	glossaryTerms, status, msg := resolveGlossary(requestData, userid, []string{requestData.ToLang})
+	if msg != "" {
+		respondUploadError(w, status, msg)
+		return
+	}
+
+	characters_used, characters_max, err := characterLimit(userid)
+	if err != nil {
+		respondUploadError(w, http.StatusInternalServerError, "Unable to verify your usage limits. Please try again later.")
+		return
+	}
+
+	doc_id := uuid.New().String()
+	originPath := storage.OriginPath(userid, doc_id)
+	translate_config := newTranslateConfig(requestData, requestData.ToLang, glossaryTerms)
+	var stats *translate.JsonStats
+	if fileformat.IsJSON(requestData.FileFormat) {
+		stats, status, msg = uploadAndAnalyze(r.Context(), originPath, file, translate_config)
+	} else {
+		stats, status, msg = uploadDocument(r.Context(), originPath, file, requestData.FileFormat, translate_config)
+	}
+	if msg != "" {
+		respondUploadError(w, status, msg)
+		return
+	}
+	char_total := billableChars(stats.Chars, requestData.ToLang)
+
+	// 配额检查，伪本地化不计费
+	if char_total+characters_used > characters_max {
+		removeUpload(originPath)
+		respondUploadError(w, http.StatusTooManyRequests, "Monthly translation quota exceeded. Please upgrade your plan or wait until the next billing cycle. Contact support for immediate assistance.")
+		return
+	}
+
+	row := newUserJsonRow(userid, doc_id, requestData, requestData.ToLang, char_total, stats.Skipped)
+	row["origin_file"] = originPath
+	if err := insertRows("user_json_translations", row); err != nil {
+		log.Printf("failed to create file translation: %v", err)
+		removeUpload(originPath)
+		respondUploadError(w, http.StatusInternalServerError, "Unable to create translation record. Please try again later.")
+		return
+	}
+
+	task, err := tasks.NewTranslateCreateTask(userid, doc_id, char_total)
+	if err == nil {
+		info, enqueueErr := tasks.AsynqClient.Enqueue(task)
+		if err = enqueueErr; err == nil {
+			log.Printf("enqueued task: id=%s queue=%s", info.ID, info.Queue)
+		}
+	}
+	if err != nil {
+		log.Printf("could not enqueue task: id=%s, error=%v", doc_id, err)
+		respondUploadError(w, http.StatusInternalServerError, "Unable to queue translation task. Please try again later.")
+		return
+	}
+
+	type CreateFileData struct {
+		Id           string                `json:"id"`
+		SkippedPaths []models.SkippedValue `json:"skipped_paths"`
+		CharTotal    int                   `json:"char_total"`
+	}
+
+	responsex.RespondWithJSON(w, http.StatusCreated, models.Response{
+		Code: http.StatusCreated,
+		Msg:  "Translation request created successfully",
+		Data: CreateFileData{
+			Id:           doc_id,
+			SkippedPaths: stats.Skipped,
+			CharTotal:    char_total,
+		},
+	})
+}
+
+// DownloadById 下载译文，上传文件翻译的译文从 Storage 流式读取
+func DownloadById(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if _, err := uuid.Parse(id); err != nil {
+		respondUploadError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	rows, err := fetchTranslations(id, auth.GetUserIDFromContext(r), "id,translated_json,translated_file,file_format")
+	if err != nil {
+		log.Printf("failed to fetch translation: id=%s, error=%v", id, err)
+		respondUploadError(w, http.StatusInternalServerError, "Failed to fetch data from the database.")
+		return
+	}
+	if len(rows) == 0 {
+		respondUploadError(w, http.StatusNotFound, "Translation document not found.")
+		return
+	}
+	if rows[0].TranslatedFile == "" && rows[0].TranslatedJSON == "" {
+		respondUploadError(w, http.StatusConflict, "The translation has not been completed yet.")
+		return
+	}
+
+	// 译文和原文的格式相同
+	w.Header().Set("Content-Type", fileformat.ContentType(rows[0].FileFormat))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+fileformat.Extension(rows[0].FileFormat)))
+	if rows[0].TranslatedFile == "" {
+		io.WriteString(w, rows[0].TranslatedJSON)
+		return
+	}
+
+	body, err := storage.Download(r.Context(), rows[0].TranslatedFile)
+	if err != nil {
+		log.Printf("failed to download translated file: id=%s, error=%v", id, err)
+		w.Header().Del("Content-Disposition")
+		respondUploadError(w, http.StatusInternalServerError, "Unable to download the translated file. Please try again later.")
+		return
+	}
+	defer body.Close()
+
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("failed to send translated file: id=%s, error=%v", id, err)
+	}
+}
+
+// readUploadForm 读取 file 之前的表单字段，返回请求的选项和文件。文件之后的字段会被忽略，
+// 没有指定 file_format 时按文件名推断，无法推断时按 JSON 处理
+func readUploadForm(reader *multipart.Reader) (UserJsonDataRequest, *multipart.Part, string) {
+	var requestData UserJsonDataRequest
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return requestData, nil, "Please upload the JSON file in the file field after the other form fields."
+		}
+		if err != nil {
+			return requestData, nil, "Invalid upload. Please provide a JSON file no larger than 64MB."
+		}
+		if part.FormName() == "file" {
+			if requestData.FileFormat == "" {
+				requestData.FileFormat = fileformat.Detect(part.FileName())
+			}
+			return requestData, part, ""
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+		part.Close()
+		if err != nil || len(value) > maxFormFieldSize {
+			return requestData, nil, fmt.Sprintf("The %s field is too large.", part.FormName())
+		}
+		if msg := setUploadField(&requestData, part.FormName(), string(value)); msg != "" {
+			return requestData, nil, msg
+		}
+	}
+}
+
+// setUploadField 按字段名设置请求的选项，skip_patterns 可以出现多次
+func setUploadField(requestData *UserJsonDataRequest, name string, value string) string {
+	switch name {
+	case "from_lang":
+		requestData.FromLang = value
+	case "to_lang":
+		requestData.ToLang = value
+	case "file_format":
+		requestData.FileFormat = value
+	case "ignored_fields":
+		requestData.IgnoredFields = value
+	case "included_fields":
+		requestData.IncludedFields = value
+	case "placeholders":
+		requestData.Placeholders = value
+	case "formats":
+		requestData.Formats = value
+	case "disabled_detectors":
+		requestData.DisabledDetectors = value
+	case "skip_patterns":
+		requestData.SkipPatterns = append(requestData.SkipPatterns, value)
+	case "disable_memory":
+		disableMemory, err := strconv.ParseBool(value)
+		if err != nil {
+			return "Invalid disable_memory. Please use true or false."
+		}
+		requestData.DisableMemory = disableMemory
+	case "description":
+		requestData.Description = value
+	case "glossary_id":
+		requestData.GlossaryId = value
+	case "mode":
+		requestData.Mode = value
+	case "comment_context":
+		commentContext, err := strconv.ParseBool(value)
+		if err != nil {
+			return "Invalid comment_context. Please use true or false."
+		}
+		requestData.CommentContext = commentContext
+	case "pseudo_expansion":
+		expansion, err := strconv.Atoi(value)
+		if err != nil {
+			return "Invalid pseudo_expansion. Please use a whole number percentage."
+		}
+		requestData.PseudoExpansion = &expansion
+	case "to_langs", "base_id", "previous_source", "previous_target", "existing_target", "prune_removed", "key_style", "key_separator", "indent", "sort_keys":
+		return fmt.Sprintf("The %s field is not supported for file uploads.", name)
+	}
+	return ""
+}
+
+// validateUpload 校验上传文件翻译的语言和选项，返回给用户的提示，校验通过时为空
+func validateUpload(requestData UserJsonDataRequest) string {
+	if requestData.FromLang == "" {
+		return "Please specify the source language."
+	}
+	if requestData.ToLang == "" {
+		return "Please specify the target language."
+	}
+	if requestData.FromLang == requestData.ToLang {
+		return "Source and target languages must be different. Please choose a different target language."
+	}
+	if utf8.RuneCountInString(requestData.Description) > maxDescriptionLength {
+		return fmt.Sprintf("The description must not exceed %d characters.", maxDescriptionLength)
+	}
+	if !translateapi.IsLanguageSupported(requestData.FromLang) {
+		return "The specified source language is not supported. Please check our documentation for supported languages."
+	}
+	if !translateapi.IsLanguageSupported(requestData.ToLang) && !translate.IsPseudoLocale(requestData.ToLang) {
+		return fmt.Sprintf("The specified target language %s is not supported. Please check our documentation for supported languages.", requestData.ToLang)
+	}
+	return validateOptions(requestData)
+}
+
+// uploadAndAnalyze 把文件上传到 Storage 的同时统计需要翻译的字符数，文件只读取一次。
+// 失败时删除已经上传的文件，返回响应的状态码和提示
+func uploadAndAnalyze(ctx context.Context, path string, file io.Reader, config models.Config) (*translate.JsonStats, int, string) {
+	var uploadFailed atomic.Bool
+	pr, pw := io.Pipe()
+	uploaded := make(chan error, 1)
+	go func() {
+		err := storage.Upload(ctx, path, pr, "application/json")
+		if err != nil {
+			uploadFailed.Store(true)
+		}
+		pr.CloseWithError(err)
+		uploaded <- err
+	}()
+
+	stats, err := translate.AnalyzeStream(io.TeeReader(file, pw), config)
+	// 上传失败时写入管道会出错，统计也会失败，这时以上传的错误为准
+	uploadBroken := uploadFailed.Load()
+	pw.CloseWithError(err)
+	uploadErr := <-uploaded
+
+	if uploadBroken || (err == nil && uploadErr != nil) {
+		log.Printf("failed to upload file: path=%s, error=%v", path, uploadErr)
+		removeUpload(path)
+		return nil, http.StatusInternalServerError, "Unable to save the uploaded file. Please try again later."
+	}
+	if err != nil {
+		removeUpload(path)
+		if strings.Contains(err.Error(), "http: request body too large") {
+			return nil, http.StatusRequestEntityTooLarge, "The uploaded file must not exceed 64MB."
+		}
+		return nil, http.StatusBadRequest, fmt.Sprintf("The uploaded file is not a valid JSON document: %v", err)
+	}
+	return stats, 0, ""
+}
+
+// uploadDocument 读取 JSON 之外格式的整个文件，解析并统计字符数后上传到 Storage，返回响应的状态码和提示
+func uploadDocument(ctx context.Context, path string, file io.Reader, format string, config models.Config) (*translate.JsonStats, int, string) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		if strings.Contains(err.Error(), "http: request body too large") {
+			return nil, http.StatusRequestEntityTooLarge, "The uploaded file must not exceed 64MB."
+		}
+		return nil, http.StatusBadRequest, "Invalid upload. Please provide a file no larger than 64MB."
+	}
+
+	stats, err := fileformat.Analyze(format, string(content), config)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Sprintf("The uploaded file is not a valid %s file: %v", format, err)
+	}
+
+	if err := storage.Upload(ctx, path, bytes.NewReader(content), fileformat.ContentType(format)); err != nil {
+		log.Printf("failed to upload file: path=%s, error=%v", path, err)
+		removeUpload(path)
+		return nil, http.StatusInternalServerError, "Unable to save the uploaded file. Please try again later."
+	}
+	return stats, 0, ""
+}
+
+// removeUpload 删除创建失败的请求已经上传的文件，请求可能已经取消，不使用请求的 context
+func removeUpload(path string) {
+	if err := storage.Remove(context.Background(), path); err != nil {
+		log.Printf("failed to remove uploaded file: path=%s, error=%v", path, err)
+	}
+}
+
+func respondUploadError(w http.ResponseWriter, status int, msg string) {
+	responsex.RespondWithJSON(w, status, models.Response{
+		Code: status,
+		Msg:  msg,
+		Data: map[string]interface{}{},
+	})
+}
@@ -0,0 +1,171 @@
+package json
+
+import (
+	"fmt"
+	"json_trans_api/models/models"
+	"json_trans_api/models/tables"
+	"json_trans_api/pkg/fileformat"
+	responsex "json_trans_api/pkg/response"
+	"json_trans_api/pkg/translate"
+	"json_trans_api/pkg/xliff"
+	"json_trans_api/service/api/middleware/auth"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// ExportXliff 把翻译完成的记录导出为 XLIFF 交给译员审校，query 参数 version 为 1.2(默认) 或 2.0。
+// unit 的 id 是字符串的 key 路径，占位符写在 note 中，忽略的字段和自动跳过的字符串不导出
+func ExportXliff(w http.ResponseWriter, r *http.Request) {
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		version = xliff.Version12
+	}
+	if !xliff.IsSupportedVersion(version) {
+		respondUploadError(w, http.StatusBadRequest, "Invalid version. Supported values are 1.2 and 2.0.")
+		return
+	}
+
+	row, status, msg := fetchReviewTranslation(r)
+	if row == nil {
+		respondUploadError(w, status, msg)
+		return
+	}
+
+	units, err := translate.ReviewUnits(row.OriginJSON, row.TranslatedJSON, reviewConfig(row))
+	if err != nil {
+		log.Printf("failed to collect review units: id=%s, error=%v", row.Id, err)
+		respondUploadError(w, http.StatusInternalServerError, "Unable to export the translation. Please try again later.")
+		return
+	}
+
+	doc := xliff.Document{
+		Version:    version,
+		SourceLang: row.FromLang,
+		TargetLang: row.ToLang,
+		Original:   row.Id + ".json",
+	}
+	for _, unit := range units {
+		doc.Units = append(doc.Units, xliff.Unit{Id: unit.Path, Source: unit.Source, Target: unit.Target, Placeholders: unit.Placeholders})
+	}
+	data, err := xliff.Encode(doc)
+	if err != nil {
+		log.Printf("failed to encode xliff: id=%s, error=%v", row.Id, err)
+		respondUploadError(w, http.StatusInternalServerError, "Unable to export the translation. Please try again later.")
+		return
+	}
+
+	w.Header().Set("Content-Type", xliff.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", row.Id+".xlf"))
+	w.Write(data)
+}
+
+// ImportXliff 导入审校后的 XLIFF，表单字段 file。按 unit 的 id 把译文写回 translated_json，
+// 原文中不存在的 id 和占位符被修改的译文不导入，和导入的结果一起返回
+func ImportXliff(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxFormFieldSize); err != nil {
+		respondUploadError(w, http.StatusBadRequest, "Invalid upload. Please provide an XLIFF file no larger than 64MB.")
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		respondUploadError(w, http.StatusBadRequest, "Please upload the XLIFF file in the file field.")
+		return
+	}
+	defer file.Close()
+
+	doc, err := xliff.Decode(file)
+	if err != nil {
+		respondUploadError(w, http.StatusBadRequest, fmt.Sprintf("Unable to parse the XLIFF file: %v", err))
+		return
+	}
+
+	row, status, msg := fetchReviewTranslation(r)
+	if row == nil {
+		respondUploadError(w, status, msg)
+		return
+	}
+	if doc.TargetLang != "" && !strings.EqualFold(doc.TargetLang, row.ToLang) {
+		respondUploadError(w, http.StatusBadRequest, fmt.Sprintf("The XLIFF target language %s does not match the translation target language %s.", doc.TargetLang, row.ToLang))
+		return
+	}
+
+	reviewed := make([]translate.ReviewUnit, 0, len(doc.Units))
+	for _, unit := range doc.Units {
+		reviewed = append(reviewed, translate.ReviewUnit{Path: unit.Id, Target: unit.Target})
+	}
+	translated, report, err := translate.ApplyReview(row.OriginJSON, row.TranslatedJSON, reviewed, reviewConfig(row))
+	if err != nil {
+		log.Printf("failed to apply review: id=%s, error=%v", row.Id, err)
+		respondUploadError(w, http.StatusInternalServerError, "Unable to import the XLIFF file. Please try again later.")
+		return
+	}
+
+	if len(report.Updated) > 0 {
+		updateData := map[string]interface{}{
+			"translated_json": translated,
+			"update_time":     time.Now().UTC().Format(time.RFC3339),
+		}
+		if _, err := performSupabaseUpdate(row.Id, auth.GetUserIDFromContext(r), updateData); err != nil {
+			log.Printf("failed to save reviewed translation: id=%s, error=%v", row.Id, err)
+			respondUploadError(w, http.StatusInternalServerError, "Unable to save the reviewed translation. Please try again later.")
+			return
+		}
+	}
+
+	responsex.RespondWithJSON(w, http.StatusOK, models.Response{
+		Code: http.StatusOK,
+		Msg:  "Review imported successfully",
+		Data: report,
+	})
+}
+
+// fetchReviewTranslation 获取可以导出和导入 XLIFF 的翻译，需要已翻译完成的 JSON 翻译，
+// 获取失败时返回 nil 以及响应的状态码和提示
+func fetchReviewTranslation(r *http.Request) (*tables.UserJsonData, int, string) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, http.StatusBadRequest, "Invalid ID format"
+	}
+
+	rows, err := fetchTranslations(id, auth.GetUserIDFromContext(r), "*")
+	if err != nil {
+		log.Printf("failed to fetch translation: id=%s, error=%v", id, err)
+		return nil, http.StatusInternalServerError, "Failed to fetch data from the database."
+	}
+	if len(rows) == 0 {
+		return nil, http.StatusNotFound, "Translation document not found."
+	}
+
+	row := &rows[0]
+	if row.OriginFile != "" || !fileformat.IsJSON(row.FileFormat) {
+		return nil, http.StatusBadRequest, "XLIFF export and import support JSON translations created from the request body only."
+	}
+	if row.TranslatedJSON == "" {
+		return nil, http.StatusConflict, "The translation has not been completed yet."
+	}
+	return row, 0, ""
+}
+
+// reviewConfig 按翻译时的选项收集需要审校的字符串，和翻译时实际翻译的字符串一致
+func reviewConfig(row *tables.UserJsonData) models.Config {
+	return models.Config{
+		SourceLang:        row.FromLang,
+		TargetLang:        row.ToLang,
+		IgnoredFields:     translate.GetIgnoredFields(row.IgnoredFields),
+		IncludedFields:    translate.GetIncludedFields(row.IncludedFields),
+		Placeholders:      translate.GetPlaceholders(row.Placeholders),
+		Formats:           translate.GetFormats(row.Formats),
+		DisabledDetectors: translate.GetDisabledDetectors(row.DisabledDetectors),
+		SkipPatterns:      row.SkipPatterns,
+		KeyStyle:          row.KeyStyle,
+		KeySeparator:      row.KeySeparator,
+		Indent:            row.Indent,
+		SortKeys:          row.SortKeys,
+	}
+}
@@ -0,0 +1,261 @@
+package glossary
+
+import (
+	"encoding/json"
+	"fmt"
+	"json_trans_api/models/models"
+	"json_trans_api/models/tables"
+	"json_trans_api/pkg/glossary"
+	responsex "json_trans_api/pkg/response"
+	"json_trans_api/service/api/middleware/auth"
+	"json_trans_api/utils/translateapi"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// 导入的术语文件最大 5MB
+const maxImportSize = 5 << 20
+
+type GlossaryRequest struct {
+	Name       string                `json:"name"`
+	SourceLang string                `json:"source_lang"`
+	TargetLang string                `json:"target_lang"` // 为空时适用于所有目标语言
+	Terms      []models.GlossaryTerm `json:"terms"`
+}
+
+// validate 检查语言和术语，返回给用户的错误信息
+func (req *GlossaryRequest) validate() string {
+	if req.SourceLang == "" {
+		return "Please specify the source language."
+	}
+	if !translateapi.IsLanguageSupported(req.SourceLang) {
+		return "The specified source language is not supported. Please check our documentation for supported languages."
+	}
+	if req.TargetLang != "" && !translateapi.IsLanguageSupported(req.TargetLang) {
+		return "The specified target language is not supported. Please check our documentation for supported languages."
+	}
+	if req.TargetLang != "" && strings.EqualFold(req.SourceLang, req.TargetLang) {
+		return "Source and target languages must be different. Please choose a different target language."
+	}
+
+	req.Terms = glossary.Normalize(req.Terms)
+	if err := glossary.ValidateTerms(req.Terms); err != nil {
+		return fmt.Sprintf("Invalid terms: %v", err)
+	}
+	return ""
+}
+
+// ListGlossaries 获取当前用户的术语表，不包含术语
+func ListGlossaries(w http.ResponseWriter, r *http.Request) {
+	glossaries, err := glossary.List(auth.GetUserIDFromContext(r))
+	if err != nil {
+		log.Printf("failed to list glossaries: %v", err)
+		respondInternalError(w)
+		return
+	}
+
+	responsex.RespondWithJSON(w, http.StatusOK, models.Response{
+		Code: http.StatusOK,
+		Msg:  "Success",
+		Data: glossaries,
+	})
+}
+
+// GetGlossary 获取术语表和其中的术语
+func GetGlossary(w http.ResponseWriter, r *http.Request) {
+	userGlossary, err := glossary.Get(strings.TrimSpace(chi.URLParam(r, "id")), auth.GetUserIDFromContext(r))
+	if err != nil {
+		log.Printf("failed to fetch glossary: %v", err)
+		respondInternalError(w)
+		return
+	}
+	if userGlossary == nil {
+		respondNotFound(w)
+		return
+	}
+
+	responsex.RespondWithJSON(w, http.StatusOK, models.Response{
+		Code: http.StatusOK,
+		Msg:  "Success",
+		Data: userGlossary,
+	})
+}
+
+// CreateGlossary 创建术语表
+func CreateGlossary(w http.ResponseWriter, r *http.Request) {
+	var req GlossaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondBadRequest(w, "Invalid request format. Please check your request body.")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		respondBadRequest(w, msg)
+		return
+	}
+
+	createGlossary(w, r, req)
+}
+
+// UpdateGlossary 替换术语表的名称、语言和全部术语
+func UpdateGlossary(w http.ResponseWriter, r *http.Request) {
+	var req GlossaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondBadRequest(w, "Invalid request format. Please check your request body.")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		respondBadRequest(w, msg)
+		return
+	}
+
+	userid := auth.GetUserIDFromContext(r)
+	existing, err := glossary.Get(strings.TrimSpace(chi.URLParam(r, "id")), userid)
+	if err != nil {
+		log.Printf("failed to fetch glossary: %v", err)
+		respondInternalError(w)
+		return
+	}
+	if existing == nil {
+		respondNotFound(w)
+		return
+	}
+
+	updated, err := glossary.Update(&tables.Glossary{
+		Id:         existing.Id,
+		Userid:     userid,
+		Name:       req.Name,
+		SourceLang: req.SourceLang,
+		TargetLang: req.TargetLang,
+		Terms:      req.Terms,
+	})
+	if err != nil {
+		log.Printf("failed to update glossary: %v", err)
+		respondInternalError(w)
+		return
+	}
+	if updated == nil {
+		respondNotFound(w)
+		return
+	}
+
+	responsex.RespondWithJSON(w, http.StatusOK, models.Response{
+		Code: http.StatusOK,
+		Msg:  "Success",
+		Data: updated,
+	})
+}
+
+// DeleteGlossary 删除术语表
+func DeleteGlossary(w http.ResponseWriter, r *http.Request) {
+	deleted, err := glossary.Delete(strings.TrimSpace(chi.URLParam(r, "id")), auth.GetUserIDFromContext(r))
+	if err != nil {
+		log.Printf("failed to delete glossary: %v", err)
+		respondInternalError(w)
+		return
+	}
+	if !deleted {
+		respondNotFound(w)
+		return
+	}
+
+	responsex.RespondWithJSON(w, http.StatusOK, models.Response{
+		Code: http.StatusOK,
+		Msg:  "Success",
+		Data: map[string]interface{}{},
+	})
+}
+
+// ImportGlossary 从上传的 CSV 或 TBX 文件创建术语表，表单字段 file、name、source_lang、target_lang
+func ImportGlossary(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		respondBadRequest(w, "Invalid upload. Please provide a CSV or TBX file no larger than 5MB.")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondBadRequest(w, "Please upload the glossary file in the file field.")
+		return
+	}
+	defer file.Close()
+
+	format := glossary.DetectFormat(header.Filename)
+	if format == "" {
+		respondBadRequest(w, "Unsupported file type. Please upload a .csv or .tbx file.")
+		return
+	}
+
+	req := GlossaryRequest{
+		Name:       r.FormValue("name"),
+		SourceLang: r.FormValue("source_lang"),
+		TargetLang: r.FormValue("target_lang"),
+	}
+	if req.Name == "" {
+		req.Name = strings.TrimSuffix(header.Filename, "."+format)
+	}
+
+	// TBX 按语言选取术语，需要先知道源语言
+	if req.SourceLang == "" {
+		respondBadRequest(w, "Please specify the source language.")
+		return
+	}
+	if req.Terms, err = glossary.Parse(format, file, req.SourceLang, req.TargetLang); err != nil {
+		respondBadRequest(w, fmt.Sprintf("Unable to parse the glossary file: %v", err))
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		respondBadRequest(w, msg)
+		return
+	}
+
+	createGlossary(w, r, req)
+}
+
+func createGlossary(w http.ResponseWriter, r *http.Request, req GlossaryRequest) {
+	created, err := glossary.Create(&tables.Glossary{
+		Userid:     auth.GetUserIDFromContext(r),
+		Name:       req.Name,
+		SourceLang: req.SourceLang,
+		TargetLang: req.TargetLang,
+		Terms:      req.Terms,
+	})
+	if err != nil {
+		log.Printf("failed to create glossary: %v", err)
+		respondInternalError(w)
+		return
+	}
+
+	responsex.RespondWithJSON(w, http.StatusCreated, models.Response{
+		Code: http.StatusCreated,
+		Msg:  "Glossary created successfully",
+		Data: created,
+	})
+}
+
+func respondBadRequest(w http.ResponseWriter, msg string) {
+	responsex.RespondWithJSON(w, http.StatusBadRequest, models.Response{
+		Code: http.StatusBadRequest,
+		Msg:  msg,
+		Data: map[string]interface{}{},
+	})
+}
+
+func respondNotFound(w http.ResponseWriter) {
+	responsex.RespondWithJSON(w, http.StatusNotFound, models.Response{
+		Code: http.StatusNotFound,
+		Msg:  "Glossary not found",
+		Data: map[string]interface{}{},
+	})
+}
+
+func respondInternalError(w http.ResponseWriter) {
+	responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
+		Code: http.StatusInternalServerError,
+		Msg:  "Internal server error. Please try again later.",
+		Data: map[string]interface{}{},
+	})
+}
@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"json_trans_api/models/models"
+	responsex "json_trans_api/pkg/response"
+	"json_trans_api/pkg/translate"
+	"json_trans_api/service/api/middleware/auth"
+	"log"
+	"net/http"
+)
+
+// PurgeMemory 清除当前用户的翻译记忆
+func PurgeMemory(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r)
+	if err := translate.PurgeMemory(r.Context(), userID); err != nil {
+		log.Printf("failed to purge translation memory: userid=%s, error=%v", userID, err)
+		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "Failed to purge translation memory. Please try again later.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	responsex.RespondWithJSON(w, http.StatusOK, models.Response{
+		Code: http.StatusOK,
+		Msg:  "Translation memory purged successfully",
+		Data: map[string]interface{}{},
+	})
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"json_trans_api/pkg/rds"
+	responsex "json_trans_api/pkg/response"
+	"json_trans_api/service/api/middleware/auth"
+	"json_trans_api/utils/translateapi"
+	"log"
+	"net/http"
+)
+
+// GetUsage 查询所有 worker 共享的翻译接口限流在上一秒的令牌使用情况。
+// 限流是所有用户共享的，包含其他用户的用量，只有管理员可以查询
+func GetUsage(w http.ResponseWriter, r *http.Request) {
+	if !config.IsAdmin(auth.GetUserIDFromContext(r)) {
+		responsex.RespondWithJSON(w, http.StatusForbidden, models.Response{
+			Code: http.StatusForbidden,
+			Msg:  "Rate limit usage is available to administrators only.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	cfg := config.Cfg.Translate
+	if cfg.ClusterQPS <= 0 {
+		responsex.RespondWithJSON(w, http.StatusNotFound, models.Response{
+			Code: http.StatusNotFound,
+			Msg:  "Cluster rate limiting is not enabled.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	limiter := rds.NewRateLimiter(rds.RateLimitPrefix+translateapi.DefaultTranslator.Name(), cfg.ClusterQPS, cfg.PairQPS)
+	usage, err := limiter.Usage(r.Context())
+	if err != nil {
+		log.Printf("failed to get rate limit usage: %v", err)
+		responsex.RespondWithJSON(w, http.StatusInternalServerError, models.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "Failed to get rate limit usage. Please try again later.",
+			Data: map[string]interface{}{},
+		})
+		return
+	}
+
+	responsex.RespondWithJSON(w, http.StatusOK, models.Response{
+		Code: http.StatusOK,
+		Msg:  "Success",
+		Data: usage,
+	})
+}
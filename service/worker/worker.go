@@ -3,8 +3,12 @@ package worker
 import (
 	"fmt"
 	"json_trans_api/config"
+	"json_trans_api/pkg/rds"
 	"json_trans_api/pkg/tasks"
+	"json_trans_api/pkg/translate"
+	"json_trans_api/utils/translateapi"
 	"log"
+	"time"
 
 	"github.com/hibiken/asynq"
 )
@@ -16,6 +20,20 @@ func Run() {
 	// 启动发送队列处理器
 	tasks.StartSendQueue()
 
+	// 多个 worker 共用同一个翻译账号，启用分布式限流后所有进程共享QPS
+	if config.Cfg.Translate.ClusterQPS > 0 {
+		rateLimiter := rds.NewRateLimiter(rds.RateLimitPrefix+translateapi.DefaultTranslator.Name(), config.Cfg.Translate.ClusterQPS, config.Cfg.Translate.PairQPS)
+		translate.SetLimiter(rateLimiter)
+		go rateLimiter.LogUsage()
+	}
+
+	// 翻译记忆
+	memoryTTL := time.Duration(config.Cfg.Translate.Memory.TTLDays) * 24 * time.Hour
+	translate.SetupMemory(rds.NewMemoryStore(memoryTTL, translate.MemoryKeyPrefix))
+	if config.Cfg.Translate.Memory.Enabled {
+		go translate.LogMemoryStats()
+	}
+
 	srv := asynq.NewServer(
 		asynq.RedisClientOpt{Addr: fmt.Sprintf("%s:%d", config.Cfg.Redis.Host, config.Cfg.Redis.Port), Password: config.Cfg.Redis.Password},
 		asynq.Config{
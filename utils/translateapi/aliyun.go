@@ -0,0 +1,212 @@
+package translateapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"net/http"
+	"strconv"
+
+	"github.com/alibabacloud-go/tea/tea"
+
+	alimt20181012 "github.com/alibabacloud-go/alimt-20181012/v2/client"
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+)
+
+// AliyunTranslator 阿里云机器翻译
+type AliyunTranslator struct {
+	client *alimt20181012.Client
+}
+
+func NewAliyunTranslator(cfg config.Aliyun) (*AliyunTranslator, error) {
+	openapiConfig := &openapi.Config{
+		AccessKeyId:     tea.String(cfg.AccessKeyId),
+		AccessKeySecret: tea.String(cfg.AccessKeySecret),
+		Endpoint:        tea.String("mt.aliyuncs.com"),
+	}
+
+	client, err := alimt20181012.NewClient(openapiConfig)
+	if err != nil {
+		return nil, fmt.Errorf("init aliyun translate error: %v", err)
+	}
+
+	return &AliyunTranslator{client: client}, nil
+}
+
+func (t *AliyunTranslator) Name() string {
+	return ProviderAliyun
+}
+
+func (t *AliyunTranslator) Translate(ctx context.Context, from string, to string, text string) (string, error) {
+	translateGeneralRequest := &alimt20181012.TranslateGeneralRequest{
+		FormatType:     tea.String("text"),
+		SourceLanguage: tea.String(from),
+		TargetLanguage: tea.String(to),
+		SourceText:     tea.String(text),
+		Scene:          tea.String("general"),
+	}
+	runtime := &util.RuntimeOptions{}
+	if err := ctx.Err(); err != nil {
+		return text, err
+	}
+	result, err := t.client.TranslateGeneralWithOptions(translateGeneralRequest, runtime)
+	if err != nil {
+		return text, aliyunError(err)
+	}
+
+	if *result.Body.Code == 200 {
+		return *result.Body.Data.Translated, nil
+	}
+
+	// 翻译失败，返回原文
+	return text, aliyunBodyError(*result.Body.Code, tea.StringValue(result.Body.Message))
+}
+
+// BatchTranslate 使用 GetBatchTranslate 接口批量翻译，SourceText 为 {"序号": "原文"} 的JSON
+func (t *AliyunTranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	sourceText := make(map[string]string, len(req.Text))
+	for i, text := range req.Text {
+		sourceText[strconv.Itoa(i)] = text
+	}
+
+	sourceTextBytes, err := json.Marshal(sourceText)
+	if err != nil {
+		return nil, err
+	}
+
+	formatType := "text"
+	if req.TagHandling == "html" {
+		formatType = "html"
+	}
+
+	getBatchTranslateRequest := &alimt20181012.GetBatchTranslateRequest{
+		ApiType:        tea.String("translate_standard"),
+		FormatType:     tea.String(formatType),
+		SourceLanguage: tea.String(req.SourceLang),
+		TargetLanguage: tea.String(req.TargetLang),
+		SourceText:     tea.String(string(sourceTextBytes)),
+		Scene:          tea.String("general"),
+	}
+	runtime := &util.RuntimeOptions{}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result, err := t.client.GetBatchTranslateWithOptions(getBatchTranslateRequest, runtime)
+	if err != nil {
+		return nil, aliyunError(err)
+	}
+
+	if *result.Body.Code != 200 {
+		return nil, aliyunBodyError(*result.Body.Code, tea.StringValue(result.Body.Message))
+	}
+
+	translations := make([]models.TranslationResponse, len(req.Text))
+	seen := make([]bool, len(req.Text))
+	for _, item := range result.Body.TranslatedList {
+		index, err := strconv.Atoi(fmt.Sprint(item["index"]))
+		if err != nil || index < 0 || index >= len(req.Text) {
+			return nil, fmt.Errorf("unexpected batch translate index: %v", item["index"])
+		}
+
+		if code := fmt.Sprint(item["code"]); code != "200" {
+			return nil, fmt.Errorf("batch translate failed at index %d: code %s", index, code)
+		}
+
+		translated, _ := item["translated"].(string)
+		translations[index] = models.TranslationResponse{
+			DetectedSourceLanguage: req.SourceLang,
+			Text:                   translated,
+		}
+		seen[index] = true
+	}
+
+	// 接口漏掉某条时不能当成空字符串写回
+	for index, ok := range seen {
+		if !ok {
+			return nil, fmt.Errorf("batch translate result missing index %d", index)
+		}
+	}
+
+	return translations, nil
+}
+
+func (t *AliyunTranslator) Detect(ctx context.Context, text string) (string, error) {
+	getDetectLanguageRequest := &alimt20181012.GetDetectLanguageRequest{
+		SourceText: tea.String(text),
+	}
+
+	runtime := &util.RuntimeOptions{}
+	result, err := t.client.GetDetectLanguageWithOptions(getDetectLanguageRequest, runtime)
+	if err != nil {
+		return "", aliyunError(err)
+	}
+
+	/*
+		{
+			"headers": {
+				"access-control-allow-origin": "*",
+				"access-control-expose-headers": "*",
+				"connection": "keep-alive",
+				"content-length": "130",
+				"content-type": "application/json;charset=utf-8",
+				"date": "Sun, 17 Nov 2024 04:21:35 GMT",
+				"etag": "1GGkwvRNIr+lxM8EfnJcTpw0",
+				"keep-alive": "timeout=25",
+				"x-acs-request-id": "E2CBB919-0B12-5053-9613-93A11D7C04DB",
+				"x-acs-trace-id": "63b0b9a91879cd9000c021077a560645"
+			},
+			"statusCode": 200,
+			"body": {
+				"DetectedLanguage": "zh",
+				"LanguageProbabilities": "zh:0.999900;zh-tw:0.000100;",
+				"RequestId": "E2CBB919-0B12-5053-9613-93A11D7C04DB"
+			}
+		}
+	*/
+	if *result.StatusCode == 200 {
+		return *result.Body.DetectedLanguage, nil
+	}
+
+	return "", nil
+}
+
+func (t *AliyunTranslator) SupportedLanguages() []config.Language {
+	return config.SupportedLanguagesAli
+}
+
+// BatchLimit GetBatchTranslate 单次最多50条，总长度不超过8000字符
+func (t *AliyunTranslator) BatchLimit() (int, int) {
+	return 50, 8000
+}
+
+// aliyunError 把SDK的错误转换为 APIError，限流时 Code 为 Throttling.*
+func aliyunError(err error) error {
+	var sdkErr *tea.SDKError
+	if errors.As(err, &sdkErr) {
+		return &APIError{
+			Provider:   ProviderAliyun,
+			StatusCode: tea.IntValue(sdkErr.StatusCode),
+			Code:       tea.StringValue(sdkErr.Code),
+			Message:    tea.StringValue(sdkErr.Message),
+		}
+	}
+	return err
+}
+
+// aliyunBodyError 接口正常返回但业务码不是200，10001(请求超时)和10002(系统错误)属于临时错误
+func aliyunBodyError(code int32, message string) error {
+	statusCode := http.StatusBadRequest
+	if code == 10001 || code == 10002 {
+		statusCode = http.StatusServiceUnavailable
+	}
+	return &APIError{
+		Provider:   ProviderAliyun,
+		StatusCode: statusCode,
+		Code:       strconv.Itoa(int(code)),
+		Message:    message,
+	}
+}
@@ -0,0 +1,112 @@
+package translateapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"net/http"
+	"strings"
+)
+
+const deeplDefaultEndpoint = "https://api-free.deepl.com"
+
+// DeepLTranslator DeepL 翻译，models.TranslationRequest 的字段与 DeepL /v2/translate 接口保持一致
+type DeepLTranslator struct {
+	authKey  string
+	endpoint string
+}
+
+type deeplTranslateResponse struct {
+	Translations []models.TranslationResponse `json:"translations"`
+}
+
+func NewDeepLTranslator(cfg config.DeepL) (*DeepLTranslator, error) {
+	if cfg.AuthKey == "" {
+		return nil, errors.New("deepl auth key is empty")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = deeplDefaultEndpoint
+	}
+
+	return &DeepLTranslator{
+		authKey:  cfg.AuthKey,
+		endpoint: strings.TrimRight(endpoint, "/"),
+	}, nil
+}
+
+func (t *DeepLTranslator) Name() string {
+	return ProviderDeepL
+}
+
+// ContextMode DeepL 的 context 参数作用于整个请求，不计入翻译字符
+func (t *DeepLTranslator) ContextMode() string {
+	return ContextPerRequest
+}
+
+func (t *DeepLTranslator) Translate(ctx context.Context, from string, to string, text string) (string, error) {
+	translations, err := t.BatchTranslate(ctx, models.TranslationRequest{
+		Text:       []string{text},
+		SourceLang: from,
+		TargetLang: to,
+	})
+	if err != nil {
+		return text, err
+	}
+	return translations[0].Text, nil
+}
+
+func (t *DeepLTranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	// DeepL 的语言代码为大写
+	req.SourceLang = strings.ToUpper(req.SourceLang)
+	req.TargetLang = strings.ToUpper(req.TargetLang)
+
+	var result deeplTranslateResponse
+	err := doJSON(ctx, ProviderDeepL, http.MethodPost, t.endpoint+"/v2/translate", t.header(), req, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Translations) != len(req.Text) {
+		return nil, fmt.Errorf("deepl returned %d translations for %d texts", len(result.Translations), len(req.Text))
+	}
+
+	for i := range result.Translations {
+		result.Translations[i].DetectedSourceLanguage = strings.ToLower(result.Translations[i].DetectedSourceLanguage)
+	}
+
+	return result.Translations, nil
+}
+
+// Detect DeepL 没有单独的语言检测接口，不指定源语言翻译一次，取返回的检测结果
+func (t *DeepLTranslator) Detect(ctx context.Context, text string) (string, error) {
+	translations, err := t.BatchTranslate(ctx, models.TranslationRequest{
+		Text:       []string{text},
+		TargetLang: "EN-US",
+	})
+	if err != nil {
+		return "", err
+	}
+	return translations[0].DetectedSourceLanguage, nil
+}
+
+func (t *DeepLTranslator) SupportedLanguages() []config.Language {
+	return filterLanguages(
+		"ar", "bg", "cs", "da", "de", "el", "en", "es", "et", "fi", "fr", "hu", "id", "it", "ja", "ko",
+		"lt", "lv", "nb", "nl", "pl", "pt", "ro", "ru", "sk", "sl", "sv", "tr", "uk", "zh",
+	)
+}
+
+// BatchLimit DeepL 单次最多50条，请求体不超过128KiB
+func (t *DeepLTranslator) BatchLimit() (int, int) {
+	return 50, 30000
+}
+
+func (t *DeepLTranslator) header() http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "DeepL-Auth-Key "+t.authKey)
+	return header
+}
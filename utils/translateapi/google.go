@@ -0,0 +1,137 @@
+package translateapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"net/http"
+	"net/url"
+)
+
+const googleEndpoint = "https://translation.googleapis.com/language/translate/v2"
+
+// GoogleTranslator Google Cloud Translation (v2 Basic)
+type GoogleTranslator struct {
+	apiKey string
+}
+
+type googleTranslateRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source,omitempty"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+}
+
+type googleTranslateResponse struct {
+	Data struct {
+		Translations []struct {
+			TranslatedText         string `json:"translatedText"`
+			DetectedSourceLanguage string `json:"detectedSourceLanguage"`
+		} `json:"translations"`
+	} `json:"data"`
+}
+
+type googleDetectResponse struct {
+	Data struct {
+		Detections [][]struct {
+			Language   string  `json:"language"`
+			Confidence float64 `json:"confidence"`
+		} `json:"detections"`
+	} `json:"data"`
+}
+
+// Google 的中文语言代码与阿里云不同
+var googleLanguageCodes = map[string]string{
+	"zh":    "zh-CN",
+	"zh-tw": "zh-TW",
+}
+
+func NewGoogleTranslator(cfg config.Google) (*GoogleTranslator, error) {
+	if cfg.ApiKey == "" {
+		return nil, errors.New("google api key is empty")
+	}
+	return &GoogleTranslator{apiKey: cfg.ApiKey}, nil
+}
+
+func (t *GoogleTranslator) Name() string {
+	return ProviderGoogle
+}
+
+func (t *GoogleTranslator) Translate(ctx context.Context, from string, to string, text string) (string, error) {
+	translations, err := t.BatchTranslate(ctx, models.TranslationRequest{
+		Text:       []string{text},
+		SourceLang: from,
+		TargetLang: to,
+	})
+	if err != nil {
+		return text, err
+	}
+	return translations[0].Text, nil
+}
+
+func (t *GoogleTranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	format := "text"
+	if req.TagHandling == "html" {
+		format = "html"
+	}
+
+	body := googleTranslateRequest{
+		Q:      req.Text,
+		Source: googleLanguageCode(req.SourceLang),
+		Target: googleLanguageCode(req.TargetLang),
+		Format: format,
+	}
+
+	var result googleTranslateResponse
+	if err := doJSON(ctx, ProviderGoogle, http.MethodPost, t.url(""), nil, body, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Data.Translations) != len(req.Text) {
+		return nil, fmt.Errorf("google returned %d translations for %d texts", len(result.Data.Translations), len(req.Text))
+	}
+
+	translations := make([]models.TranslationResponse, len(result.Data.Translations))
+	for i, item := range result.Data.Translations {
+		translations[i] = models.TranslationResponse{
+			DetectedSourceLanguage: item.DetectedSourceLanguage,
+			Text:                   item.TranslatedText,
+		}
+	}
+	return translations, nil
+}
+
+func (t *GoogleTranslator) Detect(ctx context.Context, text string) (string, error) {
+	var result googleDetectResponse
+	body := map[string]interface{}{"q": []string{text}}
+	if err := doJSON(ctx, ProviderGoogle, http.MethodPost, t.url("/detect"), nil, body, &result); err != nil {
+		return "", err
+	}
+
+	if len(result.Data.Detections) == 0 || len(result.Data.Detections[0]) == 0 {
+		return "", errors.New("google detect returned no result")
+	}
+	return result.Data.Detections[0][0].Language, nil
+}
+
+func (t *GoogleTranslator) SupportedLanguages() []config.Language {
+	return config.SupportedLanguagesAli
+}
+
+// BatchLimit Google 建议单次不超过128条、30000字符
+func (t *GoogleTranslator) BatchLimit() (int, int) {
+	return 128, 30000
+}
+
+func (t *GoogleTranslator) url(path string) string {
+	return fmt.Sprintf("%s%s?key=%s", googleEndpoint, path, url.QueryEscape(t.apiKey))
+}
+
+func googleLanguageCode(code string) string {
+	if mapped, ok := googleLanguageCodes[code]; ok {
+		return mapped
+	}
+	return code
+}
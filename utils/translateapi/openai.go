@@ -0,0 +1,202 @@
+package translateapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"net/http"
+	"strings"
+)
+
+const (
+	openaiDefaultBaseUrl = "https://api.openai.com/v1"
+	openaiDefaultModel   = "gpt-4o-mini"
+)
+
+// OpenAITranslator 基于 OpenAI Chat Completions 协议的大模型翻译，兼容各类 OpenAI 兼容接口
+type OpenAITranslator struct {
+	baseUrl string
+	apiKey  string
+	model   string
+}
+
+type openaiMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openaiChatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openaiMessage `json:"messages"`
+	Temperature float64         `json:"temperature"`
+}
+
+type openaiChatResponse struct {
+	Choices []struct {
+		Message openaiMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func NewOpenAITranslator(cfg config.OpenAI) (*OpenAITranslator, error) {
+	if cfg.ApiKey == "" {
+		return nil, errors.New("openai api key is empty")
+	}
+
+	baseUrl := cfg.BaseUrl
+	if baseUrl == "" {
+		baseUrl = openaiDefaultBaseUrl
+	}
+
+	model := cfg.Model
+	if model == "" {
+		model = openaiDefaultModel
+	}
+
+	return &OpenAITranslator{
+		baseUrl: strings.TrimRight(baseUrl, "/"),
+		apiKey:  cfg.ApiKey,
+		model:   model,
+	}, nil
+}
+
+func (t *OpenAITranslator) Name() string {
+	return ProviderOpenAI
+}
+
+// ContextMode 每条文本的上下文放在提示词里
+func (t *OpenAITranslator) ContextMode() string {
+	return ContextPerText
+}
+
+func (t *OpenAITranslator) Translate(ctx context.Context, from string, to string, text string) (string, error) {
+	translations, err := t.BatchTranslate(ctx, models.TranslationRequest{
+		Text:       []string{text},
+		SourceLang: from,
+		TargetLang: to,
+	})
+	if err != nil {
+		return text, err
+	}
+	return translations[0].Text, nil
+}
+
+// BatchTranslate 以JSON数组的形式把所有文本一次发给模型，要求按原顺序返回等长的JSON数组
+func (t *OpenAITranslator) BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	texts, err := json.Marshal(req.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := fmt.Sprintf(
+		"You are a professional software localization translator. Translate every string in the JSON array below from %s to %s. "+
+			"Keep placeholders, markup and line breaks unchanged. "+
+			"Reply with only a JSON array of strings with exactly %d items in the same order.",
+		languageName(req.SourceLang), languageName(req.TargetLang), len(req.Text),
+	)
+	if req.Formality != "" {
+		prompt += fmt.Sprintf(" Use a %s tone.", req.Formality)
+	}
+	if req.TagHandling == "html" {
+		prompt += " The strings contain HTML; translate only the text content and keep every tag and attribute intact."
+	}
+	if req.Context != "" {
+		prompt += "\nContext for all strings:\n" + req.Context
+	}
+	if len(req.Contexts) == len(req.Text) {
+		// 上下文只用来消除歧义，不需要翻译
+		contexts, err := json.Marshal(req.Contexts)
+		if err != nil {
+			return nil, err
+		}
+		prompt += "\nThe following JSON array gives the context of each string in the same order, such as its key path, sibling keys and a description. " +
+			"Use it only to choose the right meaning, do not translate it:\n" + string(contexts)
+	}
+
+	content, err := t.chat(ctx, prompt, string(texts))
+	if err != nil {
+		return nil, err
+	}
+
+	var translated []string
+	if err := json.Unmarshal([]byte(trimCodeFence(content)), &translated); err != nil {
+		return nil, fmt.Errorf("failed to parse model output: %v", err)
+	}
+
+	if len(translated) != len(req.Text) {
+		return nil, fmt.Errorf("model returned %d translations for %d texts", len(translated), len(req.Text))
+	}
+
+	translations := make([]models.TranslationResponse, len(translated))
+	for i, text := range translated {
+		translations[i] = models.TranslationResponse{
+			DetectedSourceLanguage: req.SourceLang,
+			Text:                   text,
+		}
+	}
+	return translations, nil
+}
+
+func (t *OpenAITranslator) Detect(ctx context.Context, text string) (string, error) {
+	prompt := "Detect the language of the user's text. Reply with only its ISO 639-1 code in lowercase, use zh-tw for Traditional Chinese."
+	content, err := t.chat(ctx, prompt, text)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(content)), nil
+}
+
+func (t *OpenAITranslator) SupportedLanguages() []config.Language {
+	return config.SupportedLanguagesAli
+}
+
+// BatchLimit 控制单次提示词长度，避免模型输出被截断
+func (t *OpenAITranslator) BatchLimit() (int, int) {
+	return 40, 6000
+}
+
+func (t *OpenAITranslator) chat(ctx context.Context, system string, user string) (string, error) {
+	body := openaiChatRequest{
+		Model: t.model,
+		Messages: []openaiMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Temperature: 0,
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+t.apiKey)
+
+	var result openaiChatResponse
+	if err := doJSON(ctx, ProviderOpenAI, http.MethodPost, t.baseUrl+"/chat/completions", header, body, &result); err != nil {
+		return "", err
+	}
+
+	if len(result.Choices) == 0 {
+		return "", errors.New("model returned no choices")
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+// languageName 提示词里使用语言名称比语言代码更准确
+func languageName(code string) string {
+	if name, ok := config.GetLanguageByCode(code); ok {
+		return name
+	}
+	return code
+}
+
+// trimCodeFence 去掉模型可能包裹在输出外面的 ``` 代码块标记
+func trimCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimPrefix(content, "json")
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
@@ -1,92 +1,224 @@
 package translateapi
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"json_trans_api/config"
+	"json_trans_api/models/models"
+	"json_trans_api/pkg/httpclient"
 	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProviderAliyun = "aliyun"
+	ProviderDeepL  = "deepl"
+	ProviderGoogle = "google"
+	ProviderOpenAI = "openai"
+)
 
-	"github.com/alibabacloud-go/tea/tea"
+// Translator 机器翻译服务的统一接口，不同的翻译服务商各自实现
+type Translator interface {
+	// Name 翻译服务商名称
+	Name() string
+	// Translate 翻译单条文本
+	Translate(ctx context.Context, from string, to string, text string) (string, error)
+	// BatchTranslate 批量翻译，返回结果与 req.Text 一一对应
+	BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error)
+	// Detect 检测文本的语言
+	Detect(ctx context.Context, text string) (string, error)
+	// SupportedLanguages 支持的语言列表
+	SupportedLanguages() []config.Language
+	// BatchLimit 单次批量翻译允许的最大条数和最大字符数
+	BatchLimit() (maxTexts int, maxChars int)
+}
 
-	alimt20181012 "github.com/alibabacloud-go/alimt-20181012/v2/client"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
+// 翻译服务商对上下文的支持方式
+const (
+	ContextPerRequest = "request" // 一次请求的所有文本共用一个上下文，如 DeepL 的 context 参数
+	ContextPerText    = "text"    // 每条文本可以有各自的上下文，如大模型
 )
 
-var TranslateClient *alimt20181012.Client
+// ContextTranslator 支持上下文的翻译服务商，key 路径、相邻的 key 和请求的描述会作为上下文一起发送
+type ContextTranslator interface {
+	// ContextMode 返回 ContextPerRequest 或 ContextPerText
+	ContextMode() string
+}
 
-func init() {
-	config := &openapi.Config{
-		AccessKeyId:     &config.Cfg.Aliyun.AccessKeyId,
-		AccessKeySecret: &config.Cfg.Aliyun.AccessKeySecret,
+// ContextMode 当前翻译服务商对上下文的支持方式，不支持时返回空字符串
+func ContextMode() string {
+	if t, ok := DefaultTranslator.(ContextTranslator); ok {
+		return t.ContextMode()
 	}
+	return ""
+}
 
-	var err error
-	config.Endpoint = tea.String("mt.aliyuncs.com")
-	TranslateClient, err = alimt20181012.NewClient(config)
+// APIError 翻译服务商返回的错误，用于区分限流、临时故障和其他错误
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Code       string
+	Message    string
+}
 
-	if err != nil {
-		log.Fatal("init aliyun translate error.")
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s translate error: status=%d code=%s message=%s", e.Provider, e.StatusCode, e.Code, e.Message)
+}
+
+// Retryable 限流(429)和服务端错误(5xx)可以重试
+func (e *APIError) Retryable() bool {
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError {
+		return true
 	}
+	return strings.HasPrefix(e.Code, "Throttling") || strings.HasPrefix(e.Code, "ServiceUnavailable")
 }
 
-func Translate(from string, to string, text string) (string, error) {
-	translateGeneralRequest := &alimt20181012.TranslateGeneralRequest{
-		FormatType:     tea.String("text"),
-		SourceLanguage: tea.String(from),
-		TargetLanguage: tea.String(to),
-		SourceText:     tea.String(text),
-		Scene:          tea.String("general"),
-	}
-	runtime := &util.RuntimeOptions{}
-	result, err := TranslateClient.TranslateGeneralWithOptions(translateGeneralRequest, runtime)
+// IsRetryable 判断错误是否值得重试，参数错误、余额不足等错误重试也不会成功
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+var DefaultTranslator Translator
+
+func init() {
+	var err error
+	DefaultTranslator, err = NewTranslator(config.Cfg.Translate.Provider)
 	if err != nil {
-		return text, err
+		log.Fatalf("init translate provider error: %v", err)
 	}
+}
 
-	if *result.Body.Code == 200 {
-		return *result.Body.Data.Translated, nil
+// NewTranslator 根据服务商名称创建翻译实例，为空时默认使用阿里云
+func NewTranslator(provider string) (Translator, error) {
+	switch strings.ToLower(provider) {
+	case "", ProviderAliyun:
+		return NewAliyunTranslator(config.Cfg.Aliyun)
+	case ProviderDeepL:
+		return NewDeepLTranslator(config.Cfg.Translate.DeepL)
+	case ProviderGoogle:
+		return NewGoogleTranslator(config.Cfg.Translate.Google)
+	case ProviderOpenAI:
+		return NewOpenAITranslator(config.Cfg.Translate.OpenAI)
+	default:
+		return nil, fmt.Errorf("unsupported translate provider: %s", provider)
 	}
+}
 
-	// 翻译失败，返回原文
-	return text, errors.New(*result.Body.Message)
+// SetTranslator 替换默认的翻译实例，主要用于测试时注入本地实现
+func SetTranslator(t Translator) {
+	DefaultTranslator = t
+}
+
+func Translate(from string, to string, text string) (string, error) {
+	return DefaultTranslator.Translate(context.Background(), from, to, text)
+}
+
+func BatchTranslate(ctx context.Context, req models.TranslationRequest) ([]models.TranslationResponse, error) {
+	return DefaultTranslator.BatchTranslate(ctx, req)
 }
 
 func Detect(text string) (string, error) {
-	getDetectLanguageRequest := &alimt20181012.GetDetectLanguageRequest{
-		SourceText: tea.String(text),
-	}
-
-	runtime := &util.RuntimeOptions{}
-	result, _err := TranslateClient.GetDetectLanguageWithOptions(getDetectLanguageRequest, runtime)
-	if _err != nil {
-		panic(_err)
-	}
-
-	/*
-		{
-			"headers": {
-				"access-control-allow-origin": "*",
-				"access-control-expose-headers": "*",
-				"connection": "keep-alive",
-				"content-length": "130",
-				"content-type": "application/json;charset=utf-8",
-				"date": "Sun, 17 Nov 2024 04:21:35 GMT",
-				"etag": "1GGkwvRNIr+lxM8EfnJcTpw0",
-				"keep-alive": "timeout=25",
-				"x-acs-request-id": "E2CBB919-0B12-5053-9613-93A11D7C04DB",
-				"x-acs-trace-id": "63b0b9a91879cd9000c021077a560645"
-			},
-			"statusCode": 200,
-			"body": {
-				"DetectedLanguage": "zh",
-				"LanguageProbabilities": "zh:0.999900;zh-tw:0.000100;",
-				"RequestId": "E2CBB919-0B12-5053-9613-93A11D7C04DB"
-			}
+	return DefaultTranslator.Detect(context.Background(), text)
+}
+
+func SupportedLanguages() []config.Language {
+	return DefaultTranslator.SupportedLanguages()
+}
+
+func BatchLimit() (int, int) {
+	return DefaultTranslator.BatchLimit()
+}
+
+// IsLanguageSupported 检查当前翻译服务商是否支持该语言
+func IsLanguageSupported(code string) bool {
+	for _, lang := range DefaultTranslator.SupportedLanguages() {
+		if lang.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// filterLanguages 从完整的语言列表中筛选出服务商支持的语言
+func filterLanguages(codes ...string) []config.Language {
+	supported := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		supported[code] = true
+	}
+
+	var languages []config.Language
+	for _, lang := range config.SupportedLanguagesAli {
+		if supported[lang.Code] {
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// doJSON 发送JSON请求并解析JSON响应，供基于HTTP接口的翻译服务商使用
+func doJSON(ctx context.Context, provider string, method string, url string, header http.Header, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %v", err)
 		}
-	*/
-	if *result.StatusCode == 200 {
-		return *result.Body.DetectedLanguage, nil
+		reqBody = bytes.NewBuffer(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpclient.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{
+			Provider:   provider,
+			StatusCode: resp.StatusCode,
+			Message:    string(bodyBytes),
+		}
+	}
+
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
 	}
 
-	return "", nil
+	return nil
 }